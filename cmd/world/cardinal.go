@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"time"
+
+	"github.com/alecthomas/kong"
 
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/dependency"
 	cmdsetup "pkg.world.dev/world-cli/internal/app/world-cli/controllers/cmd_setup"
@@ -15,6 +18,7 @@ var CardinalCmdPlugin struct {
 //nolint:lll, revive // needed to put all the help text in the same line
 type CardinalCmd struct {
 	Config       string                `flag:"" type:"existingfile" help:"A TOML config file"`
+	Shard        string                `flag:""                     help:"Select a shard by namespace or directory when the repository has several world.toml files"`
 	Context      context.Context       `                                                      kong:"-"`
 	Dependencies cmdsetup.Dependencies `                                                      kong:"-"`
 
@@ -24,37 +28,74 @@ type CardinalCmd struct {
 	Dev     *DevCardinalCmd     `cmd:"" group:"Cardinal Commands:" help:"Run Cardinal in fast development mode with hot reloading"`
 	Purge   *PurgeCardinalCmd   `cmd:"" group:"Cardinal Commands:" help:"Reset your Cardinal game shard to a clean state by removing all data and containers"`
 	Build   *BuildCardinalCmd   `cmd:"" group:"Cardinal Commands:" help:"Build and package your Cardinal game into production-ready Docker images"`
+	List    *ListCardinalCmd    `cmd:"" group:"Cardinal Commands:" help:"List the running game shards"                                              aliases:"ls"`
+	Images  *ImagesCardinalCmd  `cmd:"" group:"Cardinal Commands:" help:"List and prune the images built by the World CLI"`
+	Load    *LoadCardinalCmd    `cmd:"" group:"Cardinal Commands:" help:"Import the images exported by world cardinal build --output"`
+	Trace   *TraceCardinalCmd   `cmd:"" group:"Cardinal Commands:" help:"Browse the traces of the shard started with --telemetry"`
+	Debug   *DebugCardinalCmd   `cmd:"" group:"Cardinal Commands:" help:"Debug Cardinal with Delve from your IDE or the terminal"`
+	Test    *TestCardinalCmd    `cmd:"" group:"Cardinal Commands:" help:"Run the tests of your game shard against an ephemeral Redis"`
+	Bench   *BenchCardinalCmd   `cmd:"" group:"Cardinal Commands:" help:"Load test a shard with simulated personas sending messages and queries"`
+	New     *NewCardinalCmd     `cmd:"" group:"Cardinal Commands:" help:"Generate a component, message, query or system and register it in main.go"`
+}
+
+// dockerOptional is implemented by the cardinal commands that don't always need Docker.
+type dockerOptional interface {
+	NeedsDocker() bool
 }
 
-func (c *CardinalCmd) Run() error {
-	return dependency.Check(
-		dependency.Go,
-		dependency.Git,
-		dependency.Docker,
-		dependency.DockerDaemon,
-	)
+func (c *CardinalCmd) Run(kongCtx *kong.Context) error {
+	deps := []dependency.Dependency{dependency.Go, dependency.Git}
+	needsDocker := true
+	if selected := kongCtx.Selected(); selected != nil && selected.Target.CanAddr() {
+		if cmd, ok := selected.Target.Addr().Interface().(dockerOptional); ok {
+			needsDocker = cmd.NeedsDocker()
+		}
+	}
+	if needsDocker {
+		deps = append(deps, dependency.Docker, dependency.DockerDaemon)
+	}
+	return dependency.Check(deps...)
 }
 
 //nolint:lll // needed to put all the help text in the same line
 type StartCardinalCmd struct {
-	Parent     *CardinalCmd `kong:"-"`
-	Detach     bool         `         flag:"" help:"Run in detached mode"`
-	LogLevel   string       `         flag:"" help:"Set the log level for Cardinal"`
-	Debug      bool         `         flag:"" help:"Enable delve debugging"`
-	Telemetry  bool         `         flag:"" help:"Enable tracing, metrics, and profiling"`
-	Editor     bool         `         flag:"" help:"Run Cardinal Editor, useful for prototyping and debugging"`
-	EditorPort string       `         flag:"" help:"Port for Cardinal Editor"                                  default:"auto"`
+	Parent      *CardinalCmd `kong:"-"`
+	Detach      bool         `         flag:"" help:"Run in detached mode"`
+	LogLevel    string       `         flag:"" help:"Set the log level for Cardinal"`
+	Debug       bool         `         flag:"" help:"Enable delve debugging"`
+	Telemetry   bool         `         flag:"" help:"Enable tracing, metrics, and profiling"`
+	Grafana     bool         `         flag:"" help:"With --telemetry, also start Grafana with dashboards for Nakama and Cardinal"`
+	Editor      bool         `         flag:"" help:"Run Cardinal Editor, useful for prototyping and debugging"`
+	EditorPort  string       `         flag:"" help:"Port for Cardinal Editor"                                  default:"auto"`
+	AutoPorts   bool         `         flag:"" help:"Pick free host ports for services whose ports are in use"`
+	BuildArg    []string     `         flag:"" help:"Set a build arg of the Cardinal image (KEY=VALUE), can be repeated"                 sep:"none"`
+	SSH         bool         `         flag:"" help:"Forward the SSH agent to the build to fetch private modules over SSH"               name:"ssh"`
+	Insecure    bool         `         flag:"" help:"Allow passing the GitHub token as a build arg when BuildKit is not available"        name:"insecure-build-secrets"`
+	NoCache     bool         `         flag:"" help:"Build the Cardinal image without using the layer cache"`
+	NoBuild     bool         `         flag:"" help:"Run the Cardinal image already on this machine instead of building it"`
+	Wait        bool         `         flag:"" help:"With --detach, wait until every service is ready before exiting"`
+	WaitTimeout int          `         flag:"" help:"Seconds to wait for the services to be ready"                                  default:"300"`
 }
 
 func (c *StartCardinalCmd) Run() error {
 	flags := models.StartCardinalFlags{
-		Config:     c.Parent.Config,
-		Detach:     c.Detach,
-		LogLevel:   c.LogLevel,
-		Debug:      c.Debug,
-		Telemetry:  c.Telemetry,
-		Editor:     c.Editor,
-		EditorPort: c.EditorPort,
+		Config:      c.Parent.Config,
+		Shard:       c.Parent.Shard,
+		Detach:      c.Detach,
+		LogLevel:    c.LogLevel,
+		Debug:       c.Debug,
+		Telemetry:   c.Telemetry,
+		Grafana:     c.Grafana,
+		Editor:      c.Editor,
+		BuildArgs:   c.BuildArg,
+		SSH:         c.SSH,
+		Insecure:    c.Insecure,
+		NoCache:     c.NoCache,
+		NoBuild:     c.NoBuild,
+		Wait:        c.Wait,
+		WaitTimeout: c.WaitTimeout,
+		EditorPort:  c.EditorPort,
+		AutoPorts:   c.AutoPorts,
 	}
 	return c.Parent.Dependencies.CardinalHandler.Start(c.Parent.Context, flags)
 }
@@ -66,6 +107,7 @@ type StopCardinalCmd struct {
 func (c *StopCardinalCmd) Run() error {
 	flags := models.StopCardinalFlags{
 		Config: c.Parent.Config,
+		Shard:  c.Parent.Shard,
 	}
 	return c.Parent.Dependencies.CardinalHandler.Stop(c.Parent.Context, flags)
 }
@@ -79,6 +121,7 @@ type RestartCardinalCmd struct {
 func (c *RestartCardinalCmd) Run() error {
 	flags := models.RestartCardinalFlags{
 		Config: c.Parent.Config,
+		Shard:  c.Parent.Shard,
 		Detach: c.Detach,
 		Debug:  c.Debug,
 	}
@@ -89,17 +132,33 @@ type DevCardinalCmd struct {
 	Parent    *CardinalCmd `kong:"-"`
 	Editor    bool         `         flag:"" help:"Enable Cardinal Editor"`
 	PrettyLog bool         `         flag:"" help:"Run Cardinal with pretty logging" default:"true"`
+	AutoPorts bool         `         flag:"" help:"Pick a free host port for Redis if its port is in use"`
+	Debug     bool         `         flag:"" help:"Run Cardinal under Delve, attach with world cardinal debug attach or your IDE"`
+	Nakama    bool         `         flag:"" help:"Also run Nakama and its database in Docker, connected to the native Cardinal"`
+	NoDocker  bool         `         flag:"" help:"Run Redis without Docker, from redis-server on the PATH or embedded"`
+	Persist   bool         `         flag:"" help:"With --no-docker, keep the Redis data under .world/ (needs redis-server)"`
 }
 
 func (c *DevCardinalCmd) Run() error {
 	flags := models.DevCardinalFlags{
 		Config:    c.Parent.Config,
+		Shard:     c.Parent.Shard,
 		Editor:    c.Editor,
 		PrettyLog: c.PrettyLog,
+		AutoPorts: c.AutoPorts,
+		Debug:     c.Debug,
+		Nakama:    c.Nakama,
+		NoDocker:  c.NoDocker,
+		Persist:   c.Persist,
 	}
 	return c.Parent.Dependencies.CardinalHandler.Dev(c.Parent.Context, flags)
 }
 
+// NeedsDocker is false with --no-docker.
+func (c *DevCardinalCmd) NeedsDocker() bool {
+	return !c.NoDocker
+}
+
 type PurgeCardinalCmd struct {
 	Parent *CardinalCmd `kong:"-"`
 }
@@ -107,33 +166,244 @@ type PurgeCardinalCmd struct {
 func (c *PurgeCardinalCmd) Run() error {
 	flags := models.PurgeCardinalFlags{
 		Config: c.Parent.Config,
+		Shard:  c.Parent.Shard,
 	}
 	return c.Parent.Dependencies.CardinalHandler.Purge(c.Parent.Context, flags)
 }
 
 type BuildCardinalCmd struct {
-	Parent    *CardinalCmd `kong:"-"`
-	LogLevel  string       `         flag:"" help:"Set the log level for Cardinal"`
-	Debug     bool         `         flag:"" help:"Enable debugging"`
-	Telemetry bool         `         flag:"" help:"Enable tracing, metrics, and profiling"`
-	Push      string       `         flag:"" help:"Push your cardinal image to a given image repository" hidden:"true"`
-	Auth      string       `         flag:"" help:"Auth token for the given image repository"            hidden:"true"`
-	User      string       `         flag:"" help:"User for the given image repository"                  hidden:"true"`
-	Pass      string       `         flag:"" help:"Password for the given image repository"              hidden:"true"`
-	RegToken  string       `         flag:"" help:"Registry token for the given image repository"        hidden:"true"`
+	Parent     *CardinalCmd `kong:"-"`
+	LogLevel   string       `         flag:"" help:"Set the log level for Cardinal"`
+	Debug      bool         `         flag:"" help:"Enable debugging"`
+	Telemetry  bool         `         flag:"" help:"Enable tracing, metrics, and profiling"`
+	Push       []string     `         flag:"" help:"Push the image to these repositories with every tag of the build, or to these exact tags"`
+	PushResult string       `         flag:"" help:"Write the digest of every pushed reference to this JSON file"`
+	Auth       string       `         flag:"" help:"Auth token for the given image repository"            hidden:"true"`
+	User       string       `         flag:"" help:"User for the given image repository"                  hidden:"true"`
+	Pass       string       `         flag:"" help:"Password for the given image repository"              hidden:"true"`
+	RegToken   string       `         flag:"" help:"Registry token for the given image repository"        hidden:"true"`
+	Output     string       `         flag:"" help:"Export the built images to this tar archive, gzipped when it ends with .gz"   short:"o" type:"path"`
+	WithStack  bool         `         flag:"" help:"Also export the images of the rest of the stack, with the [services] and telemetry images, to the archive"`
+	ShowLog    bool         `         flag:"" help:"Print the log of the last build instead of building"`
+	BuildArg   []string     `         flag:"" help:"Set a build arg of the Cardinal image (KEY=VALUE), can be repeated"                 sep:"none"`
+	Target     string       `         flag:"" help:"Build this stage of the Dockerfile instead of the default target"`
+	Platform   []string     `         flag:"" help:"Build a multi-arch image for these platforms, e.g. linux/amd64,linux/arm64"`
+	Version    string       `         flag:"" help:"Also tag the image with this semantic version, e.g. v1.2.0"`
+	SSH        bool         `         flag:"" help:"Forward the SSH agent to the build to fetch private modules over SSH"               name:"ssh"`
+	Insecure   bool         `         flag:"" help:"Allow passing the GitHub token as a build arg when BuildKit is not available"        name:"insecure-build-secrets"`
+	NoCache    bool         `         flag:"" help:"Build the Cardinal image without using the layer cache"`
 }
 
 func (c *BuildCardinalCmd) Run() error {
 	flags := models.BuildCardinalFlags{
-		Config:    c.Parent.Config,
-		LogLevel:  c.LogLevel,
-		Debug:     c.Debug,
-		Telemetry: c.Telemetry,
-		Push:      c.Push,
-		Auth:      c.Auth,
-		User:      c.User,
-		Pass:      c.Pass,
-		RegToken:  c.RegToken,
+		Config:     c.Parent.Config,
+		Shard:      c.Parent.Shard,
+		LogLevel:   c.LogLevel,
+		Debug:      c.Debug,
+		Telemetry:  c.Telemetry,
+		Push:       c.Push,
+		PushResult: c.PushResult,
+		Auth:       c.Auth,
+		User:       c.User,
+		Pass:       c.Pass,
+		RegToken:   c.RegToken,
+		Output:     c.Output,
+		WithStack:  c.WithStack,
+		ShowLog:    c.ShowLog,
+		BuildArgs:  c.BuildArg,
+		Target:     c.Target,
+		Platforms:  c.Platform,
+		Version:    c.Version,
+		SSH:        c.SSH,
+		Insecure:   c.Insecure,
+		NoCache:    c.NoCache,
 	}
 	return c.Parent.Dependencies.CardinalHandler.Build(c.Parent.Context, flags)
 }
+
+type ListCardinalCmd struct {
+	Parent *CardinalCmd `kong:"-"`
+}
+
+func (c *ListCardinalCmd) Run() error {
+	return c.Parent.Dependencies.CardinalHandler.List(c.Parent.Context, models.ListCardinalFlags{})
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type ImagesCardinalCmd struct {
+	Parent *CardinalCmd            `kong:"-"`
+	List   *ListImagesCardinalCmd  `cmd:"" help:"List the images built by the World CLI with their size, creation time and commit" default:"1" aliases:"ls"`
+	Prune  *PruneImagesCardinalCmd `cmd:"" help:"Remove the images built by the World CLI, except the most recent ones of every service"`
+}
+
+type ListImagesCardinalCmd struct {
+	Parent *ImagesCardinalCmd `kong:"-"`
+}
+
+func (c *ListImagesCardinalCmd) Run() error {
+	cardinal := c.Parent.Parent
+	return cardinal.Dependencies.CardinalHandler.Images(cardinal.Context, models.ImagesCardinalFlags{})
+}
+
+type PruneImagesCardinalCmd struct {
+	Parent *ImagesCardinalCmd `kong:"-"`
+	Keep   int                `         flag:"" help:"Number of images to keep for every service of every shard" default:"3"`
+}
+
+func (c *PruneImagesCardinalCmd) Run() error {
+	cardinal := c.Parent.Parent
+	flags := models.PruneImagesCardinalFlags{
+		Keep: c.Keep,
+	}
+	return cardinal.Dependencies.CardinalHandler.PruneImages(cardinal.Context, flags)
+}
+
+type LoadCardinalCmd struct {
+	Parent *CardinalCmd `kong:"-"`
+	File   string       `arg:"" help:"The tar archive to import, compressed or not" type:"existingfile"`
+}
+
+func (c *LoadCardinalCmd) Run() error {
+	flags := models.LoadCardinalFlags{
+		File: c.File,
+	}
+	return c.Parent.Dependencies.CardinalHandler.Load(c.Parent.Context, flags)
+}
+
+type TraceCardinalCmd struct {
+	Parent *CardinalCmd          `kong:"-"`
+	Open   *OpenTraceCardinalCmd `cmd:"" help:"Open the Jaeger UI on the traces of this shard"`
+}
+
+type OpenTraceCardinalCmd struct {
+	Parent  *TraceCardinalCmd `kong:"-"`
+	Service string            `         flag:"" help:"The service whose traces are shown, e.g. cardinal or nakama" default:"cardinal"`
+}
+
+func (c *OpenTraceCardinalCmd) Run() error {
+	cardinal := c.Parent.Parent
+	flags := models.TraceOpenCardinalFlags{
+		Config:  cardinal.Config,
+		Shard:   cardinal.Shard,
+		Service: c.Service,
+	}
+	return cardinal.Dependencies.CardinalHandler.TraceOpen(cardinal.Context, flags)
+}
+
+type DebugCardinalCmd struct {
+	Parent *CardinalCmd            `kong:"-"`
+	Setup  *SetupDebugCardinalCmd  `cmd:"" help:"Write the VS Code and GoLand configurations attaching to Cardinal"`
+	Attach *AttachDebugCardinalCmd `cmd:"" help:"Attach a Delve terminal session to Cardinal"`
+}
+
+type SetupDebugCardinalCmd struct {
+	Parent *DebugCardinalCmd `kong:"-"`
+	IDE    string            `         flag:"" help:"The IDE to write the configurations of" enum:"all,vscode,goland" default:"all" name:"ide"`
+}
+
+func (c *SetupDebugCardinalCmd) Run() error {
+	cardinal := c.Parent.Parent
+	flags := models.DebugSetupCardinalFlags{
+		Config: cardinal.Config,
+		Shard:  cardinal.Shard,
+		IDE:    c.IDE,
+	}
+	return cardinal.Dependencies.CardinalHandler.DebugSetup(cardinal.Context, flags)
+}
+
+type AttachDebugCardinalCmd struct {
+	Parent *DebugCardinalCmd `kong:"-"`
+}
+
+func (c *AttachDebugCardinalCmd) Run() error {
+	cardinal := c.Parent.Parent
+	flags := models.DebugAttachCardinalFlags{
+		Config: cardinal.Config,
+		Shard:  cardinal.Shard,
+	}
+	return cardinal.Dependencies.CardinalHandler.DebugAttach(cardinal.Context, flags)
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type TestCardinalCmd struct {
+	Parent       *CardinalCmd `kong:"-"`
+	CoverProfile string       `         flag:"" type:"path" help:"Keep the coverage profile of the run in this file"`
+	JUnit        string       `         flag:"" type:"path" help:"Write a JUnit XML report of the run to this file, for CI" name:"junit"`
+	Args         []string     `         arg:"" optional:""   help:"The packages to test, ./... by default, then the flags of go test, after -- or the packages" passthrough:""`
+}
+
+func (c *TestCardinalCmd) Run() error {
+	flags := models.TestCardinalFlags{
+		Config:       c.Parent.Config,
+		Shard:        c.Parent.Shard,
+		Args:         c.Args,
+		CoverProfile: c.CoverProfile,
+		JUnit:        c.JUnit,
+	}
+	return c.Parent.Dependencies.CardinalHandler.Test(c.Parent.Context, flags)
+}
+
+// NeedsDocker is false, Redis runs without Docker.
+func (c *TestCardinalCmd) NeedsDocker() bool {
+	return false
+}
+
+// GracefulInterrupt stops the ephemeral Redis and removes the files of the run on Ctrl+C.
+func (c *TestCardinalCmd) GracefulInterrupt() {}
+
+//nolint:lll // needed to put all the help text in the same line
+type BenchCardinalCmd struct {
+	Parent    *CardinalCmd  `kong:"-"`
+	Scenario  string        `         arg:"" type:"existingfile" help:"The TOML or YAML file describing the personas, their messages and queries, and the rate"`
+	URL       string        `         flag:""                    help:"The Cardinal to load, e.g. of another shard or in the cloud, the one of world.toml by default" name:"url"`
+	Namespace string        `         flag:""                    help:"The namespace of the transactions, CARDINAL_NAMESPACE of world.toml by default"`
+	Personas  int           `         flag:""                    help:"Override the number of personas of the scenario"`
+	Rate      float64       `         flag:""                    help:"Override the requests per second of the scenario"`
+	Duration  time.Duration `         flag:""                    help:"Override how long the load of the scenario lasts, e.g. 1m"`
+	Output    string        `         flag:""                    help:"Write the report as JSON to this file"                                                         short:"o" type:"path"`
+}
+
+func (c *BenchCardinalCmd) Run() error {
+	flags := models.BenchCardinalFlags{
+		Config:    c.Parent.Config,
+		Shard:     c.Parent.Shard,
+		Scenario:  c.Scenario,
+		URL:       c.URL,
+		Namespace: c.Namespace,
+		Personas:  c.Personas,
+		Rate:      c.Rate,
+		Duration:  c.Duration,
+		Output:    c.Output,
+	}
+	return c.Parent.Dependencies.CardinalHandler.Bench(c.Parent.Context, flags)
+}
+
+// NeedsDocker is false, the shard may run anywhere.
+func (c *BenchCardinalCmd) NeedsDocker() bool {
+	return false
+}
+
+// GracefulInterrupt reports the load sent so far on Ctrl+C.
+func (c *BenchCardinalCmd) GracefulInterrupt() {}
+
+//nolint:lll // needed to put all the help text in the same line
+type NewCardinalCmd struct {
+	Parent *CardinalCmd `kong:"-"`
+	Kind   string       `         arg:"" enum:"component,message,query,system" help:"What to generate: component, message, query or system"`
+	Name   string       `         arg:""                                       help:"The name, e.g. PlayerHealth or player-health"`
+}
+
+func (c *NewCardinalCmd) Run() error {
+	flags := models.NewCardinalFlags{
+		Config: c.Parent.Config,
+		Shard:  c.Parent.Shard,
+		Kind:   c.Kind,
+		Name:   c.Name,
+	}
+	return c.Parent.Dependencies.CardinalHandler.New(c.Parent.Context, flags)
+}
+
+// NeedsDocker is false, generating code only needs the sources.
+func (c *NewCardinalCmd) NeedsDocker() bool {
+	return false
+}
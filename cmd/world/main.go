@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"reflect"
 	"runtime/debug"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/alecthomas/kong"
@@ -18,11 +19,14 @@ import (
 	"pkg.world.dev/world-cli/internal/app/world-cli/commands/cardinal"
 	"pkg.world.dev/world-cli/internal/app/world-cli/commands/cloud"
 	"pkg.world.dev/world-cli/internal/app/world-cli/commands/evm"
+	"pkg.world.dev/world-cli/internal/app/world-cli/commands/nakama"
 	"pkg.world.dev/world-cli/internal/app/world-cli/commands/organization"
 	"pkg.world.dev/world-cli/internal/app/world-cli/commands/project"
+	"pkg.world.dev/world-cli/internal/app/world-cli/commands/registry"
 	"pkg.world.dev/world-cli/internal/app/world-cli/commands/root"
 	"pkg.world.dev/world-cli/internal/app/world-cli/commands/user"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/endpoint"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/telemetry"
 	cmdsetup "pkg.world.dev/world-cli/internal/app/world-cli/controllers/cmd_setup"
 	cfgService "pkg.world.dev/world-cli/internal/app/world-cli/services/config"
@@ -61,6 +65,17 @@ var (
 	SentryDsn     string
 )
 
+// gracefulInterrupter is implemented by the commands that stop through their context when the CLI is
+// interrupted, so they can clean up before it exits.
+type gracefulInterrupter interface {
+	GracefulInterrupt()
+}
+
+// gracefulInterrupt is set when the selected command is a gracefulInterrupter.
+//
+//nolint:gochecknoglobals // read by the signal handler
+var gracefulInterrupt atomic.Bool
+
 func main() {
 	// Create a channel to receive signals.
 	sigChan := make(chan os.Signal, 1)
@@ -74,6 +89,10 @@ func main() {
 		sig := <-sigChan
 		switch sig {
 		case os.Interrupt, syscall.SIGTERM:
+			// Commands cleaning up after themselves stop through their context, a second signal forces the exit
+			if gracefulInterrupt.Load() {
+				<-sigChan
+			}
 			os.Exit(0)
 		}
 	}()
@@ -119,6 +138,8 @@ func main() {
 		&ProjectCmdPlugin,
 		&OrganizationCmdPlugin,
 		&UserCmdPlugin,
+		&RegistryCmdPlugin,
+		&NakamaCmdPlugin,
 	}
 
 	ctx := kong.Parse(
@@ -132,11 +153,24 @@ func main() {
 		}),
 	)
 
+	// Let the selected command clean up on Ctrl+C if it can
+	if target := ctx.Selected().Target; target.CanAddr() {
+		if _, ok := target.Addr().Interface().(gracefulInterrupter); ok {
+			gracefulInterrupt.Store(true)
+		}
+	}
+
 	// Set verbose mode if the flag is enabled
 	if CLI.Verbose {
 		logger.VerboseMode = true
 	}
 
+	// Select the container runtime, replacing the [runtime] section of world.toml
+	if err := endpoint.SetOverrides(config.Runtime{Name: CLI.Runtime, Context: CLI.DockerCtx}); err != nil {
+		printer.Errorln(err.Error())
+		return
+	}
+
 	realCtx := contextWithSigterm(context.Background())
 	SetKongParentsAndContext(realCtx, dependencies, &CLI)
 	SetKongParentsAndContext(realCtx, dependencies, &CardinalCmdPlugin)
@@ -145,6 +179,8 @@ func main() {
 	SetKongParentsAndContext(realCtx, dependencies, &ProjectCmdPlugin)
 	SetKongParentsAndContext(realCtx, dependencies, &OrganizationCmdPlugin)
 	SetKongParentsAndContext(realCtx, dependencies, &UserCmdPlugin)
+	SetKongParentsAndContext(realCtx, dependencies, &RegistryCmdPlugin)
+	SetKongParentsAndContext(realCtx, dependencies, &NakamaCmdPlugin)
 	err = ctx.Run()
 	if err != nil {
 		sentry.CaptureException(err)
@@ -324,7 +360,9 @@ func initDependencies(env, version string) (cmdsetup.Dependencies, error) {
 
 	evmHandler := evm.NewHandler()
 
-	cardinalHandler := cardinal.NewHandler()
+	registryHandler := registry.NewHandler(&inputService)
+
+	nakamaHandler := nakama.NewHandler(&inputService)
 
 	setupController := cmdsetup.NewController(
 		configService,
@@ -336,6 +374,7 @@ func initDependencies(env, version string) (cmdsetup.Dependencies, error) {
 	)
 
 	browserClient := browser.NewClient()
+	cardinalHandler := cardinal.NewHandler(browserClient)
 	rootHandler := root.NewHandler(version, configService, apiClient, setupController, browserClient)
 
 	return cmdsetup.Dependencies{
@@ -351,5 +390,7 @@ func initDependencies(env, version string) (cmdsetup.Dependencies, error) {
 		SetupController:     setupController,
 		RootHandler:         rootHandler,
 		CardinalHandler:     cardinalHandler,
+		RegistryHandler:     registryHandler,
+		NakamaHandler:       nakamaHandler,
 	}, nil
 }
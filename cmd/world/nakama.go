@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+
+	cmdsetup "pkg.world.dev/world-cli/internal/app/world-cli/controllers/cmd_setup"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+var NakamaCmdPlugin struct {
+	Nakama *NakamaCmd `cmd:"" group:"Nakama Commands:" help:"Manage the Nakama game server of your local stack"`
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type NakamaCmd struct {
+	Config string `flag:"" type:"existingfile" help:"A TOML config file"`
+	Shard  string `flag:""                     help:"Select a shard by namespace or directory when the repository has several world.toml files"`
+
+	DB *NakamaDBCmd `cmd:"" group:"Nakama Commands:" help:"Back up, restore, inspect and upgrade the Nakama database" name:"db"`
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type NakamaDBCmd struct {
+	Parent *NakamaCmd `kong:"-"`
+
+	Dump    *DumpNakamaDBCmd    `cmd:"" group:"Nakama Commands:" help:"Dump the Nakama database to a file"`
+	Restore *RestoreNakamaDBCmd `cmd:"" group:"Nakama Commands:" help:"Replace the Nakama database with a dump"`
+	Psql    *PsqlNakamaDBCmd    `cmd:"" group:"Nakama Commands:" help:"Open psql on the Nakama database"`
+	Migrate *MigrateNakamaDBCmd `cmd:"" group:"Nakama Commands:" help:"Run the schema migrations of Nakama"`
+	Upgrade *UpgradeNakamaDBCmd `cmd:"" group:"Nakama Commands:" help:"Move the Nakama database to the Postgres image of NAKAMA_DB_IMAGE, keeping its data"`
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type DumpNakamaDBCmd struct {
+	Parent       *NakamaDBCmd          `kong:"-"`
+	Context      context.Context       `kong:"-"`
+	Dependencies cmdsetup.Dependencies `kong:"-"`
+	Output       string                `         flag:"" help:"The dump file, an SQL script when it ends with .sql, defaults to <namespace>-nakama-<time>.dump" short:"o"`
+}
+
+func (c *DumpNakamaDBCmd) Run() error {
+	flags := models.DumpNakamaDBFlags{
+		Config: c.Parent.Parent.Config,
+		Shard:  c.Parent.Parent.Shard,
+		Output: c.Output,
+	}
+	return c.Dependencies.NakamaHandler.DumpDB(c.Context, flags)
+}
+
+type RestoreNakamaDBCmd struct {
+	Parent       *NakamaDBCmd          `kong:"-"`
+	Context      context.Context       `kong:"-"`
+	Dependencies cmdsetup.Dependencies `kong:"-"`
+	File         string                `         arg:"" type:"existingfile" help:"The dump written by world nakama db dump"`
+	Yes          bool                  `         flag:""                    help:"Don't ask for confirmation"          short:"y"`
+}
+
+func (c *RestoreNakamaDBCmd) Run() error {
+	flags := models.RestoreNakamaDBFlags{
+		Config: c.Parent.Parent.Config,
+		Shard:  c.Parent.Parent.Shard,
+		File:   c.File,
+		Yes:    c.Yes,
+	}
+	return c.Dependencies.NakamaHandler.RestoreDB(c.Context, flags)
+}
+
+type PsqlNakamaDBCmd struct {
+	Parent       *NakamaDBCmd          `kong:"-"`
+	Context      context.Context       `kong:"-"`
+	Dependencies cmdsetup.Dependencies `kong:"-"`
+	Command      string                `         flag:"" help:"Run a single SQL command and exit" short:"c"`
+}
+
+func (c *PsqlNakamaDBCmd) Run() error {
+	flags := models.PsqlNakamaDBFlags{
+		Config:  c.Parent.Parent.Config,
+		Shard:   c.Parent.Parent.Shard,
+		Command: c.Command,
+	}
+	return c.Dependencies.NakamaHandler.PsqlDB(c.Context, flags)
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type MigrateNakamaDBCmd struct {
+	Parent       *NakamaDBCmd          `kong:"-"`
+	Context      context.Context       `kong:"-"`
+	Dependencies cmdsetup.Dependencies `kong:"-"`
+	Command      string                `         arg:"" optional:"" help:"The migrate command of Nakama: up, down, redo or status"         default:"up" enum:"up,down,redo,status"`
+	Limit        int                   `         flag:""             help:"The number of migrations to apply or roll back, all of them by default"`
+	Yes          bool                  `         flag:""             help:"Don't ask for confirmation before rolling back migrations"                                          short:"y"`
+}
+
+func (c *MigrateNakamaDBCmd) Run() error {
+	flags := models.MigrateNakamaDBFlags{
+		Config:  c.Parent.Parent.Config,
+		Shard:   c.Parent.Parent.Shard,
+		Command: c.Command,
+		Limit:   c.Limit,
+		Yes:     c.Yes,
+	}
+	return c.Dependencies.NakamaHandler.MigrateDB(c.Context, flags)
+}
+
+type UpgradeNakamaDBCmd struct {
+	Parent       *NakamaDBCmd          `kong:"-"`
+	Context      context.Context       `kong:"-"`
+	Dependencies cmdsetup.Dependencies `kong:"-"`
+	Yes          bool                  `         flag:"" help:"Don't ask for confirmation" short:"y"`
+}
+
+func (c *UpgradeNakamaDBCmd) Run() error {
+	flags := models.UpgradeNakamaDBFlags{
+		Config: c.Parent.Parent.Config,
+		Shard:  c.Parent.Parent.Shard,
+		Yes:    c.Yes,
+	}
+	return c.Dependencies.NakamaHandler.UpgradeDB(c.Context, flags)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+
+	cmdsetup "pkg.world.dev/world-cli/internal/app/world-cli/controllers/cmd_setup"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+var RegistryCmdPlugin struct {
+	Registry *RegistryCmd `cmd:"" group:"Registry Commands:" help:"Manage the credentials of the registries your images are pushed to"`
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type RegistryCmd struct {
+	Login  *LoginRegistryCmd  `cmd:"" group:"Registry Commands:" help:"Log in to a container registry, storing the credentials like docker login"`
+	Logout *LogoutRegistryCmd `cmd:"" group:"Registry Commands:" help:"Log out of a container registry"`
+}
+
+//nolint:lll // needed to put all the help text in the same line
+type LoginRegistryCmd struct {
+	Context       context.Context       `kong:"-"`
+	Dependencies  cmdsetup.Dependencies `kong:"-"`
+	Server        string                `         arg:"" optional:"" help:"The registry to log in to, defaults to Docker Hub"`
+	Username      string                `         flag:""             help:"The username"                                      short:"u"`
+	PasswordStdin bool                  `         flag:""             help:"Read the password or token from stdin"`
+}
+
+func (c *LoginRegistryCmd) Run() error {
+	flags := models.LoginRegistryFlags{
+		Server:        c.Server,
+		Username:      c.Username,
+		PasswordStdin: c.PasswordStdin,
+	}
+	return c.Dependencies.RegistryHandler.Login(c.Context, flags)
+}
+
+type LogoutRegistryCmd struct {
+	Context      context.Context       `kong:"-"`
+	Dependencies cmdsetup.Dependencies `kong:"-"`
+	Server       string                `         arg:"" optional:"" help:"The registry to log out of, defaults to Docker Hub"`
+}
+
+func (c *LogoutRegistryCmd) Run() error {
+	flags := models.LogoutRegistryFlags{
+		Server: c.Server,
+	}
+	return c.Dependencies.RegistryHandler.Logout(c.Context, flags)
+}
@@ -17,6 +17,8 @@ type RootCmd struct {
 	kong.Plugins                 // put this here so tools will be in the right place
 	Version      *VersionCmd     `cmd:"" group:"Additional Commands:" help:"Show the version of the CLI"`
 	Verbose      bool            `                                    help:"Enable World CLI Debug logs"                               flag:"" short:"v"`
+	Runtime      string          `                                    help:"Container runtime to use: docker or podman, detected when not set" flag:""`
+	DockerCtx    string          `                                    help:"Docker context to use, e.g. a remote host over SSH"        flag:"" name:"context"`
 }
 
 //nolint:lll // must be on one line
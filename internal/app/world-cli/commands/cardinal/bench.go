@@ -0,0 +1,131 @@
+package cardinal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/bench"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+	"pkg.world.dev/world-cli/internal/pkg/tea/style"
+)
+
+// benchProgressInterval is how often world cardinal bench prints the progress of the load.
+const benchProgressInterval = 5 * time.Second
+
+func (h *Handler) Bench(ctx context.Context, f models.BenchCardinalFlags) error {
+	cfg, err := getConfig(f.Config, f.Shard)
+	if err != nil {
+		return err
+	}
+
+	scenario, err := bench.LoadScenario(f.Scenario)
+	if err != nil {
+		return err
+	}
+	if f.Personas > 0 {
+		scenario.Personas = f.Personas
+	}
+	if f.Rate > 0 {
+		scenario.Rate = f.Rate
+	}
+	if f.Duration > 0 {
+		scenario.Duration.Duration = f.Duration
+	}
+
+	url := f.URL
+	if url == "" {
+		url = fmt.Sprintf("http://localhost:%d", service.HostPort(cfg, service.PortCardinal))
+	}
+
+	namespace := f.Namespace
+	if namespace == "" {
+		namespace = cfg.DockerEnv["CARDINAL_NAMESPACE"]
+	}
+
+	printer.Infoln(style.CLIHeader("Cardinal Bench", ""))
+	printer.Infof("Sending %g requests/s from %d personas to %s for %s", scenario.Rate, scenario.Personas, url,
+		scenario.Duration)
+	if scenario.RampUp.Duration > 0 {
+		printer.Infof(", after a ramp up of %s", scenario.RampUp)
+	}
+	printer.NewLine(1)
+
+	report, err := bench.Run(ctx, scenario, bench.Options{
+		URL:       url,
+		Namespace: namespace,
+		Progress: func(p bench.Progress) {
+			printer.Infof("%6s  %d requests, %d errors, %d dropped, target %.0f requests/s\n",
+				p.Elapsed.Round(time.Second), p.Requests, p.Errors, p.Dropped, p.Rate)
+		},
+		ProgressInterval: benchProgressInterval,
+	})
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		printer.Notificationln("Interrupted, reporting the load sent so far")
+	}
+
+	printBenchReport(report)
+	if f.Output != "" {
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return eris.Wrap(err, "Failed to encode the report")
+		}
+		if err := os.WriteFile(f.Output, append(content, '\n'), 0644); err != nil { //nolint:gosec // a report
+			return eris.Wrapf(err, "Failed to write %s", f.Output)
+		}
+		printer.Infof("Report written to %s\n", f.Output)
+	}
+	return nil
+}
+
+// printBenchReport prints the totals of the run, then the results of each action.
+func printBenchReport(report *bench.Report) {
+	printer.NewLine(1)
+	printer.Infof("Throughput    %.1f requests/s, target %g requests/s\n", report.Throughput, report.TargetRate)
+	printer.Infof("Errors        %d of %d requests (%.1f%%)\n", report.Errors, report.Requests,
+		100*report.ErrorRate) //nolint:mnd // percentage
+	if report.Dropped > 0 {
+		printer.Notificationf("Dropped       %d requests due while the concurrency limit was reached\n",
+			report.Dropped)
+	}
+	if report.SetupErrors > 0 {
+		printer.Notificationf("Setup errors  %d persona creations or setup messages failed\n", report.SetupErrors)
+	}
+	printer.Infof("Tick rate     %.1f ticks/s\n", report.TickRate)
+	if report.Receipts {
+		printer.Infof("Tick lag      p50 %g, p90 %g, p99 %g, max %g ticks\n",
+			report.TickLag.P50, report.TickLag.P90, report.TickLag.P99, report.TickLag.Max)
+		if report.Unconfirmed > 0 {
+			printer.Notificationf("Unconfirmed   %d messages without a receipt\n", report.Unconfirmed)
+		}
+	} else {
+		printer.Infoln("Tick lag      unknown, Cardinal doesn't list the receipts of the transactions")
+	}
+
+	printer.NewLine(1)
+	printer.Infof("%-24s %-8s %9s %16s %10s %9s %9s %9s %9s\n",
+		"ACTION", "KIND", "REQUESTS", "ERRORS", "REQUESTS/S", "P50 MS", "P90 MS", "P99 MS", "MAX MS")
+	for _, action := range report.Actions {
+		errors := fmt.Sprintf("%d (%.1f%%)", action.Errors, 100*action.ErrorRate) //nolint:mnd // percentage
+		printer.Infof("%-24s %-8s %9d %16s %10.1f %9.1f %9.1f %9.1f %9.1f\n",
+			action.Name, action.Kind, action.Requests, errors, action.Throughput,
+			action.LatencyMs.P50, action.LatencyMs.P90, action.LatencyMs.P99, action.LatencyMs.Max)
+	}
+	for _, action := range report.Actions {
+		if action.ReceiptErrors > 0 {
+			printer.Notificationf("%s: %d messages returned an error from their system\n", action.Name,
+				action.ReceiptErrors)
+		}
+		for _, topError := range action.TopErrors {
+			printer.Errorf("%s: %dx %s\n", action.Name, topError.Count, topError.Error)
+		}
+	}
+}
@@ -4,21 +4,33 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/go-units"
 	"github.com/rotisserie/eris"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
 	"pkg.world.dev/world-cli/internal/app/world-cli/models"
 	"pkg.world.dev/world-cli/internal/pkg/printer"
 	"pkg.world.dev/world-cli/internal/pkg/tea/style"
 )
 
 func (h *Handler) Build(ctx context.Context, f models.BuildCardinalFlags) error {
-	cfg, err := config.GetConfig(&f.Config)
-	if err != nil {
+	if f.PushResult != "" && len(f.Push) == 0 {
+		return eris.New("--push-result requires --push")
+	}
+	if f.WithStack && f.Output == "" {
+		return eris.New("--with-stack requires --output")
+	}
+
+	cfg, err := getConfig(f.Config, f.Shard)
+	if err != nil && f.Shard != "" {
+		return err
+	} else if err != nil {
 		// No config file found, create a default config
 		printer.Infoln("No config file found, creating a default config")
 
@@ -39,7 +51,21 @@ func (h *Handler) Build(ctx context.Context, f models.BuildCardinalFlags) error
 
 		cfg.DockerEnv[DockerCardinalEnvLogLevel] = zerolog.DebugLevel.String()
 	}
+	// Set the namespace
+	if cfg.DockerEnv["CARDINAL_NAMESPACE"] == "" {
+		cfg.DockerEnv["CARDINAL_NAMESPACE"] = "defaultnamespace"
+	}
+	if f.ShowLog {
+		return showBuildLog(cfg)
+	}
 	cfg.Timeout = -1
+	cfg.Debug = f.Debug
+	if err := applyBuildFlags(cfg, buildFlags{
+		args: f.BuildArgs, target: f.Target, platforms: f.Platforms, version: f.Version,
+		ssh: f.SSH, insecure: f.Insecure, noCache: f.NoCache,
+	}); err != nil {
+		return err
+	}
 
 	if f.LogLevel != "" {
 		zeroLogLevel, err := zerolog.ParseLevel(f.LogLevel)
@@ -64,21 +90,20 @@ func (h *Handler) Build(ctx context.Context, f models.BuildCardinalFlags) error
 	// Print out service addresses
 	printServiceAddress("Redis", cfg.DockerEnv["REDIS_ADDRESS"])
 	// this can be changed in code by calling WithPort() on world options, but we have no way to detect that
-	printServiceAddress("Cardinal", fmt.Sprintf("localhost:%s", CardinalPort))
+	printServiceAddress("Cardinal", fmt.Sprintf("localhost:%d", service.HostPort(cfg, service.PortCardinal)))
 	printer.NewLine(2)
 	printer.Infoln("Building Cardinal game shard image...")
 	printer.Infoln("This may take a few minutes.")
 
-	// Set the namespace
-	if cfg.DockerEnv["CARDINAL_NAMESPACE"] == "" {
-		cfg.DockerEnv["CARDINAL_NAMESPACE"] = "defaultnamespace"
-	}
 	printer.Infof("Namespace: %s\n", cfg.DockerEnv["CARDINAL_NAMESPACE"])
+	if len(cfg.CardinalBuild.Platforms) > 0 {
+		printer.Infof("Platforms: %s\n", strings.Join(cfg.CardinalBuild.Platforms, ", "))
+	}
 
 	group, groupCtx := errgroup.WithContext(ctx)
 
 	// Create docker client
-	dockerClient, err := docker.NewClient(cfg)
+	dockerClient, err := docker.NewClient(ctx, cfg)
 	if err != nil {
 		return err
 	}
@@ -86,22 +111,33 @@ func (h *Handler) Build(ctx context.Context, f models.BuildCardinalFlags) error
 
 	services := getCardinalServices(cfg)
 
-	if f.Auth != "" { // FIXME: not sure if this is correct: passed in by then overwritten?
-		if (f.User != "" && f.Pass != "") || f.RegToken != "" {
-			authConfig := registry.AuthConfig{
-				Username:      f.User,
-				Password:      f.Pass,
-				RegistryToken: f.RegToken,
-			}
-			f.Auth, _ = registry.EncodeAuthConfig(authConfig)
+	// The credentials of the Docker config file are used unless they are given explicitly
+	push := docker.PushOptions{
+		Targets:    f.Push,
+		Auth:       f.Auth,
+		ResultFile: f.PushResult,
+	}
+	if (f.User != "" && f.Pass != "") || f.RegToken != "" {
+		authConfig := registry.AuthConfig{
+			Username:      f.User,
+			Password:      f.Pass,
+			RegistryToken: f.RegToken,
+		}
+		if push.Auth, err = registry.EncodeAuthConfig(authConfig); err != nil {
+			return eris.Wrap(err, "Failed to encode the registry credentials")
 		}
 	}
 
 	// Build the World Engine stack
 	group.Go(func() error {
-		if err := dockerClient.Build(groupCtx, f.Push, f.Auth, services...); err != nil {
+		if err := dockerClient.Build(groupCtx, push, services...); err != nil {
 			return eris.Wrap(err, "Encountered an error with Docker")
 		}
+		if f.Output != "" {
+			if err := exportImages(groupCtx, dockerClient, cfg, f.Output, services, f.WithStack); err != nil {
+				return err
+			}
+		}
 		return eris.Wrap(ErrGracefulExit, "Stack terminated")
 	})
 
@@ -112,3 +148,45 @@ func (h *Handler) Build(ctx context.Context, f models.BuildCardinalFlags) error
 
 	return nil
 }
+
+// exportImages saves the built images, and the images the rest of the stack needs when withStack is set,
+// to an archive that world cardinal load imports on a machine without registry access. The stack includes
+// the user defined services and the telemetry services, which may be started with --telemetry.
+func exportImages(ctx context.Context, dockerClient *docker.Client, cfg *config.Config, output string,
+	services []service.Builder, withStack bool) error {
+	if withStack {
+		services = getAllServices(cfg)
+	}
+
+	printer.Infof("Exporting images to %s...\n", output)
+	refs, err := dockerClient.Export(ctx, output, services...)
+	if err != nil {
+		return eris.Wrap(err, "Failed to export images")
+	}
+	for _, ref := range refs {
+		printer.Infof("  %s\n", ref)
+	}
+
+	size := ""
+	if info, err := os.Stat(output); err == nil {
+		size = fmt.Sprintf(" (%s)", units.HumanSize(float64(info.Size())))
+	}
+	printer.Successf("Exported %d images to %s%s, import them with: world cardinal load %s\n",
+		len(refs), output, size, output)
+	return nil
+}
+
+// showBuildLog prints the log of the last build of the Cardinal image of the shard.
+func showBuildLog(cfg *config.Config) error {
+	path, err := docker.LatestBuildLog(service.Cardinal(cfg).Image)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return eris.Wrapf(err, "Failed to read %s", path)
+	}
+	printer.Infof("%s\n\n", path)
+	printer.Info(string(content))
+	return nil
+}
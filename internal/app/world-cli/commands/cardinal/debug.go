@@ -0,0 +1,321 @@
+package cardinal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"golang.org/x/mod/modfile"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+const (
+	// dlvInstallCmd installs Delve, which world cardinal dev --debug and world cardinal debug attach run
+	dlvInstallCmd = "go install github.com/go-delve/delve/cmd/dlv@latest"
+
+	vscodeLaunchFile   = ".vscode/launch.json"
+	golandRunConfigDir = ".idea/runConfigurations"
+
+	startDebugConfigName = "Cardinal (world cardinal start --debug)"
+	devDebugConfigName   = "Cardinal (world cardinal dev --debug)"
+
+	ideAll    = "all"
+	ideVSCode = "vscode"
+	ideGoLand = "goland"
+)
+
+// golandRunConfig is a Go Remote run configuration of GoLand attaching to Delve on the given port.
+//
+//nolint:lll // the XML is written as GoLand writes it
+const golandRunConfig = `<component name="ProjectRunConfigurationManager">
+  <configuration default="false" name="%s" type="GoRemoteDebugConfigurationType" factoryName="Go Remote" host="localhost" port="%d">
+    <option name="disconnectOption" value="LEAVE" />
+    <disconnect value="LEAVE" />
+    <method v="2" />
+  </configuration>
+</component>
+`
+
+func (h *Handler) DebugSetup(_ context.Context, f models.DebugSetupCardinalFlags) error {
+	cfg, err := getConfig(f.Config, f.Shard)
+	if err != nil {
+		return err
+	}
+
+	modulePath, err := cardinalModulePath(cfg)
+	if err != nil {
+		return err
+	}
+	port := service.HostPort(cfg, service.PortCardinalDebug)
+
+	if f.IDE == ideAll || f.IDE == ideVSCode {
+		launchFile := filepath.Join(cfg.RootDir, vscodeLaunchFile)
+		existing, err := os.ReadFile(launchFile)
+		if err != nil && !os.IsNotExist(err) {
+			return eris.Wrapf(err, "Failed to read %s", launchFile)
+		}
+		launch, err := mergeLaunchConfigs(existing, vscodeLaunchConfigs(cfg.GameDir, modulePath, port))
+		if err != nil {
+			return eris.Wrapf(err, "Failed to update %s", launchFile)
+		}
+		if err := writeDebugConfig(launchFile, launch); err != nil {
+			return err
+		}
+	}
+
+	if f.IDE == ideAll || f.IDE == ideGoLand {
+		for file, name := range map[string]string{
+			"Cardinal_start_debug.xml": startDebugConfigName,
+			"Cardinal_dev_debug.xml":   devDebugConfigName,
+		} {
+			runConfig := fmt.Sprintf(golandRunConfig, name, port)
+			if err := writeDebugConfig(filepath.Join(cfg.RootDir, golandRunConfigDir, file), []byte(runConfig)); err != nil {
+				return err
+			}
+		}
+	}
+
+	printer.Infof("Start Cardinal with world cardinal start --debug or world cardinal dev --debug, then attach "+
+		"to localhost:%d from your IDE\n", port)
+	return nil
+}
+
+func (h *Handler) DebugAttach(ctx context.Context, f models.DebugAttachCardinalFlags) error {
+	cfg, err := getConfig(f.Config, f.Shard)
+	if err != nil {
+		return err
+	}
+
+	dlv, err := lookPathDelve()
+	if err != nil {
+		return err
+	}
+
+	// Cardinal runs in its container with world cardinal start --debug, and natively with world cardinal dev
+	// --debug, where the sources are where Delve expects them
+	port := service.HostPort(cfg, service.PortCardinalDebug)
+	// The Delve commands run when the session starts
+	initScript := ""
+	if dockerClient, err := docker.NewClient(ctx, cfg); err == nil {
+		defer dockerClient.Close()
+		published, err := dockerClient.PublishedPort(ctx, service.GetContainerName(cfg, "cardinal"),
+			service.ContainerPort(service.PortCardinalDebug))
+		if err != nil {
+			return err
+		}
+		if published > 0 {
+			port = published
+			modulePath, err := cardinalModulePath(cfg)
+			if err != nil {
+				return err
+			}
+			initScript = fmt.Sprintf("config substitute-path \"%s\" \"%s\"\n", modulePath,
+				filepath.Join(cfg.RootDir, cfg.GameDir))
+		}
+	}
+
+	address := net.JoinHostPort("localhost", strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", address, time.Second)
+	if err != nil {
+		return eris.Errorf("No debugger listens on %s, start Cardinal with world cardinal start --debug or "+
+			"world cardinal dev --debug", address)
+	}
+	_ = conn.Close()
+
+	args := []string{"connect", address}
+	if initScript != "" {
+		initFile, err := os.CreateTemp("", "world-dlv-init-*")
+		if err != nil {
+			return eris.Wrap(err, "Failed to create the Delve init script")
+		}
+		defer os.Remove(initFile.Name())
+		_, err = initFile.WriteString(initScript)
+		if closeErr := initFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return eris.Wrap(err, "Failed to write the Delve init script")
+		}
+		args = append(args, "--init", initFile.Name())
+	}
+
+	cmd := exec.CommandContext(ctx, dlv, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// lookPathDelve returns the path of the dlv binary.
+func lookPathDelve() (string, error) {
+	dlv, err := exec.LookPath("dlv")
+	if err != nil {
+		return "", eris.Errorf("Delve is not installed, install it with: %s", dlvInstallCmd)
+	}
+	return dlv, nil
+}
+
+// cardinalModulePath returns the module path of the game. The Cardinal image is built with -trimpath, so the
+// files of the game are named after the module path in the debug information instead of a directory.
+func cardinalModulePath(cfg *config.Config) (string, error) {
+	goMod := filepath.Join(cfg.RootDir, cfg.GameDir, "go.mod")
+	content, err := os.ReadFile(goMod)
+	if err != nil {
+		return "", eris.Wrapf(err, "Failed to read %s", goMod)
+	}
+	modulePath := modfile.ModulePath(content)
+	if modulePath == "" {
+		return "", eris.Errorf("%s has no module directive", goMod)
+	}
+	return modulePath, nil
+}
+
+// vscodeLaunchConfigs returns the launch configurations of VS Code attaching to Cardinal, in its container
+// or natively.
+func vscodeLaunchConfigs(gameDir string, modulePath string, port int) []map[string]any {
+	attach := func(name string) map[string]any {
+		return map[string]any{
+			"name":    name,
+			"type":    "go",
+			"request": "attach",
+			"mode":    "remote",
+			"host":    "127.0.0.1",
+			"port":    port,
+		}
+	}
+	start := attach(startDebugConfigName)
+	start["substitutePath"] = []map[string]string{{
+		"from": path.Join("${workspaceFolder}", filepath.ToSlash(gameDir)),
+		"to":   modulePath,
+	}}
+	return []map[string]any{start, attach(devDebugConfigName)}
+}
+
+// mergeLaunchConfigs adds the configurations to a launch.json, replacing those with the same name and
+// keeping the others. The comments of the file are dropped.
+func mergeLaunchConfigs(existing []byte, configs []map[string]any) ([]byte, error) {
+	launch := map[string]any{"version": "0.2.0"}
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := json.Unmarshal(stripJSONC(existing), &launch); err != nil {
+			return nil, eris.Wrap(err, "invalid launch.json")
+		}
+	}
+
+	current, _ := launch["configurations"].([]any)
+	for _, launchConfig := range configs {
+		replaced := false
+		for i, other := range current {
+			if other, ok := other.(map[string]any); ok && other["name"] == launchConfig["name"] {
+				current[i] = launchConfig
+				replaced = true
+			}
+		}
+		if !replaced {
+			current = append(current, launchConfig)
+		}
+	}
+	launch["configurations"] = current
+
+	content, err := json.MarshalIndent(launch, "", "  ")
+	if err != nil {
+		return nil, eris.Wrap(err, "Failed to encode launch.json")
+	}
+	return append(content, '\n'), nil
+}
+
+// stripJSONC turns the JSON with comments and trailing commas of VS Code into JSON.
+func stripJSONC(data []byte) []byte {
+	return dropTrailingCommas(dropComments(data))
+}
+
+// dropComments removes the line and block comments outside of strings.
+func dropComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch {
+		case inString:
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+			out = append(out, ch)
+		case ch == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// dropTrailingCommas removes the commas outside of strings that are followed by the end of an object or array.
+func dropTrailingCommas(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch {
+		case inString:
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+			out = append(out, ch)
+		case ch == ',':
+			next := bytes.TrimLeft(data[i+1:], " \t\r\n")
+			if len(next) > 0 && (next[0] == '}' || next[0] == ']') {
+				continue
+			}
+			out = append(out, ch)
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// writeDebugConfig writes a configuration file of an IDE, creating its directory.
+func writeDebugConfig(file string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil { //nolint:gosec // IDE configs are not secret
+		return eris.Wrapf(err, "Failed to create %s", filepath.Dir(file))
+	}
+	if err := os.WriteFile(file, content, 0644); err != nil { //nolint:gosec // IDE configs are not secret
+		return eris.Wrapf(err, "Failed to write %s", file)
+	}
+	printer.Successf("Wrote %s\n", file)
+	return nil
+}
@@ -0,0 +1,63 @@
+package cardinal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+func TestMergeLaunchConfigsKeepsOtherConfigs(t *testing.T) {
+	existing := `{
+  // Use IntelliSense to learn about possible attributes.
+  "version": "0.2.0",
+  "configurations": [
+    {"name": "Tests", "type": "go", "request": "launch", "program": "https://example.com/a//b",},
+    /* replaced */
+    {"name": "` + devDebugConfigName + `", "port": 1},
+  ],
+}`
+	merged, err := mergeLaunchConfigs([]byte(existing), vscodeLaunchConfigs("cardinal", "example.com/game", 40000))
+	assert.NilError(t, err)
+
+	var launch struct {
+		Version        string           `json:"version"`
+		Configurations []map[string]any `json:"configurations"`
+	}
+	assert.NilError(t, json.Unmarshal(merged, &launch))
+	assert.Equal(t, "0.2.0", launch.Version)
+	assert.Equal(t, 3, len(launch.Configurations))
+	assert.Equal(t, "https://example.com/a//b", launch.Configurations[0]["program"])
+	assert.Equal(t, devDebugConfigName, launch.Configurations[1]["name"])
+	assert.Equal(t, float64(40000), launch.Configurations[1]["port"])
+	assert.Equal(t, startDebugConfigName, launch.Configurations[2]["name"])
+
+	_, err = mergeLaunchConfigs([]byte(`{"configurations": [`), nil)
+	assert.ErrorContains(t, err, "invalid launch.json")
+}
+
+func TestDebugSetupWritesTheIDEConfigs(t *testing.T) {
+	rootDir := t.TempDir()
+	configFile := filepath.Join(rootDir, "world.toml")
+	assert.NilError(t, os.WriteFile(configFile, []byte("[cardinal]\nCARDINAL_NAMESPACE = \"alpha\"\n"), 0600))
+	assert.NilError(t, os.Mkdir(filepath.Join(rootDir, "cardinal"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(rootDir, "cardinal", "go.mod"),
+		[]byte("module example.com/game\n\ngo 1.24\n"), 0600))
+
+	h := &Handler{}
+	assert.NilError(t, h.DebugSetup(context.Background(), models.DebugSetupCardinalFlags{Config: configFile, IDE: ideAll}))
+
+	launch, err := os.ReadFile(filepath.Join(rootDir, vscodeLaunchFile))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(launch), `"from": "${workspaceFolder}/cardinal"`), string(launch))
+	assert.Assert(t, strings.Contains(string(launch), `"to": "example.com/game"`), string(launch))
+
+	runConfig, err := os.ReadFile(filepath.Join(rootDir, golandRunConfigDir, "Cardinal_start_debug.xml"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(runConfig), `port="40000"`), string(runConfig))
+}
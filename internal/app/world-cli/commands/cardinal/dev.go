@@ -1,14 +1,15 @@
 package cardinal
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -25,26 +26,68 @@ import (
 )
 
 const (
-	CardinalPort = "4040"
-	RedisPort    = "6379"
-
 	// Cardinal Editor Port Range.
 	cePortStart = 3000
 	cePortEnd   = 4000
 )
 
 func (h *Handler) Dev(ctx context.Context, f models.DevCardinalFlags) error {
-	cfg, err := config.GetConfig(&f.Config)
+	cfg, err := getConfig(f.Config, f.Shard)
 	if err != nil {
 		return err
 	}
 
+	// Cardinal runs natively, the containers reach it through the host
+	cfg.CardinalOnHost = true
+	if f.Nakama {
+		// Build the Nakama Go plugin of the project, if any
+		cfg.Build = true
+	}
+
+	// Without Docker, Redis runs from redis-server on the PATH or in process
+	redisServer := ""
+	if f.NoDocker {
+		if f.Nakama {
+			return eris.New("--nakama runs Nakama in Docker, it can't be used with --no-docker")
+		}
+		if redisServer, err = localRedisServer(f.Persist); err != nil {
+			return err
+		}
+		if err := resolveLocalRedisPort(cfg, f.AutoPorts); err != nil {
+			return err
+		}
+	} else {
+		if f.Persist {
+			return eris.New("--persist only applies to --no-docker, the Redis container keeps its data in a volume")
+		}
+		// Make sure the host ports of the containers are free, picking new ones if requested
+		if err := resolveDevPorts(ctx, cfg, f.AutoPorts, f.Nakama); err != nil {
+			return err
+		}
+	}
+
+	// Delve runs Cardinal in debug mode
+	dlv := ""
+	if f.Debug {
+		if dlv, err = lookPathDelve(); err != nil {
+			return err
+		}
+	}
+
 	// Print out header
 	printer.Infoln(style.CLIHeader("Cardinal", ""))
 
 	// Print out service addresses
-	printServiceAddress("Redis", fmt.Sprintf("localhost:%s", RedisPort))
-	printServiceAddress("Cardinal", fmt.Sprintf("localhost:%s", CardinalPort))
+	printServiceAddress("Redis", fmt.Sprintf("localhost:%d%s", service.HostPort(cfg, service.PortRedis),
+		describeRedis(f.NoDocker, redisServer, persistDir(cfg, f.Persist))))
+	printServiceAddress("Cardinal", fmt.Sprintf("localhost:%d", service.HostPort(cfg, service.PortCardinal)))
+	if f.Debug {
+		printServiceAddress("Cardinal Debugger",
+			fmt.Sprintf("localhost:%d", service.HostPort(cfg, service.PortCardinalDebug)))
+	}
+	if f.Nakama {
+		printPublishedPorts(cfg, service.Nakama, service.NakamaDB)
+	}
 	var port int
 	if f.Editor {
 		port, err = common.FindUnusedPort(cePortStart, cePortEnd)
@@ -57,17 +100,23 @@ func (h *Handler) Dev(ctx context.Context, f models.DevCardinalFlags) error {
 	}
 	printer.NewLine(1)
 
-	// Start redis, cardinal, and cardinal editor
+	// Start redis, nakama, cardinal, and cardinal editor
 	// If any of the services terminates, the entire group will be terminated.
 	group, groupCtx := errgroup.WithContext(ctx)
 	group.Go(func() error {
-		if err := startRedis(groupCtx, cfg); err != nil {
-			return eris.Wrap(err, "Encountered an error with Redis")
+		if f.NoDocker {
+			if err := startLocalRedis(groupCtx, cfg, redisServer, f.Persist); err != nil {
+				return eris.Wrap(err, "Encountered an error with Redis")
+			}
+			return eris.Wrap(ErrGracefulExit, "Redis terminated")
 		}
-		return eris.Wrap(ErrGracefulExit, "Redis terminated")
+		if err := startDevContainers(groupCtx, cfg, f.Nakama); err != nil {
+			return eris.Wrap(err, "Encountered an error with the containers")
+		}
+		return eris.Wrap(ErrGracefulExit, "Containers terminated")
 	})
 	group.Go(func() error {
-		if err := startCardinalDevMode(groupCtx, cfg, f.PrettyLog); err != nil {
+		if err := startCardinalDevMode(groupCtx, cfg, f.PrettyLog, dlv); err != nil {
 			return eris.Wrap(err, "Encountered an error with Cardinal")
 		}
 		return eris.Wrap(ErrGracefulExit, "Cardinal terminated")
@@ -93,35 +142,16 @@ func (h *Handler) Dev(ctx context.Context, f models.DevCardinalFlags) error {
 // Cardinal Helpers //
 //////////////////////
 
-// Otherwise, it runs cardinal using `go run .`.
-func startCardinalDevMode(ctx context.Context, cfg *config.Config, prettyLog bool) error { //nolint:gocognit
+// startCardinalDevMode builds and runs Cardinal, under Delve when dlv is set, and rebuilds and restarts it
+// when the files of the game change.
+func startCardinalDevMode(ctx context.Context, cfg *config.Config, prettyLog bool, dlv string) error {
 	printer.Infoln("Starting Cardinal...")
 	printer.Infoln(style.BoldText.Render("Press Ctrl+C to stop"))
 	printer.NewLine(1)
 
-	// Check and wait until Redis is running and is available in the expected port
-	isRedisHealthy := false
-	for !isRedisHealthy {
-		// using select to allow for context cancellation
-		select {
-		case <-ctx.Done():
-			return eris.Wrap(ctx.Err(), "Context canceled")
-		default:
-			redisAddress := fmt.Sprintf("localhost:%s", RedisPort)
-			conn, err := net.DialTimeout("tcp", redisAddress, time.Second)
-			if err != nil {
-				logger.Printf("Failed to connect to Redis at %s: %s\n", redisAddress, err)
-				time.Sleep(1 * time.Second)
-				continue
-			}
-
-			// Cleanup connection
-			if err := conn.Close(); err != nil {
-				continue
-			}
-
-			isRedisHealthy = true
-		}
+	// Wait until Redis answers PING on the expected port
+	if err := waitForRedis(ctx, cfg); err != nil {
+		return err
 	}
 
 	// Move into the cardinal directory
@@ -137,76 +167,68 @@ func startCardinalDevMode(ctx context.Context, cfg *config.Config, prettyLog boo
 	// Set dev mode environment variables
 	if err := common.WithEnv(
 		map[string]string{
-			"RUNNER_IGNORED":      "assets, tmp, vendor",
+			"REDIS_ADDRESS":       fmt.Sprintf("localhost:%d", service.HostPort(cfg, service.PortRedis)),
+			"CARDINAL_PORT":       strconv.Itoa(service.HostPort(cfg, service.PortCardinal)),
 			"CARDINAL_PRETTY_LOG": strconv.FormatBool(prettyLog),
 		},
 	); err != nil {
 		return eris.Wrap(err, "Failed to set dev mode environment variables")
 	}
 
-	// Create an error group for managing cardinal lifecycle
-	group, ctx := errgroup.WithContext(ctx)
+	runner, err := newDevRunner(filepath.Join(cfg.RootDir, cfg.GameDir), dlv,
+		net.JoinHostPort("localhost", strconv.Itoa(service.HostPort(cfg, service.PortCardinalDebug))))
+	if err != nil {
+		return err
+	}
+	return runner.watch(ctx, cfg.DevIgnore(), time.Duration(cfg.DevDebounceMs())*time.Millisecond)
+}
 
-	// Run cardinal
-	cmd := exec.Command("go", "run", ".")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = os.Environ()
-	group.Go(func() error {
-		if err := cmd.Start(); err != nil {
-			return eris.Wrap(err, "Failed to start Cardinal")
-		}
+////////////////////////
+// Containers Helpers //
+////////////////////////
 
-		if err := cmd.Wait(); err != nil {
-			return err
-		}
-		return nil
-	})
+// devServices returns the containers of dev mode: Redis, and Nakama with its database with --nakama.
+func devServices(nakama bool) []service.Builder {
+	if nakama {
+		return []service.Builder{service.Redis, service.NakamaDB, service.Nakama}
+	}
+	return []service.Builder{service.Redis}
+}
 
-	// Goroutine to handle termination
-	// There are two ways that a termination sequence can be triggered:
-	// 1) The cardinal goroutine returns a non-nil error
-	// 2) The parent context is canceled for whatever reason.
-	group.Go(func() error {
-		<-ctx.Done()
+// resolveDevPorts checks the host ports of the containers used in dev mode. With --nakama, the Nakama
+// container is replaced by one reaching the native Cardinal, so the shard must not be running.
+func resolveDevPorts(ctx context.Context, cfg *config.Config, autoPorts bool, nakama bool) error {
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
 
-		// No need to do anything if cardinal already exited or is not running
-		if cmd.ProcessState == nil || cmd.ProcessState.Exited() {
-			return nil
+	if nakama {
+		shards, err := dockerClient.ListShards(ctx)
+		if err != nil {
+			return err
 		}
-
-		if runtime.GOOS == "windows" {
-			// Sending interrupt signal is not supported in Windows
-			if err := cmd.Process.Kill(); err != nil {
-				return err
-			}
-		} else {
-			if err := cmd.Process.Signal(os.Interrupt); err != nil {
-				return err
+		cardinal := service.GetContainerName(cfg, "cardinal")
+		for _, shard := range shards {
+			if slices.Contains(shard.Containers, cardinal) {
+				return eris.Errorf("%s is running, stop it with world cardinal stop before running "+
+					"world cardinal dev --nakama", cardinal)
 			}
 		}
-
-		return nil
-	})
-
-	if err := group.Wait(); err != nil {
-		return err
 	}
 
-	return nil
+	return resolvePorts(ctx, dockerClient, autoPorts, devServices(nakama)...)
 }
 
-///////////////////
-// Redis Helpers //
-///////////////////
-
-// startRedis runs Redis in a Docker container.
-func startRedis(ctx context.Context, cfg *config.Config) error {
-	// Create an error group for managing redis lifecycle
+// startDevContainers runs Redis, and Nakama with its database with --nakama, in Docker until the context is
+// done. The Nakama container is removed afterwards, since it only works with the native Cardinal.
+func startDevContainers(ctx context.Context, cfg *config.Config, nakama bool) error {
+	// Create an error group for managing the containers lifecycle
 	group := new(errgroup.Group)
 
 	// Create docker client
-	dockerClient, err := docker.NewClient(cfg)
+	dockerClient, err := docker.NewClient(ctx, cfg)
 	if err != nil {
 		return err
 	}
@@ -215,26 +237,37 @@ func startRedis(ctx context.Context, cfg *config.Config) error {
 	// Create context with cancel
 	ctx, cancel := context.WithCancel(ctx)
 
-	// Start Redis container
+	// Start the containers
+	services := devServices(nakama)
 	group.Go(func() error {
 		cfg.Detach = true
-		if err := dockerClient.Start(ctx, service.Redis); err != nil {
+		if nakama {
+			// A Nakama container left by world cardinal start reaches the Cardinal container instead
+			if err := dockerClient.Remove(ctx, service.Nakama); err != nil {
+				cancel()
+				return err
+			}
+		}
+		if err := dockerClient.Start(ctx, services...); err != nil {
 			cancel()
-			return eris.Wrap(err, "Encountered an error with Redis")
+			return eris.Wrap(err, "Encountered an error with the containers")
 		}
 		return nil
 	})
 
 	// Goroutine to handle termination
 	// There are two ways that a termination sequence can be triggered:
-	// 1) The redis start goroutine returns a non-nil error
+	// 1) The start goroutine returns a non-nil error
 	// 2) The parent context is canceled for whatever reason.
 	group.Go(func() error {
 		<-ctx.Done()
 		// Using context background because cmd context is already done
-		if err := dockerClient.Stop(context.Background(), service.Redis); err != nil {
+		if err := dockerClient.Stop(context.Background(), services...); err != nil {
 			return err
 		}
+		if nakama {
+			return dockerClient.Remove(context.Background(), service.Nakama)
+		}
 		return nil
 	})
 
@@ -255,3 +288,63 @@ func printServiceAddress(service string, address string) {
 	addressStr := lipgloss.NewStyle().Render(address)
 	printer.Infoln(serviceStr + arrowStr + addressStr)
 }
+
+// waitForRedis waits until Redis answers PING, authenticated with REDIS_PASSWORD when it is set.
+func waitForRedis(ctx context.Context, cfg *config.Config) error {
+	redisAddress := fmt.Sprintf("localhost:%d", service.HostPort(cfg, service.PortRedis))
+	for {
+		err := pingRedis(redisAddress, cfg.DockerEnv["REDIS_PASSWORD"])
+		if err == nil {
+			return nil
+		}
+		logger.Printf("Redis is not ready at %s: %s\n", redisAddress, err)
+
+		// using select to allow for context cancellation
+		select {
+		case <-ctx.Done():
+			return eris.Wrap(ctx.Err(), "Context canceled")
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+// pingRedis sends PING to Redis, after AUTH when a password is set, and returns an error unless it
+// replies PONG. A Redis still loading its data replies with an error, unlike a plain TCP dial.
+func pingRedis(address, password string) error {
+	conn, err := net.DialTimeout("tcp", address, time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	if password != "" {
+		if err := redisCommand(conn, reader, "+OK", "AUTH", password); err != nil {
+			return err
+		}
+	}
+	return redisCommand(conn, reader, "+PONG", "PING")
+}
+
+// redisCommand sends a command in the Redis protocol and checks its single line reply.
+func redisCommand(conn net.Conn, reader *bufio.Reader, want string, args ...string) error {
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(cmd.String())); err != nil {
+		return err
+	}
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if reply = strings.TrimSpace(reply); reply != want {
+		return eris.Errorf("%s replied %q", args[0], reply)
+	}
+	return nil
+}
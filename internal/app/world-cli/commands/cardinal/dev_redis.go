@@ -0,0 +1,170 @@
+package cardinal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+const (
+	// devDataDir is the directory of the project keeping the data of world cardinal dev --no-docker --persist.
+	devDataDir = ".world"
+
+	// maxPort is the last port --auto-ports tries for the local Redis.
+	maxPort = 65535
+)
+
+// localRedisServer returns the path of the redis-server on the PATH, or an empty string when Redis runs in
+// process. Only redis-server can persist its data.
+func localRedisServer(persist bool) (string, error) {
+	redisServer, err := exec.LookPath("redis-server")
+	if err == nil {
+		return redisServer, nil
+	}
+	if persist {
+		return "", eris.New("--persist needs redis-server on the PATH, install Redis or drop --persist")
+	}
+	return "", nil
+}
+
+// resolveLocalRedisPort makes sure the host port of Redis is free, picking the next free one if requested.
+func resolveLocalRedisPort(cfg *config.Config, autoPorts bool) error {
+	port := service.HostPort(cfg, service.PortRedis)
+	if common.IsPortAvailable(port) {
+		return nil
+	}
+	if !autoPorts {
+		return eris.Errorf("Redis port %d is in use, free it, set [ports] redis in world.toml or use --auto-ports",
+			port)
+	}
+	free, err := common.FindUnusedPort(port+1, maxPort)
+	if err != nil {
+		return eris.Wrap(err, "Failed to find a free port for Redis")
+	}
+	printer.Notificationf("Redis port %d is in use, using %d instead\n", port, free)
+	if cfg.Ports == nil {
+		cfg.Ports = make(map[string]int)
+	}
+	cfg.Ports[service.PortRedis] = free
+	return nil
+}
+
+// startLocalRedis runs Redis without Docker until the context is done: redisServer when it is set, and an
+// in-process Redis compatible server otherwise.
+func startLocalRedis(ctx context.Context, cfg *config.Config, redisServer string, persist bool) error {
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(service.HostPort(cfg, service.PortRedis)))
+	password := cfg.DockerEnv["REDIS_PASSWORD"]
+	if redisServer != "" {
+		return runRedisServer(ctx, redisServer, address, password, persistDir(cfg, persist))
+	}
+	return runEmbeddedRedis(ctx, address, password)
+}
+
+// describeRedis tells where Redis runs from, next to its address.
+func describeRedis(noDocker bool, redisServer string, dataDir string) string {
+	switch {
+	case !noDocker:
+		return ""
+	case redisServer == "":
+		return " (embedded, not persisted)"
+	case dataDir == "":
+		return " (redis-server, not persisted)"
+	default:
+		return fmt.Sprintf(" (redis-server, persisted in %s)", dataDir)
+	}
+}
+
+// persistDir returns the directory Redis keeps its data in, or an empty string when it is not persisted.
+func persistDir(cfg *config.Config, persist bool) string {
+	if !persist {
+		return ""
+	}
+	return filepath.Join(cfg.RootDir, devDataDir, "redis")
+}
+
+// runRedisServer runs redis-server, with its data in dataDir when it is set.
+func runRedisServer(ctx context.Context, redisServer string, address string, password string,
+	dataDir string) error {
+	host, port, _ := net.SplitHostPort(address)
+	args := []string{"--bind", host, "--port", port, "--loglevel", "warning"}
+	if dataDir != "" {
+		if err := os.MkdirAll(dataDir, 0755); err != nil { //nolint:gosec // the dev data is not secret
+			return eris.Wrapf(err, "Failed to create %s", dataDir)
+		}
+		args = append(args, "--dir", dataDir, "--appendonly", "yes")
+	} else {
+		args = append(args, "--save", "", "--appendonly", "no")
+	}
+	if password != "" {
+		args = append(args, "--requirepass", password)
+	}
+
+	cmd := exec.Command(redisServer, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		return eris.Wrap(err, "Failed to start redis-server")
+	}
+	stopped := make(chan error, 1)
+	go func() { stopped <- cmd.Wait() }()
+
+	select {
+	case err := <-stopped:
+		return eris.Errorf("redis-server exited: %v: %s", exitReason(err), strings.TrimSpace(output.String()))
+	case <-ctx.Done():
+	}
+
+	// Redis writes its data when it is interrupted. Sending interrupt signal is not supported in Windows.
+	if runtime.GOOS == "windows" || cmd.Process.Signal(os.Interrupt) != nil {
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-stopped:
+	case <-time.After(devStopTimeout):
+		_ = cmd.Process.Kill()
+		<-stopped
+	}
+	return nil
+}
+
+// runEmbeddedRedis runs an in-process Redis compatible server. Its data is lost when it stops.
+func runEmbeddedRedis(ctx context.Context, address string, password string) error {
+	server := miniredis.NewMiniRedis()
+	if password != "" {
+		server.RequireAuth(password)
+	}
+	if err := server.StartAddr(address); err != nil {
+		return eris.Wrap(err, "Failed to start the embedded Redis")
+	}
+	defer server.Close()
+
+	// The embedded Redis only expires keys when its clock is moved forward
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			server.FastForward(now.Sub(last))
+			last = now
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cardinal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+)
+
+func TestEmbeddedRedisAnswersWithThePassword(t *testing.T) {
+	port, err := common.FindUnusedPort(20000, 30000)
+	assert.NilError(t, err)
+	cfg := &config.Config{
+		RootDir:   t.TempDir(),
+		DockerEnv: map[string]string{"REDIS_PASSWORD": "secret"},
+		Ports:     map[string]int{service.PortRedis: port},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- startLocalRedis(ctx, cfg, "", false) }()
+	assert.NilError(t, waitForRedis(ctx, cfg))
+
+	address := net.JoinHostPort("localhost", strconv.Itoa(port))
+	assert.ErrorContains(t, pingRedis(address, "wrong"), `AUTH replied "-WRONGPASS`)
+	assert.ErrorContains(t, pingRedis(address, ""), `PING replied "-NOAUTH`)
+	assert.NilError(t, pingRedis(address, "secret"))
+
+	// The port is released once the command stops
+	cancel()
+	assert.NilError(t, <-stopped)
+	assert.Assert(t, common.IsPortAvailable(port))
+	assert.Assert(t, errors.Is(pingRedis(address, "secret"), syscall.ECONNREFUSED))
+}
@@ -0,0 +1,198 @@
+package cardinal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/watcher"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+// devStopTimeout is how long Cardinal has to shut down gracefully before it is killed.
+const devStopTimeout = 10 * time.Second
+
+// devRunner builds Cardinal and runs the last build that succeeded.
+type devRunner struct {
+	gameDir string
+	// binDir holds the binaries of the builds, removed when the runner stops
+	binDir string
+	// dlv runs Cardinal under Delve, listening on debugAddr, when set
+	dlv       string
+	debugAddr string
+
+	builds  int
+	bin     string
+	cmd     *exec.Cmd
+	stopped chan error
+}
+
+func newDevRunner(gameDir string, dlv string, debugAddr string) (*devRunner, error) {
+	binDir, err := os.MkdirTemp("", "world-cardinal-dev-*")
+	if err != nil {
+		return nil, eris.Wrap(err, "Failed to create the build directory of Cardinal")
+	}
+	return &devRunner{gameDir: gameDir, binDir: binDir, dlv: dlv, debugAddr: debugAddr}, nil
+}
+
+// watch rebuilds Cardinal when the files of the game change, and restarts it once the build succeeds. A
+// failed build leaves the last good build running. It returns when the context is done.
+func (r *devRunner) watch(ctx context.Context, ignore []string, debounce time.Duration) error {
+	defer os.RemoveAll(r.binDir)
+	defer r.stop()
+
+	fileWatcher, err := watcher.New(r.gameDir, ignore, debounce)
+	if err != nil {
+		return err
+	}
+	defer fileWatcher.Close()
+
+	// Changes made during a build are coalesced into the next one
+	changes := make(chan []string, 1)
+	go func() {
+		_ = fileWatcher.Run(ctx, func(paths []string) {
+			select {
+			case changes <- paths:
+			default:
+			}
+		})
+	}()
+
+	r.rebuild(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case paths := <-changes:
+			printer.Infof("%s changed, rebuilding Cardinal...\n", describeChanges(paths))
+			r.rebuild(ctx)
+		case err := <-r.stopped:
+			r.cmd, r.stopped = nil, nil
+			if ctx.Err() == nil {
+				printer.Errorf("Cardinal exited: %v\n", exitReason(err))
+				printer.Infoln("Waiting for changes to restart it")
+			}
+		}
+	}
+}
+
+// rebuild builds Cardinal and replaces the running build with it when the build succeeds.
+func (r *devRunner) rebuild(ctx context.Context) {
+	started := time.Now()
+	bin, output, err := r.build(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		if r.cmd != nil {
+			printer.Errorln("Build failed, Cardinal keeps running the last build:")
+		} else {
+			printer.Errorln("Build failed, Cardinal starts once it builds:")
+		}
+		printer.Errorln(strings.TrimRight(output, "\n"))
+		_ = os.Remove(bin)
+		return
+	}
+
+	if r.cmd != nil {
+		printer.Infof("Built in %s, restarting Cardinal\n", time.Since(started).Round(100*time.Millisecond))
+		r.stop()
+	}
+	if r.bin != "" {
+		_ = os.Remove(r.bin)
+	}
+	r.bin = bin
+	if err := r.start(); err != nil {
+		printer.Errorf("Failed to start Cardinal: %v\n", err)
+	}
+}
+
+// build builds Cardinal into a new binary, returning the output of the build when it fails.
+func (r *devRunner) build(ctx context.Context) (string, string, error) {
+	r.builds++
+	name := fmt.Sprintf("cardinal-%d", r.builds)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	bin := filepath.Join(r.binDir, name)
+
+	args := []string{"build", "-o", bin}
+	if r.dlv != "" {
+		// Optimizations get in the way of the debugger
+		args = append(args, "-gcflags", "all=-N -l")
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, ".")...)
+	cmd.Dir = r.gameDir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return bin, output.String(), eris.Wrap(err, "Failed to build Cardinal")
+	}
+	return bin, "", nil
+}
+
+// start runs the last build, under Delve in debug mode.
+func (r *devRunner) start() error {
+	cmd := exec.Command(r.bin)
+	if r.dlv != "" {
+		cmd = exec.Command(r.dlv, "exec", r.bin, "--headless", "--api-version=2", "--accept-multiclient",
+			"--continue", "--listen="+r.debugAddr)
+	}
+	cmd.Dir = r.gameDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if err := cmd.Start(); err != nil {
+		return eris.Wrap(err, "Failed to start Cardinal")
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- cmd.Wait() }()
+	r.cmd, r.stopped = cmd, stopped
+	return nil
+}
+
+// stop shuts Cardinal down, and kills it when it doesn't exit in time.
+func (r *devRunner) stop() {
+	if r.cmd == nil {
+		return
+	}
+	defer func() { r.cmd, r.stopped = nil, nil }()
+
+	// Sending interrupt signal is not supported in Windows
+	if runtime.GOOS == "windows" || r.cmd.Process.Signal(os.Interrupt) != nil {
+		_ = r.cmd.Process.Kill()
+	}
+	select {
+	case <-r.stopped:
+	case <-time.After(devStopTimeout):
+		printer.Errorf("Cardinal didn't stop within %s, killing it\n", devStopTimeout)
+		_ = r.cmd.Process.Kill()
+		<-r.stopped
+	}
+}
+
+// describeChanges names the changed files, or counts them when there are many.
+func describeChanges(paths []string) string {
+	const maxNamed = 3
+	if len(paths) <= maxNamed {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d other files", strings.Join(paths[:maxNamed], ", "), len(paths)-maxNamed)
+}
+
+// exitReason describes how Cardinal exited.
+func exitReason(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
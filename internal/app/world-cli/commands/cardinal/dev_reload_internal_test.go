@@ -0,0 +1,131 @@
+package cardinal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+// devMain is a game logging its version when it starts and when it is interrupted, running until then.
+const devMain = `package main
+
+import (
+	"os"
+	"os/signal"
+)
+
+func log(line string) {
+	f, _ := os.OpenFile(%[1]q, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	_, _ = f.WriteString(line + "\n")
+	_ = f.Close()
+}
+
+func main() {
+	log("%[2]s")
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+	log("%[2]s stopped")
+}
+`
+
+// lockedBuffer is a buffer written and read by different goroutines.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureStdout returns what is printed on stdout until the end of the test.
+func captureStdout(t *testing.T) *lockedBuffer {
+	r, w, err := os.Pipe()
+	assert.NilError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	output := &lockedBuffer{}
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(output, r)
+		close(copied)
+	}()
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		_ = w.Close()
+		<-copied
+		_ = r.Close()
+	})
+	return output
+}
+
+func TestDevRunnerKeepsTheLastGoodBuild(t *testing.T) {
+	gameDir := t.TempDir()
+	startsLog := filepath.Join(t.TempDir(), "starts.log")
+	writeMain := func(content string) {
+		assert.NilError(t, os.WriteFile(filepath.Join(gameDir, "main.go"), []byte(content), 0600))
+	}
+	goMod := []byte("module example.com/game\n\ngo 1.24\n")
+	assert.NilError(t, os.WriteFile(filepath.Join(gameDir, "go.mod"), goMod, 0600))
+	writeMain(fmt.Sprintf(devMain, startsLog, "v1"))
+
+	output := captureStdout(t)
+	runner, err := newDevRunner(gameDir, "", "")
+	assert.NilError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runner.watch(ctx, []string{"*_test.go"}, 50*time.Millisecond) }()
+
+	starts := func(want string) func(poll.LogT) poll.Result {
+		return func(poll.LogT) poll.Result {
+			content, _ := os.ReadFile(startsLog)
+			if strings.TrimSpace(string(content)) == want {
+				return poll.Success()
+			}
+			return poll.Continue("started %q", content)
+		}
+	}
+	timeout := poll.WithTimeout(time.Minute)
+	poll.WaitOn(t, starts("v1"), timeout)
+
+	// A build error is reported and doesn't stop the running build
+	writeMain("package main\n\nfunc main() { undefined() }\n")
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if strings.Contains(output.String(), "undefined: undefined") {
+			return poll.Success()
+		}
+		return poll.Continue("no build error printed")
+	}, timeout)
+	assert.Assert(t, strings.Contains(output.String(), "Build failed, Cardinal keeps running the last build"))
+	content, err := os.ReadFile(startsLog)
+	assert.NilError(t, err)
+	assert.Equal(t, "v1\n", string(content))
+
+	// The next good build replaces it
+	writeMain(fmt.Sprintf(devMain, startsLog, "v2"))
+	poll.WaitOn(t, starts("v1\nv1 stopped\nv2"), timeout)
+
+	cancel()
+	assert.NilError(t, <-done)
+	_, err = os.Stat(runner.binDir)
+	assert.Assert(t, os.IsNotExist(err))
+}
@@ -0,0 +1,107 @@
+package cardinal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+func (h *Handler) Images(ctx context.Context, _ models.ImagesCardinalFlags) error {
+	cfg, err := config.GetRuntimeConfig()
+	if err != nil {
+		return err
+	}
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	images, err := dockerClient.ListImages(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(images) == 0 {
+		printer.Infoln("No images built by the World CLI found")
+		return nil
+	}
+
+	namespace := ""
+	for _, img := range images {
+		if img.Namespace != namespace {
+			namespace = img.Namespace
+			printer.NewLine(1)
+			printer.Headerf("  %s  ", namespace)
+			printer.NewLine(1)
+		}
+		printServiceAddress(docker.ShortImageID(img.ID), describeImage(img))
+	}
+
+	return nil
+}
+
+func (h *Handler) PruneImages(ctx context.Context, f models.PruneImagesCardinalFlags) error {
+	if f.Keep < 0 {
+		return eris.New("--keep can't be negative")
+	}
+
+	cfg, err := config.GetRuntimeConfig()
+	if err != nil {
+		return err
+	}
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	removed, err := dockerClient.PruneImages(ctx, f.Keep)
+	if err != nil {
+		return err
+	}
+
+	var reclaimed int64
+	for _, img := range removed {
+		printer.Infof("Removed %s (%s)\n", docker.ShortImageID(img.ID), describeImage(img))
+		reclaimed += img.Size
+		for _, platformImg := range img.Platforms {
+			reclaimed += platformImg.Size
+		}
+	}
+	printer.Successf("Removed %d images, reclaimed %s\n", len(removed), units.HumanSize(float64(reclaimed)))
+
+	return nil
+}
+
+// describeImage returns the tags, size, age and commit of an image on a single line.
+func describeImage(img docker.Image) string {
+	tags := "<untagged>"
+	if len(img.Tags) > 0 {
+		tags = strings.Join(img.Tags, ", ")
+	}
+	parts := []string{
+		tags,
+		units.HumanSize(float64(img.Size)),
+		units.HumanDuration(time.Since(img.Created)) + " ago",
+	}
+	if len(img.Platforms) > 0 {
+		parts = append(parts, fmt.Sprintf("%d more platforms", len(img.Platforms)))
+	}
+	if img.Revision != "" {
+		commit := "commit " + img.Revision
+		if img.Dirty {
+			commit += " (dirty)"
+		}
+		parts = append(parts, commit)
+	}
+	return strings.Join(parts, " · ")
+}
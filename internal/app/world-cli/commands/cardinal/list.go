@@ -0,0 +1,60 @@
+package cardinal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+func (h *Handler) List(ctx context.Context, _ models.ListCardinalFlags) error {
+	cfg, err := config.GetRuntimeConfig()
+	if err != nil {
+		return err
+	}
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	shards, err := dockerClient.ListShards(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(shards) == 0 {
+		printer.Infoln("No running shards found")
+		return nil
+	}
+
+	for _, shard := range shards {
+		printer.NewLine(1)
+		printer.Headerf("  %s  ", shard.Namespace)
+		printer.NewLine(1)
+		if shard.RootDir != "" {
+			printer.Infof("Directory: %s\n", shard.RootDir)
+		}
+		for _, name := range shard.Containers {
+			ports := make([]string, 0, len(shard.Ports[name]))
+			for _, port := range shard.Ports[name] {
+				ports = append(ports, fmt.Sprintf("localhost:%d", port))
+			}
+			address := strings.Join(ports, ", ")
+			switch {
+			case slices.Contains(shard.CrashLooping, name):
+				address += fmt.Sprintf(" (crash looping, restarted %d times)", shard.Restarts[name])
+			case shard.Restarts[name] > 0:
+				address += fmt.Sprintf(" (restarted %d times)", shard.Restarts[name])
+			}
+			printServiceAddress(name, address)
+		}
+	}
+
+	return nil
+}
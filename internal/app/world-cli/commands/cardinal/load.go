@@ -0,0 +1,35 @@
+package cardinal
+
+import (
+	"context"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+func (h *Handler) Load(ctx context.Context, f models.LoadCardinalFlags) error {
+	cfg, err := config.GetRuntimeConfig()
+	if err != nil {
+		return err
+	}
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	printer.Infof("Loading images from %s...\n", f.File)
+	loaded, err := dockerClient.Load(ctx, f.File)
+	if err != nil {
+		return err
+	}
+	for _, ref := range loaded {
+		printer.Infof("  %s\n", ref)
+	}
+	printer.Successf("Loaded %d images, start them without building with: world cardinal start --no-build\n",
+		len(loaded))
+
+	return nil
+}
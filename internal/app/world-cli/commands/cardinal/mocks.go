@@ -43,3 +43,53 @@ func (m *MockHandler) Build(ctx context.Context, flags models.BuildCardinalFlags
 	args := m.Called(ctx, flags)
 	return args.Error(0)
 }
+
+func (m *MockHandler) List(ctx context.Context, flags models.ListCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) Images(ctx context.Context, flags models.ImagesCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) PruneImages(ctx context.Context, flags models.PruneImagesCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) Load(ctx context.Context, flags models.LoadCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) TraceOpen(ctx context.Context, flags models.TraceOpenCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) DebugSetup(ctx context.Context, flags models.DebugSetupCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) DebugAttach(ctx context.Context, flags models.DebugAttachCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) Test(ctx context.Context, flags models.TestCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) Bench(ctx context.Context, flags models.BenchCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) New(ctx context.Context, flags models.NewCardinalFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
@@ -0,0 +1,39 @@
+package cardinal
+
+import (
+	"context"
+	"path/filepath"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/scaffold"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+func (h *Handler) New(_ context.Context, f models.NewCardinalFlags) error {
+	cfg, err := getConfig(f.Config, f.Shard)
+	if err != nil {
+		return err
+	}
+
+	modulePath, err := cardinalModulePath(cfg)
+	if err != nil {
+		return err
+	}
+	gameDir := filepath.Join(cfg.RootDir, cfg.GameDir)
+	result, err := scaffold.Generate(gameDir, modulePath, scaffold.Kind(f.Kind), f.Name)
+	if err != nil {
+		return err
+	}
+
+	file, err := filepath.Rel(cfg.RootDir, result.File)
+	if err != nil {
+		file = result.File
+	}
+	printer.Successf("Created %s\n", file)
+	if result.Registered {
+		printer.Successf("Registered it in %s\n", filepath.Join(cfg.GameDir, "main.go"))
+		return nil
+	}
+	printer.Notificationf("main.go registers no %s yet, add this to MustInitWorld:\n    %s\n", f.Kind, result.Manual)
+	return nil
+}
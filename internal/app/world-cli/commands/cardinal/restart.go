@@ -3,13 +3,12 @@ package cardinal
 import (
 	"context"
 
-	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
 	"pkg.world.dev/world-cli/internal/app/world-cli/models"
 )
 
 func (h *Handler) Restart(ctx context.Context, f models.RestartCardinalFlags) error {
-	cfg, err := config.GetConfig(&f.Config)
+	cfg, err := getConfig(f.Config, f.Shard)
 	if err != nil {
 		return err
 	}
@@ -18,7 +17,7 @@ func (h *Handler) Restart(ctx context.Context, f models.RestartCardinalFlags) er
 	cfg.Detach = f.Detach
 
 	// Create docker client
-	dockerClient, err := docker.NewClient(cfg)
+	dockerClient, err := docker.NewClient(ctx, cfg)
 	if err != nil {
 		return err
 	}
@@ -38,14 +38,31 @@ var (
 
 //nolint:gocognit // this is a naturally complex command
 func (h *Handler) Start(ctx context.Context, f models.StartCardinalFlags) error {
-	cfg, err := config.GetConfig(&f.Config)
+	cfg, err := getConfig(f.Config, f.Shard)
 	if err != nil {
 		return err
 	}
-	cfg.Build = true
+	// Without a build, the stack runs the images already on the machine, e.g. loaded with world cardinal load
+	cfg.Build = !f.NoBuild
 	cfg.Debug = f.Debug
 	cfg.Detach = f.Detach
+	if f.Wait && !f.Detach {
+		return eris.New("--wait requires --detach, the readiness of the stack is printed in the logs otherwise")
+	}
+	cfg.Wait = f.Wait
+	cfg.Timeout = f.WaitTimeout
 	cfg.Telemetry = f.Telemetry
+	if f.Grafana && !f.Telemetry {
+		return eris.New("--grafana requires --telemetry, Grafana shows the metrics and traces it collects")
+	}
+	cfg.Grafana = f.Grafana
+	if err := applyBuildFlags(cfg, buildFlags{
+		args: f.BuildArgs, ssh: f.SSH, insecure: f.Insecure, noCache: f.NoCache,
+	}); err != nil {
+		return err
+	}
+	// The local stack runs the image of the daemon platform, [build] platforms only apply to world cardinal build
+	cfg.CardinalBuild.Platforms = nil
 	if f.LogLevel != "" {
 		zeroLogLevel, err := zerolog.ParseLevel(f.LogLevel)
 		if err != nil {
@@ -64,13 +81,37 @@ func (h *Handler) Start(ctx context.Context, f models.StartCardinalFlags) error
 			DockerCardinalEnvLogLevel, validLogLevels())
 	}
 
+	// Create docker client
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	services := getServices(cfg)
+
+	// Without a build, the images the CLI builds must already be on the machine
+	if f.NoBuild {
+		missing, err := dockerClient.MissingBuiltImages(ctx, services...)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return eris.Errorf("Image %s not found, run world cardinal load <archive> or drop --no-build",
+				strings.Join(missing, ", "))
+		}
+	}
+
+	// Make sure the host ports are free, picking new ones if requested
+	if err := resolvePorts(ctx, dockerClient, f.AutoPorts, services...); err != nil {
+		return err
+	}
+
 	// Print out header
 	printer.Infoln(style.CLIHeader("Cardinal", ""))
 
 	// Print out service addresses
-	printServiceAddress("Redis", cfg.DockerEnv["REDIS_ADDRESS"])
-	// this can be changed in code by calling WithPort() on world options, but we have no way to detect that
-	printServiceAddress("Cardinal", fmt.Sprintf("localhost:%s", CardinalPort))
+	printPublishedPorts(cfg, services...)
 	var editorPort int
 	if f.Editor { //nolint:nestif // this is not overly complex
 		if f.EditorPort == "auto" {
@@ -100,15 +141,6 @@ func (h *Handler) Start(ctx context.Context, f models.StartCardinalFlags) error
 
 	group, groupCtx := errgroup.WithContext(ctx)
 
-	// Create docker client
-	dockerClient, err := docker.NewClient(cfg)
-	if err != nil {
-		return err
-	}
-	defer dockerClient.Close()
-
-	services := getServices(cfg)
-
 	// Start the World Engine stack
 	group.Go(func() error {
 		if err := dockerClient.Start(groupCtx, services...); err != nil {
@@ -151,13 +183,26 @@ func validLogLevels() string {
 
 func getServices(cfg *config.Config) []service.Builder {
 	services := []service.Builder{service.NakamaDB, service.Redis, service.Cardinal, service.Nakama}
-	if cfg.Telemetry && cfg.DockerEnv["NAKAMA_TRACE_ENABLED"] == "true" {
-		services = append(services, service.Jaeger)
+	// Nakama and Cardinal export their traces to Jaeger through the OpenTelemetry collector
+	if service.TracingEnabled(cfg) {
+		services = append(services, service.Jaeger, service.OTelCollector)
 	}
-	if cfg.Telemetry && cfg.DockerEnv["NAKAMA_METRICS_ENABLED"] == "true" {
-		services = append(services, service.Prometheus)
+	// Grafana shows the metrics scraped by Prometheus
+	if cfg.Telemetry && (cfg.DockerEnv["NAKAMA_METRICS_ENABLED"] == "true" || cfg.Grafana) {
+		services = append(services, service.Prometheus, service.RedisExporter)
 	}
-	return services
+	if cfg.Telemetry && cfg.Grafana {
+		services = append(services, service.Grafana)
+	}
+	return append(services, service.CustomServices(cfg)...)
+}
+
+// getAllServices returns every service the stack may have started, regardless of the flags it was started with.
+func getAllServices(cfg *config.Config) []service.Builder {
+	services := []service.Builder{service.Nakama, service.Cardinal,
+		service.NakamaDB, service.Redis, service.Jaeger, service.OTelCollector, service.Prometheus,
+		service.RedisExporter, service.Grafana}
+	return append(services, service.CustomServices(cfg)...)
 }
 
 func getCardinalServices(_ *config.Config) []service.Builder {
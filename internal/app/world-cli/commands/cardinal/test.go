@@ -0,0 +1,317 @@
+package cardinal
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"golang.org/x/sync/errgroup"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/gotest"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+// ErrTestsFailed is returned by world cardinal test when a test or a package failed.
+var ErrTestsFailed = eris.New("Tests failed")
+
+func (h *Handler) Test(ctx context.Context, f models.TestCardinalFlags) error {
+	cfg, err := getConfig(f.Config, f.Shard)
+	if err != nil {
+		return err
+	}
+	packages, goTestFlags := splitTestArgs(f.Args)
+
+	// The run gets its own Redis on a free port and its own namespace, so it never touches a running shard
+	redisServer, err := localRedisServer(false)
+	if err != nil {
+		return err
+	}
+	port, err := freeLocalPort()
+	if err != nil {
+		return err
+	}
+	if cfg.Ports == nil {
+		cfg.Ports = make(map[string]int)
+	}
+	cfg.Ports[service.PortRedis] = port
+	namespace, err := testNamespace(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Coverage is written to a temporary profile unless it is kept
+	tmpDir, err := os.MkdirTemp("", "world-cardinal-test-*")
+	if err != nil {
+		return eris.Wrap(err, "Failed to create the directory of the test run")
+	}
+	defer os.RemoveAll(tmpDir)
+	keptProfile := f.CoverProfile
+	if profile, ok := goTestFlag(goTestFlags, "coverprofile"); ok {
+		if keptProfile != "" {
+			return eris.New("--cover-profile and the -coverprofile flag of go test can't be used together")
+		}
+		// go test runs in GameDir
+		keptProfile = profile
+		if !filepath.IsAbs(profile) {
+			keptProfile = filepath.Join(cfg.RootDir, cfg.GameDir, profile)
+		}
+	}
+	coverProfile := keptProfile
+	if coverProfile == "" {
+		coverProfile = filepath.Join(tmpDir, "cover.out")
+	}
+
+	printer.Infof("Testing %s with Redis at localhost:%d%s\n", strings.Join(testPackages(packages), " "),
+		port, describeRedis(true, redisServer, ""))
+
+	// Redis is stopped once the tests are done, or when they are interrupted
+	redisCtx, stopRedis := context.WithCancel(ctx)
+	defer stopRedis()
+	group, groupCtx := errgroup.WithContext(redisCtx)
+	group.Go(func() error {
+		return startLocalRedis(groupCtx, cfg, redisServer, false)
+	})
+
+	report := gotest.NewReport()
+	testErr := func() error {
+		defer stopRedis()
+		if err := waitForRedis(groupCtx, cfg); err != nil {
+			return err
+		}
+		return runGoTest(groupCtx, cfg, namespace, packages, goTestFlags, coverProfile, report)
+	}()
+	// Redis failing stops the tests, its error tells why
+	if err := group.Wait(); err != nil {
+		testErr = eris.Wrap(err, "Encountered an error with Redis")
+	}
+	if ctx.Err() != nil {
+		return eris.Wrap(ctx.Err(), "Tests interrupted")
+	}
+
+	if f.JUnit != "" {
+		if err := writeJUnit(report, f.JUnit); err != nil {
+			return err
+		}
+		printer.Infof("JUnit report written to %s\n", f.JUnit)
+	}
+	if total, err := coverageTotal(ctx, cfg, coverProfile); err == nil {
+		printer.Infof("Total coverage: %s of statements\n", total)
+		if keptProfile != "" {
+			printer.Infof("Coverage profile written to %s\n", keptProfile)
+		}
+	}
+
+	if testErr != nil {
+		return testErr
+	}
+	if report.Failed() {
+		return ErrTestsFailed
+	}
+	return nil
+}
+
+// runGoTest runs go test in GameDir with the env of world.toml and the test Redis, printing the output like
+// go test does and recording the results in the report. Coverage is collected unless the go test flags set it.
+func runGoTest(ctx context.Context, cfg *config.Config, namespace string, packages []string, goTestFlags []string,
+	coverProfile string, report *gotest.Report) error {
+	args := []string{"test", "-json"}
+	if _, ok := goTestFlag(goTestFlags, "coverprofile"); !ok {
+		args = append(args, "-coverprofile="+coverProfile)
+	}
+	if _, ok := goTestFlag(goTestFlags, "coverpkg"); !ok {
+		args = append(args, "-coverpkg=./...")
+	}
+	args = append(append(args, goTestFlags...), testPackages(packages)...)
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = filepath.Join(cfg.RootDir, cfg.GameDir)
+	cmd.Env = append(os.Environ(), testEnv(cfg, namespace)...)
+	cmd.Stderr = os.Stderr
+	interruptGroup(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return eris.Wrap(err, "Failed to read the output of go test")
+	}
+	if err := cmd.Start(); err != nil {
+		return eris.Wrap(err, "Failed to run go test")
+	}
+	printTestEvents(stdout, report, verboseTest(goTestFlags))
+
+	if err := cmd.Wait(); err != nil {
+		// go test exits with 1 when tests fail, which the report tells
+		var exitErr *exec.ExitError
+		if eris.As(err, &exitErr) && report.Failed() {
+			return nil
+		}
+		return eris.Wrap(err, "go test failed")
+	}
+	return nil
+}
+
+// printTestEvents reads the events of go test -json into the report. Unless verbose, only the build errors,
+// the output of the failed tests and the package summaries are printed, like go test does.
+func printTestEvents(r io.Reader, report *gotest.Report, verbose bool) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) //nolint:mnd // long test output lines
+	for scanner.Scan() {
+		var event gotest.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			printer.Infoln(scanner.Text())
+			continue
+		}
+		report.Add(event)
+
+		switch {
+		case event.Action == gotest.ActionBuildOutput:
+			printer.Info(event.Output)
+		case verbose:
+			if event.Action == gotest.ActionOutput {
+				printer.Info(event.Output)
+			}
+		case event.Action == gotest.ActionFail && event.Test != "":
+			printer.Info(report.Output(event.Package, event.Test))
+		case event.Action == gotest.ActionOutput && event.Test == "" && isPackageSummary(event.Output):
+			printer.Info(event.Output)
+		}
+	}
+}
+
+// isPackageSummary returns true for the line go test prints for each package.
+func isPackageSummary(line string) bool {
+	return strings.HasPrefix(line, "ok  ") || strings.HasPrefix(line, "FAIL\t") || strings.HasPrefix(line, "?   ")
+}
+
+// verboseTest returns true when the go test flags ask for the output of every test. The -v flag of the World
+// CLI, which is parsed before the go test flags, asks for it too.
+func verboseTest(goTestFlags []string) bool {
+	value, ok := goTestFlag(goTestFlags, "v")
+	return logger.VerboseMode || ok && value != "false"
+}
+
+// splitTestArgs splits the arguments of world cardinal test into the packages and the go test flags. The
+// packages come first, the flags start at the first argument starting with - or after --.
+func splitTestArgs(args []string) ([]string, []string) {
+	i := slices.IndexFunc(args, func(arg string) bool { return strings.HasPrefix(arg, "-") })
+	if i < 0 {
+		return args, nil
+	}
+	flags := slices.Clone(args[i:])
+	if flags[0] == "--" {
+		flags = flags[1:]
+	}
+	return args[:i], flags
+}
+
+// goTestFlag returns the value of a flag among the go test flags, in any of the forms go test accepts, and
+// whether it is set. A flag set without a value, like -v, has an empty value.
+func goTestFlag(goTestFlags []string, name string) (string, bool) {
+	value, found := "", false
+	for i, flag := range goTestFlags {
+		if !strings.HasPrefix(flag, "-") {
+			continue
+		}
+		flag = strings.TrimPrefix(strings.TrimPrefix(flag, "-"), "-")
+		flag = strings.TrimPrefix(flag, "test.")
+		if flag == name {
+			// The value may be the next argument, e.g. -coverprofile cover.out
+			value, found = "", true
+			if i+1 < len(goTestFlags) && !strings.HasPrefix(goTestFlags[i+1], "-") {
+				value = goTestFlags[i+1]
+			}
+		} else if flagName, flagValue, ok := strings.Cut(flag, "="); ok && flagName == name {
+			value, found = flagValue, true
+		}
+	}
+	return value, found
+}
+
+// testPackages returns the packages to test, every package of GameDir by default.
+func testPackages(packages []string) []string {
+	if len(packages) == 0 {
+		return []string{"./..."}
+	}
+	return packages
+}
+
+// testEnv returns the env of world.toml with the address of the test Redis and the namespace of the run.
+func testEnv(cfg *config.Config, namespace string) []string {
+	env := make([]string, 0, len(cfg.DockerEnv)+2) //nolint:mnd // the overridden variables
+	for key, value := range cfg.DockerEnv {
+		if key == "CARDINAL_NAMESPACE" || key == "REDIS_ADDRESS" {
+			continue
+		}
+		env = append(env, key+"="+value)
+	}
+	slices.Sort(env)
+	return append(env,
+		"CARDINAL_NAMESPACE="+namespace,
+		"REDIS_ADDRESS="+net.JoinHostPort("localhost", strconv.Itoa(service.HostPort(cfg, service.PortRedis))),
+	)
+}
+
+// testNamespace returns the namespace of the shard with a random suffix, unique to the run.
+func testNamespace(cfg *config.Config) (string, error) {
+	suffix := make([]byte, 4) //nolint:mnd // 8 hex characters
+	if _, err := rand.Read(suffix); err != nil {
+		return "", eris.Wrap(err, "Failed to generate the namespace of the test run")
+	}
+	return fmt.Sprintf("%s-test-%s", cfg.DockerEnv["CARDINAL_NAMESPACE"], hex.EncodeToString(suffix)), nil
+}
+
+// freeLocalPort returns a port of the loopback interface that is free, picked by the system.
+func freeLocalPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, eris.Wrap(err, "Failed to find a free port for Redis")
+	}
+	defer listener.Close()
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return 0, eris.New("Failed to find a free port for Redis")
+	}
+	return addr.Port, nil
+}
+
+// writeJUnit writes the JUnit XML report of the run to the file.
+func writeJUnit(report *gotest.Report, file string) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return eris.Wrapf(err, "Failed to create %s", file)
+	}
+	defer out.Close()
+	return report.WriteJUnit(out)
+}
+
+// coverageTotal returns the percentage of the statements covered by the run, from its coverage profile.
+func coverageTotal(ctx context.Context, cfg *config.Config, coverProfile string) (string, error) {
+	if _, err := os.Stat(coverProfile); err != nil {
+		return "", eris.Wrap(err, "No coverage profile")
+	}
+	cmd := exec.CommandContext(ctx, "go", "tool", "cover", "-func="+coverProfile)
+	cmd.Dir = filepath.Join(cfg.RootDir, cfg.GameDir)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to read the coverage profile")
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) == 0 || fields[0] != "total:" {
+		return "", eris.New("No total in the coverage profile")
+	}
+	return fields[len(fields)-1], nil
+}
@@ -0,0 +1,117 @@
+package cardinal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+// gameTest checks the env of world cardinal test: Redis answers with the password of world.toml, and the
+// namespace is the one of the shard with a suffix.
+const gameTest = `package game
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedis(t *testing.T) {
+	conn, err := net.Dial("tcp", os.Getenv("REDIS_ADDRESS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("*2\r\n$4\r\nAUTH\r\n$6\r\nsecret\r\n*1\r\n$4\r\nPING\r\n"))
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"+OK", "+PONG"} {
+		if reply, _ := reader.ReadString('\n'); strings.TrimSpace(reply) != want {
+			t.Fatalf("got %q, want %q", reply, want)
+		}
+	}
+	if !strings.HasPrefix(os.Getenv("CARDINAL_NAMESPACE"), "alpha-test-") {
+		t.Fatal(os.Getenv("CARDINAL_NAMESPACE"))
+	}
+}
+
+func TestFails(t *testing.T) {
+	t.Error("boom")
+}
+`
+
+func TestTestRunsAgainstAnEphemeralRedis(t *testing.T) {
+	rootDir := t.TempDir()
+	configFile := filepath.Join(rootDir, "world.toml")
+	assert.NilError(t, os.WriteFile(configFile,
+		[]byte("[cardinal]\nCARDINAL_NAMESPACE = \"alpha\"\nREDIS_PASSWORD = \"secret\"\n"), 0600))
+	gameDir := filepath.Join(rootDir, "cardinal")
+	assert.NilError(t, os.Mkdir(gameDir, 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(gameDir, "go.mod"),
+		[]byte("module example.com/game\n\ngo 1.24\n"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(gameDir, "game_test.go"), []byte(gameTest), 0600))
+
+	junit := filepath.Join(rootDir, "junit.xml")
+	h := &Handler{}
+	err := h.Test(context.Background(), models.TestCardinalFlags{Config: configFile, JUnit: junit})
+	assert.ErrorIs(t, err, ErrTestsFailed)
+
+	report, err := os.ReadFile(junit)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(report), `<testsuites tests="2" failures="1" skipped="0"`), string(report))
+	assert.Assert(t, strings.Contains(string(report), `<testcase name="TestRedis" classname="example.com/game"`))
+
+	// The flags of go test come without --, and its coverage flags replace those of the run
+	err = h.Test(context.Background(), models.TestCardinalFlags{Config: configFile,
+		Args: []string{"-run", "TestRedis", "-coverprofile=mine.out"}})
+	assert.NilError(t, err)
+	_, err = os.Stat(filepath.Join(gameDir, "mine.out"))
+	assert.NilError(t, err)
+}
+
+func TestSplitTestArgs(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		packages []string
+		flags    []string
+	}{
+		{nil, nil, nil},
+		{[]string{"./system/..."}, []string{"./system/..."}, nil},
+		{[]string{"-v"}, []string{}, []string{"-v"}},
+		{[]string{"-run", "TestMove", "-v"}, []string{}, []string{"-run", "TestMove", "-v"}},
+		{[]string{"./system", "--", "-count=1"}, []string{"./system"}, []string{"-count=1"}},
+		{[]string{"--", "-v"}, []string{}, []string{"-v"}},
+	} {
+		packages, flags := splitTestArgs(test.args)
+		assert.DeepEqual(t, test.packages, packages)
+		assert.DeepEqual(t, test.flags, flags)
+	}
+
+	// The packages default to every package of the game
+	packages, flags := splitTestArgs([]string{"-v"})
+	assert.DeepEqual(t, []string{"./..."}, testPackages(packages))
+	assert.Assert(t, verboseTest(flags))
+	assert.Assert(t, !verboseTest([]string{"-v=false"}))
+	assert.Assert(t, verboseTest([]string{"-test.v"}))
+}
+
+func TestGoTestFlag(t *testing.T) {
+	for _, flags := range [][]string{
+		{"-coverprofile=c.out"},
+		{"--coverprofile=c.out"},
+		{"-test.coverprofile=c.out"},
+		{"-v", "-coverprofile", "c.out", "-count=1"},
+	} {
+		value, ok := goTestFlag(flags, "coverprofile")
+		assert.Assert(t, ok, flags)
+		assert.Equal(t, "c.out", value, flags)
+	}
+	_, ok := goTestFlag([]string{"-coverpkg=./..."}, "coverprofile")
+	assert.Assert(t, !ok)
+}
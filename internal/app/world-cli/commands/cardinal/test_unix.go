@@ -0,0 +1,18 @@
+//go:build !windows
+
+package cardinal
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// interruptGroup runs the command in its own process group, interrupted as a whole when its context is done.
+// go test doesn't pass the interrupt on to the test binaries it runs, killing it alone leaves them running.
+func interruptGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+	}
+	cmd.WaitDelay = devStopTimeout
+}
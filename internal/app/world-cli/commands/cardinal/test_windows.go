@@ -0,0 +1,11 @@
+//go:build windows
+
+package cardinal
+
+import (
+	"os/exec"
+)
+
+// interruptGroup keeps the default cancellation of the command, sending interrupt signal is not supported
+// in Windows.
+func interruptGroup(*exec.Cmd) {}
@@ -0,0 +1,61 @@
+package cardinal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+func (h *Handler) TraceOpen(ctx context.Context, f models.TraceOpenCardinalFlags) error {
+	cfg, err := getConfig(f.Config, f.Shard)
+	if err != nil {
+		return err
+	}
+
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	shards, err := dockerClient.ListShards(ctx)
+	if err != nil {
+		return err
+	}
+	namespace := cfg.DockerEnv["CARDINAL_NAMESPACE"]
+	jaeger := service.Jaeger(cfg).Name
+	port := service.HostPort(cfg, service.PortJaeger)
+	running := false
+	for _, shard := range shards {
+		if shard.Namespace == namespace && slices.Contains(shard.Containers, jaeger) {
+			running = true
+			// The port may have been picked by --auto-ports
+			if ports := shard.Ports[jaeger]; len(ports) > 0 {
+				port = int(ports[0])
+			}
+		}
+	}
+	if !running {
+		return eris.Errorf("%s is not running, start the shard with: world cardinal start --telemetry", jaeger)
+	}
+
+	traceURL := jaegerSearchURL(port, f.Service, namespace)
+	printer.Infof("Opening %s\n", traceURL)
+	return h.browserClient.OpenURL(traceURL)
+}
+
+// jaegerSearchURL returns the Jaeger UI searching the traces of a service of the given namespace, which the
+// OpenTelemetry collector sets on every span.
+func jaegerSearchURL(port int, serviceName string, namespace string) string {
+	query := url.Values{}
+	query.Set("service", serviceName)
+	query.Set("tags", fmt.Sprintf(`{"service.namespace":%q}`, namespace))
+	return fmt.Sprintf("http://localhost:%d/search?%s", port, query.Encode())
+}
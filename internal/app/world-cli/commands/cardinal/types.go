@@ -1,12 +1,18 @@
 package cardinal
 
-import "pkg.world.dev/world-cli/internal/app/world-cli/interfaces"
+import (
+	"pkg.world.dev/world-cli/internal/app/world-cli/clients/browser"
+	"pkg.world.dev/world-cli/internal/app/world-cli/interfaces"
+)
 
 var _ interfaces.CardinalHandler = &Handler{}
 
 type Handler struct {
+	browserClient browser.ClientInterface
 }
 
-func NewHandler() interfaces.CardinalHandler {
-	return &Handler{}
+func NewHandler(browserClient browser.ClientInterface) interfaces.CardinalHandler {
+	return &Handler{
+		browserClient: browserClient,
+	}
 }
@@ -0,0 +1,63 @@
+package cardinal
+
+import (
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+// getConfig returns the config of the given shard, or the config found from the config flag
+// and the current directory when no shard is selected.
+func getConfig(configFile string, shard string) (*config.Config, error) {
+	if shard == "" {
+		return config.GetConfig(&configFile)
+	}
+	if configFile != "" {
+		return nil, eris.New("--config and --shard can't be used together")
+	}
+	return config.GetShardConfig(shard)
+}
+
+// buildFlags are the command flags overriding the [build] section of world.toml.
+type buildFlags struct {
+	args      []string
+	target    string
+	platforms []string
+	version   string
+	ssh       bool
+	insecure  bool
+	noCache   bool
+}
+
+// applyBuildFlags overrides the build settings of the Cardinal image from world.toml with the command flags.
+func applyBuildFlags(cfg *config.Config, f buildFlags) error {
+	for _, arg := range f.args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			return eris.Errorf("invalid build arg %q, must be KEY=VALUE", arg)
+		}
+		cfg.CardinalBuild.SetArg(key, value)
+	}
+	if f.target != "" {
+		if cfg.Debug {
+			cfg.CardinalBuild.DebugTarget = f.target
+		} else {
+			cfg.CardinalBuild.Target = f.target
+		}
+	}
+	if len(f.platforms) > 0 {
+		if err := cfg.CardinalBuild.SetPlatforms(f.platforms); err != nil {
+			return err
+		}
+	}
+	if f.version != "" {
+		if err := cfg.CardinalBuild.SetVersion(f.version); err != nil {
+			return err
+		}
+	}
+	cfg.CardinalBuild.SSH = cfg.CardinalBuild.SSH || f.ssh
+	cfg.CardinalBuild.AllowInsecureSecrets = cfg.CardinalBuild.AllowInsecureSecrets || f.insecure
+	cfg.CardinalBuild.NoCache = f.noCache
+	return nil
+}
@@ -0,0 +1,39 @@
+package cardinal
+
+import (
+	"context"
+	"fmt"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+// resolvePorts makes sure every host port of the given services can be bound.
+// With autoPorts, conflicting ports are moved to free ports instead of failing.
+func resolvePorts(ctx context.Context, dockerClient *docker.Client, autoPorts bool,
+	serviceBuilders ...service.Builder) error {
+	if !autoPorts {
+		return dockerClient.VerifyPorts(ctx, serviceBuilders...)
+	}
+
+	remapped, err := dockerClient.AssignFreePorts(ctx, serviceBuilders...)
+	if err != nil {
+		return err
+	}
+	for _, conflict := range remapped {
+		printer.Notificationf("%s port %d %s, using %d instead\n",
+			conflict.Port.Label, conflict.Port.Host, conflict.Reason, conflict.Remapped)
+	}
+	return nil
+}
+
+// printPublishedPorts prints the host address of every port published by the given services.
+func printPublishedPorts(cfg *config.Config, serviceBuilders ...service.Builder) {
+	for _, sb := range serviceBuilders {
+		for _, port := range sb(cfg).Ports {
+			printServiceAddress(port.Label, fmt.Sprintf("localhost:%d", port.Host))
+		}
+	}
+}
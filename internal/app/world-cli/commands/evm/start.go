@@ -32,7 +32,7 @@ func (h *Handler) Start(ctx context.Context, flags models.StartEVMFlags) error {
 	}
 
 	// Create docker client
-	dockerClient, err := docker.NewClient(cfg)
+	dockerClient, err := docker.NewClient(ctx, cfg)
 	if err != nil {
 		return err
 	}
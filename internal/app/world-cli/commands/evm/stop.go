@@ -17,7 +17,7 @@ func (h *Handler) Stop(ctx context.Context, flags models.StopEVMFlags) error {
 	}
 
 	// Create docker client
-	dockerClient, err := docker.NewClient(cfg)
+	dockerClient, err := docker.NewClient(ctx, cfg)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,258 @@
+package nakama
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/rotisserie/eris"
+	"golang.org/x/term"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+const (
+	// backupDirName is the directory of the backups made before upgrades, inside the World CLI config directory
+	backupDirName = "backups"
+	// dumpTimeFormat is the format of the time ending the names of the dumps, which sorts chronologically
+	dumpTimeFormat = "20060102-150405"
+	// sqlDumpExt is the extension of the dumps written as SQL scripts, other dumps use the custom format
+	sqlDumpExt = ".sql"
+	// upgradeImageExample is the image suggested when NAKAMA_DB_IMAGE is not set to a new image
+	upgradeImageExample = "postgres:16-alpine"
+)
+
+func (h *Handler) DumpDB(ctx context.Context, flags models.DumpNakamaDBFlags) error {
+	cfg, err := getConfig(flags.Config, flags.Shard)
+	if err != nil {
+		return err
+	}
+
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	output := flags.Output
+	if output == "" {
+		output = dumpFileName(cfg, time.Now())
+	}
+	if err := dockerClient.DumpNakamaDB(ctx, output, isSQLDump(output)); err != nil {
+		return eris.Wrap(err, "Failed to dump the Nakama database")
+	}
+
+	size := ""
+	if info, err := os.Stat(output); err == nil {
+		size = " (" + units.HumanSize(float64(info.Size())) + ")"
+	}
+	printer.Successf("Dumped the Nakama database to %s%s\n", output, size)
+	return nil
+}
+
+func (h *Handler) RestoreDB(ctx context.Context, flags models.RestoreNakamaDBFlags) error {
+	cfg, err := getConfig(flags.Config, flags.Shard)
+	if err != nil {
+		return err
+	}
+
+	dump, err := os.Open(flags.File)
+	if err != nil {
+		return eris.Wrap(err, "Failed to open the dump")
+	}
+	defer dump.Close()
+
+	prompt := fmt.Sprintf("This replaces the Nakama database of %s with %s. Continue?",
+		cfg.DockerEnv["CARDINAL_NAMESPACE"], flags.File)
+	if ok, err := h.confirm(ctx, flags.Yes, prompt); !ok || err != nil {
+		return err
+	}
+
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	if err := dockerClient.RestoreNakamaDB(ctx, dump); err != nil {
+		return eris.Wrap(err, "Failed to restore the Nakama database")
+	}
+	printer.Successf("Restored the Nakama database from %s\n", flags.File)
+	return nil
+}
+
+func (h *Handler) PsqlDB(ctx context.Context, flags models.PsqlNakamaDBFlags) error {
+	cfg, err := getConfig(flags.Config, flags.Shard)
+	if err != nil {
+		return err
+	}
+
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	// Start the database before the terminal is switched to raw mode
+	if err := dockerClient.StartNakamaDB(ctx); err != nil {
+		return err
+	}
+
+	var args []string
+	opts := docker.ExecOptions{Stdout: h.stdout, Stderr: h.stderr}
+	fd := int(os.Stdin.Fd()) //nolint:gosec // file descriptors fit in an int
+	switch {
+	case flags.Command != "":
+		args = []string{"--command", flags.Command}
+	case term.IsTerminal(fd):
+		if width, height, err := term.GetSize(fd); err == nil {
+			opts.ConsoleSize = &[2]uint{uint(height), uint(width)} //nolint:gosec // sizes are positive
+		}
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return eris.Wrap(err, "Failed to set up the terminal")
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+		opts.TTY = true
+		opts.Stdin = h.stdin
+	default:
+		// A script piped to psql stops at its first error
+		args = []string{"--set", "ON_ERROR_STOP=1"}
+		opts.Stdin = h.stdin
+	}
+	return dockerClient.NakamaDBShell(ctx, args, opts)
+}
+
+func (h *Handler) MigrateDB(ctx context.Context, flags models.MigrateNakamaDBFlags) error {
+	cfg, err := getConfig(flags.Config, flags.Shard)
+	if err != nil {
+		return err
+	}
+
+	// Rolling back migrations drops the data of their tables
+	if flags.Command == "down" || flags.Command == "redo" {
+		prompt := fmt.Sprintf("nakama migrate %s rolls back migrations of the Nakama database of %s and may "+
+			"delete data. Continue?", flags.Command, cfg.DockerEnv["CARDINAL_NAMESPACE"])
+		if ok, err := h.confirm(ctx, flags.Yes, prompt); !ok || err != nil {
+			return err
+		}
+	}
+
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.MigrateNakama(ctx, flags.Command, flags.Limit,
+		docker.ExecOptions{Stdout: h.stdout, Stderr: h.stderr})
+}
+
+func (h *Handler) UpgradeDB(ctx context.Context, flags models.UpgradeNakamaDBFlags) error {
+	cfg, err := getConfig(flags.Config, flags.Shard)
+	if err != nil {
+		return err
+	}
+
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	namespace := cfg.DockerEnv["CARDINAL_NAMESPACE"]
+	current, err := dockerClient.NakamaDBContainerImage(ctx)
+	if err != nil {
+		return err
+	}
+	target := service.NakamaDBImage(cfg)
+	if current == "" {
+		return eris.Errorf("%s has no Nakama database to upgrade, world cardinal start creates it with %s",
+			namespace, target)
+	}
+	if current == target {
+		return eris.Errorf("The Nakama database of %s already runs %s, set %s in the [nakama] section of "+
+			"world.toml to the image to upgrade to, e.g. %q", namespace, current, service.NakamaDBImageKey,
+			upgradeImageExample)
+	}
+
+	backupPath, err := backupFileName(cfg, time.Now())
+	if err != nil {
+		return err
+	}
+	printer.Infof("Upgrading the Nakama database of %s from %s to %s:\n", namespace, current, target)
+	printer.Infof("  1. Dump it to %s\n", backupPath)
+	printer.Infoln("  2. Remove its container and volume")
+	printer.Infof("  3. Start %s and restore the dump\n", target)
+	printer.Infoln("Nakama is stopped meanwhile and started again afterwards.")
+	if ok, err := h.confirm(ctx, flags.Yes, "Continue?"); !ok || err != nil {
+		return err
+	}
+
+	if err := dockerClient.UpgradeNakamaDB(ctx, backupPath); err != nil {
+		if _, statErr := os.Stat(backupPath); statErr == nil {
+			printer.Errorf("The upgrade failed, restore the backup with: world nakama db restore %s\n", backupPath)
+		}
+		return eris.Wrap(err, "Failed to upgrade the Nakama database")
+	}
+	printer.Successf("Upgraded the Nakama database of %s to %s\n", namespace, target)
+	printer.Infof("The backup is kept in %s, delete it once your data is checked\n", backupPath)
+	return nil
+}
+
+// confirm asks to confirm a destructive operation, unless yes is set.
+func (h *Handler) confirm(ctx context.Context, yes bool, prompt string) (bool, error) {
+	if yes {
+		return true, nil
+	}
+	ok, err := h.inputService.Confirm(ctx, prompt+" (y/N)", "n")
+	if err != nil {
+		return false, eris.Wrap(err, "Failed to prompt user")
+	}
+	if !ok {
+		printer.Errorln("Cancelled")
+	}
+	return ok, nil
+}
+
+// dumpFileName returns the default name of a dump of the Nakama database, in the current directory.
+func dumpFileName(cfg *config.Config, now time.Time) string {
+	return fmt.Sprintf("%s-nakama-%s.dump", cfg.DockerEnv["CARDINAL_NAMESPACE"], now.Format(dumpTimeFormat))
+}
+
+// backupFileName returns the path of the backup made before an upgrade, creating its directory.
+func backupFileName(cfg *config.Config, now time.Time) (string, error) {
+	configDir, err := config.GetCLIConfigDir()
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to get the config directory")
+	}
+	backupDir := filepath.Join(configDir, backupDirName)
+	if err := os.MkdirAll(backupDir, 0755); err != nil { //nolint:gosec // the backups are not secret
+		return "", eris.Wrap(err, "Failed to create the backup directory")
+	}
+	return filepath.Join(backupDir, dumpFileName(cfg, now)), nil
+}
+
+// isSQLDump returns true when the dump is written as an SQL script rather than in the custom format.
+func isSQLDump(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), sqlDumpExt)
+}
+
+// getConfig returns the config of the given shard, or the config found from the config flag
+// and the current directory when no shard is selected.
+func getConfig(configFile string, shard string) (*config.Config, error) {
+	if shard == "" {
+		return config.GetConfig(&configFile)
+	}
+	if configFile != "" {
+		return nil, eris.New("--config and --shard can't be used together")
+	}
+	return config.GetShardConfig(shard)
+}
@@ -0,0 +1,41 @@
+package nakama
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"pkg.world.dev/world-cli/internal/app/world-cli/interfaces"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+// Interface guard.
+var _ interfaces.NakamaHandler = (*MockHandler)(nil)
+
+type MockHandler struct {
+	mock.Mock
+}
+
+func (m *MockHandler) DumpDB(ctx context.Context, flags models.DumpNakamaDBFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) RestoreDB(ctx context.Context, flags models.RestoreNakamaDBFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) PsqlDB(ctx context.Context, flags models.PsqlNakamaDBFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) MigrateDB(ctx context.Context, flags models.MigrateNakamaDBFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) UpgradeDB(ctx context.Context, flags models.UpgradeNakamaDBFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
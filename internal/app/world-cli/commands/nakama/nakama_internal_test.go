@@ -0,0 +1,39 @@
+package nakama
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/services/input"
+)
+
+func TestDumpFileNames(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "mygame"}}
+	now := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	assert.Equal(t, "mygame-nakama-20250304-050607.dump", dumpFileName(cfg, now))
+
+	assert.Assert(t, isSQLDump("backup.sql"))
+	assert.Assert(t, isSQLDump("BACKUP.SQL"))
+	assert.Assert(t, !isSQLDump("backup.dump"))
+	assert.Assert(t, !isSQLDump("sql"))
+}
+
+func TestConfirmDestructiveOperations(t *testing.T) {
+	ctx := context.Background()
+	inputService := &input.MockService{}
+	inputService.On("Confirm", ctx, "Drop it? (y/N)", "n").Return(false, nil).Once()
+	h := &Handler{inputService: inputService}
+
+	ok, err := h.confirm(ctx, false, "Drop it?")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	// --yes skips the prompt
+	ok, err = h.confirm(ctx, true, "Drop it?")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	inputService.AssertExpectations(t)
+}
@@ -0,0 +1,28 @@
+package nakama
+
+import (
+	"io"
+	"os"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/interfaces"
+	"pkg.world.dev/world-cli/internal/app/world-cli/services/input"
+)
+
+// Interface guard.
+var _ interfaces.NakamaHandler = (*Handler)(nil)
+
+type Handler struct {
+	inputService input.ServiceInterface
+	stdin        io.Reader
+	stdout       io.Writer
+	stderr       io.Writer
+}
+
+func NewHandler(inputService input.ServiceInterface) interfaces.NakamaHandler {
+	return &Handler{
+		inputService: inputService,
+		stdin:        os.Stdin,
+		stdout:       os.Stdout,
+		stderr:       os.Stderr,
+	}
+}
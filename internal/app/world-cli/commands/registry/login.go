@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"golang.org/x/term"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+func (h *Handler) Login(ctx context.Context, flags models.LoginRegistryFlags) error {
+	server := docker.RegistryServer(flags.Server)
+
+	username := flags.Username
+	if username == "" {
+		var err error
+		username, err = h.inputService.Prompt(ctx, "Username for "+server, "")
+		if err != nil {
+			return eris.Wrap(err, "failed to read the username")
+		}
+	}
+	if username == "" {
+		return eris.New("a username is required")
+	}
+
+	password, err := h.readPassword(flags.PasswordStdin)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.GetRuntimeConfig()
+	if err != nil {
+		return err
+	}
+	dockerClient, err := docker.NewClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	if _, err := dockerClient.Login(ctx, server, username, password); err != nil {
+		return err
+	}
+	printer.Successf("Logged in to %s\n", server)
+	return nil
+}
+
+// readPassword reads the password from stdin when asked to, or prompts for it without echoing it.
+func (h *Handler) readPassword(fromStdin bool) (string, error) {
+	if fromStdin {
+		content, err := io.ReadAll(h.stdin)
+		if err != nil {
+			return "", eris.Wrap(err, "failed to read the password from stdin")
+		}
+		password := strings.TrimRight(string(content), "\r\n")
+		if password == "" {
+			return "", eris.New("the password read from stdin is empty")
+		}
+		return password, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", eris.New("stdin is not a terminal, pass the password with --password-stdin")
+	}
+	printer.Info("Password: ")
+	content, err := term.ReadPassword(fd)
+	printer.NewLine(1)
+	if err != nil {
+		return "", eris.Wrap(err, "failed to read the password")
+	}
+	if len(content) == 0 {
+		return "", eris.New("a password is required")
+	}
+	return string(content), nil
+}
@@ -0,0 +1,18 @@
+package registry
+
+import (
+	"context"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+func (h *Handler) Logout(_ context.Context, flags models.LogoutRegistryFlags) error {
+	server := docker.RegistryServer(flags.Server)
+	if err := docker.Logout(server); err != nil {
+		return err
+	}
+	printer.Successf("Removed the credentials of %s\n", server)
+	return nil
+}
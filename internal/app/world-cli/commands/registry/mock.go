@@ -0,0 +1,26 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"pkg.world.dev/world-cli/internal/app/world-cli/interfaces"
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+// Interface guard.
+var _ interfaces.RegistryHandler = (*MockHandler)(nil)
+
+type MockHandler struct {
+	mock.Mock
+}
+
+func (m *MockHandler) Login(ctx context.Context, flags models.LoginRegistryFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
+
+func (m *MockHandler) Logout(ctx context.Context, flags models.LogoutRegistryFlags) error {
+	args := m.Called(ctx, flags)
+	return args.Error(0)
+}
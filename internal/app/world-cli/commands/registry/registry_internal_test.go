@@ -0,0 +1,19 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReadPasswordFromStdin(t *testing.T) {
+	h := &Handler{stdin: strings.NewReader("s3cret\n")}
+	password, err := h.readPassword(true)
+	assert.NilError(t, err)
+	assert.Equal(t, "s3cret", password)
+
+	h = &Handler{stdin: strings.NewReader("\r\n")}
+	_, err = h.readPassword(true)
+	assert.ErrorContains(t, err, "empty")
+}
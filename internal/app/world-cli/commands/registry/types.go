@@ -0,0 +1,24 @@
+package registry
+
+import (
+	"io"
+	"os"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/interfaces"
+	"pkg.world.dev/world-cli/internal/app/world-cli/services/input"
+)
+
+// Interface guard.
+var _ interfaces.RegistryHandler = (*Handler)(nil)
+
+type Handler struct {
+	inputService input.ServiceInterface
+	stdin        io.Reader
+}
+
+func NewHandler(inputService input.ServiceInterface) interfaces.RegistryHandler {
+	return &Handler{
+		inputService: inputService,
+		stdin:        os.Stdin,
+	}
+}
@@ -1,8 +1,12 @@
 package root
 
 import (
+	"context"
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/dependency"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/endpoint"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/teacmd"
 	"pkg.world.dev/world-cli/internal/pkg/tea/component/program"
 	"pkg.world.dev/world-cli/internal/pkg/tea/style"
@@ -31,8 +35,23 @@ func (h *Handler) Doctor() error {
 type WorldDoctorModel struct {
 	DepStatus    []teacmd.DependencyStatus
 	DepStatusErr error
+	DepsChecked  bool
+	Runtime      *RuntimeStatus
+}
+
+// RuntimeStatus is the container runtime the World CLI uses and how it reaches it.
+type RuntimeStatus struct {
+	Endpoint endpoint.Endpoint
+	// Server is the runtime serving the API, which can differ from the endpoint runtime, e.g. Podman
+	// behind a Docker socket
+	Server  string
+	Version string
+	Err     error
 }
 
+// runtimeCheckTimeout bounds the time spent querying the container runtime.
+const runtimeCheckTimeout = 10 * time.Second
+
 func NewWorldDoctorModel() WorldDoctorModel {
 	return WorldDoctorModel{}
 }
@@ -43,7 +62,28 @@ func NewWorldDoctorModel() WorldDoctorModel {
 
 // Init returns an initial command for the application to run.
 func (m WorldDoctorModel) Init() tea.Cmd {
-	return teacmd.CheckDependenciesCmd(DoctorDeps)
+	return tea.Batch(teacmd.CheckDependenciesCmd(DoctorDeps), checkRuntimeCmd)
+}
+
+// checkRuntimeCmd resolves the container runtime and queries its version.
+func checkRuntimeCmd() tea.Msg {
+	status := &RuntimeStatus{}
+	status.Endpoint, status.Err = endpoint.Current()
+	if status.Err != nil {
+		return status
+	}
+
+	cli, err := endpoint.NewAPIClient(status.Endpoint)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), runtimeCheckTimeout)
+	defer cancel()
+	status.Server, status.Version, status.Err = endpoint.ServerRuntime(ctx, cli)
+	return status
 }
 
 // Update handles incoming events and updates the model accordingly.
@@ -57,6 +97,11 @@ func (m WorldDoctorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case teacmd.CheckDependenciesMsg:
 		m.DepStatus = msg.DepStatus
 		m.DepStatusErr = msg.Err
+		m.DepsChecked = true
+	case *RuntimeStatus:
+		m.Runtime = msg
+	}
+	if m.DepsChecked && m.Runtime != nil {
 		return m, tea.Quit
 	}
 	return m, nil
@@ -68,5 +113,28 @@ func (m WorldDoctorModel) View() string {
 	out := style.Container.Render("--- World CLI Doctor ---") + "\n\n"
 	out += "Checking dependencies...\n"
 	out += depList + "\n" + help + "\n"
+	if m.Runtime != nil {
+		out += m.Runtime.View()
+	}
+	return out
+}
+
+// View renders the runtime and its endpoint.
+func (r *RuntimeStatus) View() string {
+	out := "Container runtime:\n"
+	ep := r.Endpoint
+	if r.Err != nil && ep.Host == "" {
+		return out + style.CrossIcon.Render() + " " + r.Err.Error() + "\n"
+	}
+
+	if r.Err != nil {
+		out += style.CrossIcon.Render() + " " + ep.Runtime + " is not reachable: " + r.Err.Error() + "\n"
+	} else {
+		out += style.TickIcon.Render() + " " + r.Server + " " + r.Version + "\n"
+	}
+	out += "  Endpoint: " + ep.Host + " (" + ep.Source + ")\n"
+	if ep.Context != "" {
+		out += "  Docker context: " + ep.Context + "\n"
+	}
 	return out
 }
@@ -0,0 +1,299 @@
+package bench
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"gotest.tools/v3/assert"
+)
+
+const scenarioTOML = `
+personas = 4
+rate = 100
+duration = "1s"
+ramp_up = "200ms"
+
+[[setup]]
+message = "create-player"
+body = { nickname = "{{persona}}" }
+
+[[actions]]
+message = "attack-player"
+body = { target = "{{random_persona}}" }
+weight = 3
+
+[[actions]]
+query = "player-health"
+body = { nickname = "{{persona}}" }
+`
+
+const scenarioYAML = `
+rate: 50
+duration: 2s
+actions:
+  - query: player-health
+    body:
+      nickname: "{{persona}}"
+`
+
+func writeScenario(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario(writeScenario(t, "bench.toml", scenarioTOML))
+	assert.NilError(t, err)
+	assert.Equal(t, 4, scenario.Personas)
+	assert.Equal(t, 200*time.Millisecond, scenario.RampUp.Duration)
+	assert.Equal(t, "/tx/game/attack-player", scenario.Actions[0].path())
+	assert.Equal(t, "/query/game/player-health", scenario.Actions[1].path())
+	assert.Equal(t, 1, scenario.Actions[1].Weight)
+
+	scenario, err = LoadScenario(writeScenario(t, "bench.yaml", scenarioYAML))
+	assert.NilError(t, err)
+	assert.Equal(t, defaultPersonas, scenario.Personas)
+	assert.Equal(t, 2*time.Second, scenario.Duration.Duration)
+	assert.Equal(t, "{{persona}}", scenario.Actions[0].Body["nickname"])
+
+	_, err = LoadScenario(writeScenario(t, "bench.toml", "rate = 1\nrat = 2\n"))
+	assert.ErrorContains(t, err, "unknown key rat")
+	_, err = LoadScenario(writeScenario(t, "bench.toml", "[[actions]]\nmessage = \"a\"\nquery = \"b\"\n"))
+	assert.ErrorContains(t, err, "either a message or a query")
+}
+
+func TestRender(t *testing.T) {
+	body := map[string]any{"nickname": "{{persona}}", "targets": []any{"{{random_persona}}"}, "damage": 3}
+	rendered := render(body, "bench-1", func() string { return "bench-2" })
+	assert.DeepEqual(t, map[string]any{"nickname": "bench-1", "targets": []any{"bench-2"}, "damage": 3}, rendered)
+}
+
+func TestPercentiles(t *testing.T) {
+	values := make([]float64, 0, 100)
+	for i := 100; i > 0; i-- {
+		values = append(values, float64(i))
+	}
+	assert.Equal(t, Percentiles{P50: 50, P90: 90, P99: 99, Max: 100}, percentiles(values))
+	assert.Equal(t, Percentiles{}, percentiles(nil))
+}
+
+func TestDueRequestsRampsUp(t *testing.T) {
+	assert.Equal(t, 0, dueRequests(100, 2*time.Second, 0))
+	assert.Equal(t, 25, dueRequests(100, 2*time.Second, time.Second))
+	assert.Equal(t, 100, dueRequests(100, 2*time.Second, 2*time.Second))
+	assert.Equal(t, 200, dueRequests(100, 2*time.Second, 3*time.Second))
+	assert.Equal(t, 100, dueRequests(100, 0, time.Second))
+}
+
+// fakeCardinal runs a tick every 20ms and lists the receipts of the transactions of the ticks that ran.
+// It verifies the signatures of the transactions against the signers of their personas. Attacking a player that
+// doesn't exist fails in its system, the health query fails for unknown players.
+type fakeCardinal struct {
+	mu   sync.Mutex
+	tick uint64
+	// personas are the signer addresses of the personas
+	personas map[string]string
+	players  map[string]bool
+	queued   []receipt
+	ran      []receipt
+}
+
+func newFakeCardinal(t *testing.T) *httptest.Server {
+	cardinal := &fakeCardinal{personas: map[string]string{}, players: map[string]bool{}}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cardinal.mu.Lock()
+				cardinal.ran = append(cardinal.ran, cardinal.queued...)
+				cardinal.queued = nil
+				cardinal.tick++
+				cardinal.mu.Unlock()
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(healthReply{IsServerRunning: true, IsGameLoopRunning: true})
+	})
+	mux.HandleFunc("POST /tx/{group}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var tx transaction
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&tx))
+		assert.Check(t, tx.Namespace == "alpha")
+		var body map[string]string
+		assert.Check(t, json.Unmarshal(tx.Body, &body))
+		signer, err := recoverSigner(tx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		cardinal.mu.Lock()
+		defer cardinal.mu.Unlock()
+		var errs []string
+		switch r.PathValue("name") {
+		case "create-persona":
+			cardinal.personas[body["personaTag"]] = body["signerAddress"]
+		case "create-player":
+			cardinal.players[body["nickname"]] = true
+		case "attack-player":
+			if !cardinal.players[body["target"]] {
+				errs = append(errs, "no such player")
+			}
+		}
+		if _, ok := cardinal.personas[tx.PersonaTag]; !ok {
+			http.Error(w, "persona not found", http.StatusBadRequest)
+			return
+		}
+		if cardinal.personas[tx.PersonaTag] != signer {
+			http.Error(w, "signature of another signer", http.StatusUnauthorized)
+			return
+		}
+		hash := tx.PersonaTag + "-" + r.PathValue("name") + "-" + time.Now().String()
+		cardinal.queued = append(cardinal.queued, receipt{TxHash: hash, Tick: cardinal.tick, Errors: errs})
+		_ = json.NewEncoder(w).Encode(txReply{TxHash: hash, Tick: cardinal.tick})
+	})
+	mux.HandleFunc("POST /query/game/player-health", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&body))
+		cardinal.mu.Lock()
+		defer cardinal.mu.Unlock()
+		if !cardinal.players[body["nickname"]] {
+			http.Error(w, "player not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"hp": 100}`))
+	})
+	mux.HandleFunc("POST /query/receipts/list", func(w http.ResponseWriter, r *http.Request) {
+		var request receiptsRequest
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&request))
+		cardinal.mu.Lock()
+		defer cardinal.mu.Unlock()
+		reply := receiptsReply{StartTick: request.StartTick, EndTick: cardinal.tick}
+		for _, receipt := range cardinal.ran {
+			if receipt.Tick >= request.StartTick {
+				reply.Receipts = append(reply.Receipts, receipt)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(reply)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		server.Close()
+		close(done)
+	})
+	return server
+}
+
+// recoverSigner returns the address of the signer of the transaction, as Cardinal verifies it.
+func recoverSigner(tx transaction) (string, error) {
+	signature, err := hex.DecodeString(tx.Signature)
+	if err != nil {
+		return "", err
+	}
+	key, err := crypto.SigToPub(transactionHash(tx.PersonaTag, tx.Namespace, tx.Nonce, tx.Body), signature)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(*key).Hex(), nil
+}
+
+func TestSign(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NilError(t, err)
+	c := newClient("http://localhost:4040", "alpha", http.DefaultClient)
+
+	tx, err := c.sign("bench-1", key, 7, map[string]any{"target": "bench-2", "damage": 3})
+	assert.NilError(t, err)
+	assert.Equal(t, `{"damage":3,"target":"bench-2"}`, string(tx.Body))
+	assert.Equal(t, uint64(7), tx.Nonce)
+	signer, err := recoverSigner(tx)
+	assert.NilError(t, err)
+	assert.Equal(t, signerAddress(key), signer)
+
+	// The signature covers the nonce and the namespace
+	tx.Nonce = 8
+	signer, err = recoverSigner(tx)
+	assert.NilError(t, err)
+	assert.Assert(t, signer != signerAddress(key))
+	tx.Nonce, tx.Namespace = 7, "beta"
+	signer, err = recoverSigner(tx)
+	assert.NilError(t, err)
+	assert.Assert(t, signer != signerAddress(key))
+}
+
+func TestRunAgainstCardinal(t *testing.T) {
+	server := newFakeCardinal(t)
+	scenario, err := LoadScenario(writeScenario(t, "bench.toml", scenarioTOML))
+	assert.NilError(t, err)
+
+	report, err := Run(context.Background(), scenario, Options{
+		URL: server.URL, Namespace: "alpha", ReceiptPoll: 20 * time.Millisecond,
+	})
+	assert.NilError(t, err)
+
+	// Every persona created its player in the setup, so no request fails
+	assert.Equal(t, 0, report.SetupErrors)
+	assert.Assert(t, report.Requests >= 90 && report.Requests <= 110, report.Requests)
+	assert.Equal(t, 0, report.Errors)
+	assert.Equal(t, 0, report.Dropped)
+	assert.Assert(t, report.Receipts)
+	assert.Equal(t, 0, report.Unconfirmed)
+	assert.Assert(t, report.TickRate > 10, report.TickRate)
+	assert.Assert(t, report.TickLag.Max <= 1, report.TickLag.Max)
+
+	assert.Equal(t, 2, len(report.Actions))
+	attack := report.Actions[0]
+	assert.Equal(t, "attack-player", attack.Name)
+	assert.Equal(t, kindMessage, attack.Kind)
+	assert.Equal(t, 0, attack.ReceiptErrors)
+	assert.Assert(t, attack.Requests > report.Actions[1].Requests)
+	assert.Assert(t, attack.LatencyMs.Max > 0)
+}
+
+func TestRunReportsErrors(t *testing.T) {
+	server := newFakeCardinal(t)
+	// Without the setup, the players don't exist
+	scenario, err := LoadScenario(writeScenario(t, "bench.toml",
+		strings.Replace(scenarioTOML, "[[setup]]\nmessage = \"create-player\"", "[[setup]]\nmessage = \"noop\"", 1)))
+	assert.NilError(t, err)
+
+	report, err := Run(context.Background(), scenario, Options{
+		URL: server.URL, Namespace: "alpha", ReceiptPoll: 20 * time.Millisecond,
+	})
+	assert.NilError(t, err)
+
+	attack, health := report.Actions[0], report.Actions[1]
+	assert.Equal(t, attack.Requests, attack.ReceiptErrors)
+	assert.Equal(t, health.Requests, health.Errors)
+	assert.Equal(t, 1.0, health.ErrorRate)
+	assert.Assert(t, strings.HasPrefix(health.TopErrors[0].Error, "404 Not Found: player not found"))
+	assert.Equal(t, health.Errors, report.Errors)
+}
+
+func TestRunFailsWhenCardinalIsDown(t *testing.T) {
+	server := newFakeCardinal(t)
+	server.Close()
+	scenario, err := LoadScenario(writeScenario(t, "bench.toml", scenarioTOML))
+	assert.NilError(t, err)
+
+	_, err = Run(context.Background(), scenario, Options{URL: server.URL, Namespace: "alpha"})
+	assert.ErrorContains(t, err, "Cardinal is not ready at "+server.URL)
+}
@@ -0,0 +1,203 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	healthPath        = "/health"
+	createPersonaPath = "/tx/persona/create-persona"
+	receiptsPath      = "/query/receipts/list"
+
+	// maxErrorBody is how much of the body of a failed request ends up in its error
+	maxErrorBody = 200
+)
+
+// client sends the requests of the personas to the HTTP server of Cardinal. Transactions are signed by the key
+// of their persona, so shards verifying signatures accept them too.
+type client struct {
+	baseURL   string
+	namespace string
+	http      *http.Client
+}
+
+// transaction is the body of a transaction of Cardinal.
+type transaction struct {
+	PersonaTag string          `json:"personaTag"`
+	Namespace  string          `json:"namespace"`
+	Nonce      uint64          `json:"nonce"`
+	Signature  string          `json:"signature"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// txReply is the reply of Cardinal to a transaction, queued for the tick.
+type txReply struct {
+	TxHash string `json:"txHash"`
+	Tick   uint64 `json:"tick"`
+}
+
+type receiptsRequest struct {
+	StartTick uint64 `json:"startTick"`
+}
+
+type receiptsReply struct {
+	StartTick uint64    `json:"startTick"`
+	EndTick   uint64    `json:"endTick"`
+	Receipts  []receipt `json:"receipts"`
+}
+
+// receipt is the result of a transaction, once its tick ran.
+type receipt struct {
+	TxHash string   `json:"txHash"`
+	Tick   uint64   `json:"tick"`
+	Errors []string `json:"errors"`
+}
+
+type healthReply struct {
+	IsServerRunning   bool `json:"isServerRunning"`
+	IsGameLoopRunning bool `json:"isGameLoopRunning"`
+}
+
+func newClient(baseURL string, namespace string, httpClient *http.Client) *client {
+	return &client{baseURL: strings.TrimRight(baseURL, "/"), namespace: namespace, http: httpClient}
+}
+
+// health returns an error unless Cardinal and its game loop are running.
+func (c *client) health(ctx context.Context) error {
+	var reply healthReply
+	if err := c.do(ctx, http.MethodGet, healthPath, nil, &reply); err != nil {
+		return err
+	}
+	if !reply.IsGameLoopRunning {
+		return eris.New("the game loop of Cardinal is not running")
+	}
+	return nil
+}
+
+// createPersona creates a persona whose transactions are signed by key.
+func (c *client) createPersona(ctx context.Context, tag string, key *ecdsa.PrivateKey, nonce uint64) (txReply,
+	error) {
+	body := map[string]string{"personaTag": tag, "signerAddress": signerAddress(key)}
+	return c.send(ctx, createPersonaPath, tag, key, nonce, body)
+}
+
+// send sends a transaction of the persona, signed by its key.
+func (c *client) send(ctx context.Context, path string, persona string, key *ecdsa.PrivateKey, nonce uint64,
+	body any) (txReply, error) {
+	tx, err := c.sign(persona, key, nonce, body)
+	if err != nil {
+		return txReply{}, err
+	}
+	var reply txReply
+	err = c.do(ctx, http.MethodPost, path, tx, &reply)
+	return reply, err
+}
+
+// sign returns the transaction of the persona with its signature, computed as Cardinal verifies it: over the
+// Keccak-256 hash of the persona tag, the namespace, the decimal nonce and the JSON body.
+func (c *client) sign(persona string, key *ecdsa.PrivateKey, nonce uint64, body any) (transaction, error) {
+	// The body is encoded once, so the bytes sent are the bytes signed
+	content, err := json.Marshal(body)
+	if err != nil {
+		return transaction{}, eris.Wrap(err, "Failed to encode the transaction")
+	}
+	hash := transactionHash(persona, c.namespace, nonce, content)
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return transaction{}, eris.Wrap(err, "Failed to sign the transaction")
+	}
+	return transaction{
+		PersonaTag: persona,
+		Namespace:  c.namespace,
+		Nonce:      nonce,
+		Signature:  hex.EncodeToString(signature),
+		Body:       content,
+	}, nil
+}
+
+// transactionHash returns the hash of a transaction that its signature signs.
+func transactionHash(persona string, namespace string, nonce uint64, body []byte) []byte {
+	return crypto.Keccak256([]byte(persona), []byte(namespace), []byte(strconv.FormatUint(nonce, 10)), body)
+}
+
+// signerAddress returns the checksummed address of the key, the signer of a persona.
+func signerAddress(key *ecdsa.PrivateKey) string {
+	return crypto.PubkeyToAddress(key.PublicKey).Hex()
+}
+
+// query runs a query.
+func (c *client) query(ctx context.Context, path string, body any) error {
+	return c.do(ctx, http.MethodPost, path, body, nil)
+}
+
+// receipts returns the receipts of the transactions of the ticks from startTick.
+func (c *client) receipts(ctx context.Context, startTick uint64) (receiptsReply, error) {
+	var reply receiptsReply
+	err := c.do(ctx, http.MethodPost, receiptsPath, receiptsRequest{StartTick: startTick}, &reply)
+	return reply, err
+}
+
+// do sends a request with a JSON body and decodes the JSON reply into reply, unless it is nil.
+func (c *client) do(ctx context.Context, method string, path string, body any, reply any) error {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return eris.Wrap(err, "Failed to encode the request")
+		}
+		reader = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return eris.Wrap(err, "Failed to create the request")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return eris.Wrap(err, "Failed to read the reply")
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &statusError{status: resp.StatusCode, body: string(content)}
+	}
+	if reply == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, reply); err != nil {
+		return eris.Wrapf(err, "invalid reply of %s", path)
+	}
+	return nil
+}
+
+// statusError is the error of a request Cardinal replied to with an error status.
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	body := strings.TrimSpace(e.body)
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody] + "..."
+	}
+	return fmt.Sprintf("%d %s: %s", e.status, http.StatusText(e.status), body)
+}
@@ -0,0 +1,526 @@
+package bench
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	defaultReceiptPoll = 200 * time.Millisecond
+	defaultTimeout     = 10 * time.Second
+	// setupTimeout is how long the receipts of a setup step are waited for
+	setupTimeout = 30 * time.Second
+	// drainTimeout is how long the receipts of the load are waited for once it is over
+	drainTimeout = 5 * time.Second
+	// setupPause replaces the receipts between the setup steps when Cardinal doesn't serve them
+	setupPause = 2 * time.Second
+	// dispatchInterval is the longest the dispatcher sleeps between requests
+	dispatchInterval = 10 * time.Millisecond
+)
+
+// Options are the settings of a run that don't belong to its scenario.
+type Options struct {
+	// URL is the HTTP server of Cardinal
+	URL string
+	// Namespace is the namespace of the shard, set in the transactions
+	Namespace string
+	// HTTPClient sends the requests, a client with a 10s timeout by default
+	HTTPClient *http.Client
+	// ReceiptPoll is how often the receipts are listed, 200ms by default
+	ReceiptPoll time.Duration
+	// Progress is called every ProgressInterval during the load, when set
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+}
+
+// Progress is the state of the load, reported while it runs.
+type Progress struct {
+	Elapsed  time.Duration
+	Requests int
+	Errors   int
+	Dropped  int
+	// Rate is the target rate at this point of the ramp up
+	Rate float64
+}
+
+// Run creates the personas of the scenario, sends its setup messages, then its actions at the target rate,
+// and reports the results. Interrupting the context stops the load early and still returns its report.
+func Run(ctx context.Context, scenario *Scenario, opts Options) (*Report, error) {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: &http.Transport{MaxIdleConnsPerHost: scenario.Concurrency},
+		}
+	}
+	if opts.ReceiptPoll == 0 {
+		opts.ReceiptPoll = defaultReceiptPoll
+	}
+	c := newClient(opts.URL, opts.Namespace, opts.HTTPClient)
+	if err := c.health(ctx); err != nil {
+		return nil, eris.Wrapf(err, "Cardinal is not ready at %s", opts.URL)
+	}
+
+	r, err := newRun(scenario, c)
+	if err != nil {
+		return nil, err
+	}
+	trackerCtx, stopTracker := context.WithCancel(context.Background())
+	defer stopTracker()
+	go r.tracker.run(trackerCtx, opts.ReceiptPoll)
+
+	if err := r.setup(ctx); err != nil {
+		return nil, err
+	}
+	seconds := r.load(ctx, opts)
+	if ctx.Err() == nil {
+		r.tracker.wait(ctx, nil, drainTimeout)
+	}
+	return r.report(opts.URL, seconds), nil
+}
+
+// run is the state of a run.
+type run struct {
+	scenario *Scenario
+	client   *client
+	tracker  *tracker
+
+	personas []string
+	// signers are the keys signing the transactions of the personas
+	signers []*ecdsa.PrivateKey
+	// nonces of the personas, incremented by each of their transactions
+	nonces []atomic.Uint64
+
+	actionStats []*actionStats
+	setupErrors atomic.Int64
+	dropped     atomic.Int64
+	weights     int
+}
+
+func newRun(scenario *Scenario, c *client) (*run, error) {
+	// Tags are unique to the run, so its personas can always be created
+	id := make([]byte, 3) //nolint:mnd // 6 hex characters
+	if _, err := rand.Read(id); err != nil {
+		return nil, eris.Wrap(err, "Failed to generate the id of the run")
+	}
+	r := &run{
+		scenario: scenario,
+		client:   c,
+		tracker:  newTracker(c),
+		personas: make([]string, scenario.Personas),
+		signers:  make([]*ecdsa.PrivateKey, scenario.Personas),
+		nonces:   make([]atomic.Uint64, scenario.Personas),
+	}
+	for i := range r.personas {
+		r.personas[i] = fmt.Sprintf("%s-%s-%d", scenario.PersonaTag, hex.EncodeToString(id), i)
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, eris.Wrap(err, "Failed to generate the signers of the personas")
+		}
+		r.signers[i] = key
+	}
+	for _, action := range scenario.Actions {
+		r.actionStats = append(r.actionStats, newActionStats(action))
+		r.weights += action.Weight
+	}
+	return r, nil
+}
+
+// setup creates the personas, then sends each setup message for every persona, waiting for their receipts
+// between the steps.
+func (r *run) setup(ctx context.Context) error {
+	createPersona := func(ctx context.Context, i int) (txReply, error) {
+		return r.client.createPersona(ctx, r.personas[i], r.signers[i], r.nonces[i].Add(1)-1)
+	}
+	if err := r.setupStep(ctx, createPersona); err != nil {
+		return eris.Wrap(err, "Failed to create the personas")
+	}
+	for _, action := range r.scenario.Setup {
+		send := func(ctx context.Context, i int) (txReply, error) {
+			return r.send(ctx, action, i)
+		}
+		if err := r.setupStep(ctx, send); err != nil {
+			return eris.Wrapf(err, "Failed to send %s", action.Name())
+		}
+	}
+	return nil
+}
+
+// setupStep sends a transaction for every persona and waits for their receipts.
+func (r *run) setupStep(ctx context.Context, send func(context.Context, int) (txReply, error)) error {
+	var (
+		mu     sync.Mutex
+		hashes []string
+		errs   []error
+		wg     sync.WaitGroup
+	)
+	limit := make(chan struct{}, r.scenario.Concurrency)
+	for i := range r.personas {
+		limit <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-limit; wg.Done() }()
+			reply, err := send(ctx, i)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			hashes = append(hashes, reply.TxHash)
+			r.tracker.sent(reply, nil, time.Now(), &r.setupErrors)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.setupErrors.Add(int64(len(errs)))
+	if len(errs) == len(r.personas) && len(errs) > 0 {
+		return errs[0]
+	}
+	r.tracker.wait(ctx, hashes, setupTimeout)
+	return nil
+}
+
+// load sends the actions at the target rate until the end of the scenario or the context, and returns how
+// long it lasted in seconds.
+func (r *run) load(ctx context.Context, opts Options) float64 {
+	rampUp, total := r.scenario.RampUp.Duration, r.scenario.RampUp.Duration+r.scenario.Duration.Duration
+	inFlight := make(chan struct{}, r.scenario.Concurrency)
+	started := time.Now()
+	lastProgress := started
+	issued := 0
+
+	for ctx.Err() == nil {
+		elapsed := time.Since(started)
+		if elapsed >= total {
+			break
+		}
+		for due := dueRequests(r.scenario.Rate, rampUp, elapsed); issued < due; issued++ {
+			select {
+			case inFlight <- struct{}{}:
+				go func(persona int) {
+					defer func() { <-inFlight }()
+					r.request(ctx, persona)
+				}(issued % len(r.personas))
+			default:
+				r.dropped.Add(1)
+			}
+		}
+		if opts.Progress != nil && opts.ProgressInterval > 0 && time.Since(lastProgress) >= opts.ProgressInterval {
+			lastProgress = time.Now()
+			opts.Progress(r.progress(elapsed))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(dispatchInterval):
+		}
+	}
+	seconds := time.Since(started).Seconds()
+
+	// Wait for the requests in flight
+	for range r.scenario.Concurrency {
+		inFlight <- struct{}{}
+	}
+	return seconds
+}
+
+// request sends an action picked by weight for the persona.
+func (r *run) request(ctx context.Context, persona int) {
+	pick := mathrand.IntN(r.weights) //nolint:gosec // picking actions needs no cryptographic randomness
+	for i, action := range r.scenario.Actions {
+		if pick >= action.Weight {
+			pick -= action.Weight
+			continue
+		}
+		stats := r.actionStats[i]
+		started := time.Now()
+		if action.Query != "" {
+			body := render(action.Body, r.personas[persona], r.randomPersona(persona))
+			err := r.client.query(ctx, action.path(), body)
+			stats.request(time.Since(started), err)
+			return
+		}
+		reply, err := r.send(ctx, action, persona)
+		stats.request(time.Since(started), err)
+		if err == nil {
+			r.tracker.sent(reply, stats, started, nil)
+		}
+		return
+	}
+}
+
+// send sends a message of the persona.
+func (r *run) send(ctx context.Context, action Action, persona int) (txReply, error) {
+	body := render(action.Body, r.personas[persona], r.randomPersona(persona))
+	return r.client.send(ctx, action.path(), r.personas[persona], r.signers[persona],
+		r.nonces[persona].Add(1)-1, body)
+}
+
+// randomPersona returns a function picking another persona than the given one, when there are several.
+func (r *run) randomPersona(persona int) func() string {
+	return func() string {
+		if len(r.personas) == 1 {
+			return r.personas[0]
+		}
+		other := mathrand.IntN(len(r.personas) - 1) //nolint:gosec // no cryptographic randomness needed
+		if other >= persona {
+			other++
+		}
+		return r.personas[other]
+	}
+}
+
+func (r *run) progress(elapsed time.Duration) Progress {
+	progress := Progress{Elapsed: elapsed, Dropped: int(r.dropped.Load())}
+	for _, stats := range r.actionStats {
+		requests, errors := stats.counts()
+		progress.Requests += requests
+		progress.Errors += errors
+	}
+	if rampUp := r.scenario.RampUp.Duration; elapsed < rampUp {
+		progress.Rate = r.scenario.Rate * elapsed.Seconds() / rampUp.Seconds()
+	} else {
+		progress.Rate = r.scenario.Rate
+	}
+	return progress
+}
+
+func (r *run) report(url string, seconds float64) *Report {
+	report := &Report{
+		URL:         url,
+		Personas:    len(r.personas),
+		TargetRate:  r.scenario.Rate,
+		Seconds:     seconds,
+		Dropped:     int(r.dropped.Load()),
+		SetupErrors: int(r.setupErrors.Load()),
+	}
+	var tickLags []float64
+	for _, stats := range r.actionStats {
+		action := stats.report(seconds)
+		report.Requests += action.Requests
+		report.Errors += action.Errors
+		report.Actions = append(report.Actions, action)
+		stats.mu.Lock()
+		tickLags = append(tickLags, stats.tickLags...)
+		stats.mu.Unlock()
+	}
+	report.Throughput = perSecond(report.Requests-report.Errors, seconds)
+	report.ErrorRate = ratio(report.Errors, report.Requests)
+	report.TickRate = r.tracker.tickRate()
+	report.Receipts = r.tracker.supported()
+	report.TickLag = percentiles(tickLags)
+	report.Unconfirmed = r.tracker.unconfirmed()
+	return report
+}
+
+// dueRequests returns how many requests are due after elapsed, the rate growing linearly during the ramp up.
+func dueRequests(rate float64, rampUp time.Duration, elapsed time.Duration) int {
+	seconds, ramp := elapsed.Seconds(), rampUp.Seconds()
+	if seconds < ramp {
+		return int(rate * seconds * seconds / (2 * ramp)) //nolint:mnd // the area under the ramp
+	}
+	return int(rate*ramp/2 + rate*(seconds-ramp)) //nolint:mnd // the area under the ramp
+}
+
+// tracker lists the receipts of the transactions sent by the run.
+type tracker struct {
+	client *client
+
+	mu          sync.Mutex
+	pending     map[string]pendingTx
+	tracking    bool
+	startTick   uint64
+	unsupported bool
+	// The first and last ticks seen, and when, for the tick rate
+	firstTick, lastTick uint64
+	firstSeen, lastSeen time.Time
+}
+
+// pendingTx is a transaction waiting for its receipt. Its stats record the receipt, or failed counts it
+// when its system returns an error.
+type pendingTx struct {
+	tick   uint64
+	sentAt time.Time
+	stats  *actionStats
+	failed *atomic.Int64
+}
+
+func newTracker(c *client) *tracker {
+	return &tracker{client: c, pending: make(map[string]pendingTx)}
+}
+
+// sent records a transaction Cardinal queued.
+func (t *tracker) sent(reply txReply, stats *actionStats, sentAt time.Time, failed *atomic.Int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seeTick(reply.Tick)
+	if t.unsupported || reply.TxHash == "" {
+		return
+	}
+	// A transaction queued for a tick the receipts were already listed from is listed again
+	if !t.tracking || reply.Tick < t.startTick {
+		t.startTick, t.tracking = reply.Tick, true
+	}
+	t.pending[reply.TxHash] = pendingTx{tick: reply.Tick, sentAt: sentAt, stats: stats, failed: failed}
+}
+
+// seeTick records a tick of Cardinal, the caller holds the lock.
+func (t *tracker) seeTick(tick uint64) {
+	now := time.Now()
+	if t.firstSeen.IsZero() {
+		t.firstTick, t.firstSeen = tick, now
+	}
+	if tick >= t.lastTick {
+		t.lastTick, t.lastSeen = tick, now
+	}
+}
+
+// run lists the receipts until the context is done, or Cardinal turns out not to serve them.
+func (t *tracker) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.mu.Lock()
+		startTick, idle := t.startTick, len(t.pending) == 0
+		t.mu.Unlock()
+		if idle {
+			continue
+		}
+
+		reply, err := t.client.receipts(ctx, startTick)
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound {
+			t.mu.Lock()
+			t.unsupported = true
+			t.pending = make(map[string]pendingTx)
+			t.mu.Unlock()
+			return
+		}
+		if err != nil {
+			continue
+		}
+		t.receive(reply)
+	}
+}
+
+// receive records the receipts of the pending transactions.
+func (t *tracker) receive(reply receiptsReply) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if reply.EndTick > 0 {
+		t.seeTick(reply.EndTick)
+	}
+	for _, receipt := range reply.Receipts {
+		tx, ok := t.pending[receipt.TxHash]
+		if !ok {
+			continue
+		}
+		delete(t.pending, receipt.TxHash)
+		failed := len(receipt.Errors) > 0
+		if tx.stats != nil {
+			var lag uint64
+			if receipt.Tick > tx.tick {
+				lag = receipt.Tick - tx.tick
+			}
+			tx.stats.receipt(now.Sub(tx.sentAt), lag, failed)
+		}
+		if failed && tx.failed != nil {
+			tx.failed.Add(1)
+		}
+	}
+	if reply.EndTick > t.startTick {
+		t.startTick = reply.EndTick
+	}
+}
+
+// wait waits until the receipts of the transactions are seen, every pending one when hashes is nil, or
+// until the timeout.
+func (t *tracker) wait(ctx context.Context, hashes []string, timeout time.Duration) {
+	if !t.supported() {
+		if hashes != nil {
+			// Give Cardinal a few ticks to run the transactions
+			select {
+			case <-ctx.Done():
+			case <-time.After(setupPause):
+			}
+		}
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		if t.done(hashes) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(defaultReceiptPoll):
+		}
+	}
+}
+
+// done returns true when none of the transactions is pending, or none at all when hashes is nil.
+func (t *tracker) done(hashes []string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unsupported {
+		return true
+	}
+	if hashes == nil {
+		return len(t.pending) == 0
+	}
+	for _, hash := range hashes {
+		if _, ok := t.pending[hash]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *tracker) supported() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.unsupported
+}
+
+// unconfirmed returns how many messages of the load never got a receipt.
+func (t *tracker) unconfirmed() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count := 0
+	for _, tx := range t.pending {
+		if tx.stats != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// tickRate returns the ticks per second Cardinal ran while the run watched it.
+func (t *tracker) tickRate() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	seconds := t.lastSeen.Sub(t.firstSeen).Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(t.lastTick-t.firstTick) / seconds
+}
@@ -0,0 +1,248 @@
+package bench
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rotisserie/eris"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultPersonas    = 10
+	defaultRate        = 10
+	defaultDuration    = 30 * time.Second
+	defaultConcurrency = 64
+	defaultPersonaTag  = "bench"
+
+	// Placeholders of the string values of a body
+	personaPlaceholder       = "{{persona}}"
+	randomPersonaPlaceholder = "{{random_persona}}"
+)
+
+// Scenario describes a load test, read from a TOML or YAML file:
+//
+//	personas = 50     # simulated personas, created before the load
+//	rate = 200        # requests per second, across every persona
+//	duration = "1m"   # how long the load lasts, after the ramp up
+//	ramp_up = "10s"   # the rate grows linearly to its target during the ramp up
+//
+//	[[setup]]         # sent once by every persona, in order, before the load
+//	message = "create-player"
+//	body = { nickname = "{{persona}}" }
+//
+//	[[actions]]       # picked at random by weight for each request of the load
+//	message = "attack-player"
+//	body = { target = "{{random_persona}}" }
+//	weight = 3
+//
+//	[[actions]]
+//	query = "player-health"
+//	body = { nickname = "{{persona}}" }
+//
+// In the string values of a body, {{persona}} is the tag of the persona sending the request and
+// {{random_persona}} the tag of another persona.
+type Scenario struct {
+	// Personas is the number of simulated personas
+	Personas int `toml:"personas" yaml:"personas"`
+	// PersonaTag prefixes the tags of the personas, followed by the run and their index
+	PersonaTag string `toml:"persona_tag" yaml:"persona_tag"`
+	// Rate is the target number of requests per second of the load
+	Rate float64 `toml:"rate" yaml:"rate"`
+	// Duration is how long the load lasts after the ramp up, e.g. 30s
+	Duration Duration `toml:"duration" yaml:"duration"`
+	// RampUp is how long the rate takes to grow to its target
+	RampUp Duration `toml:"ramp_up" yaml:"ramp_up"`
+	// Concurrency is the maximum number of requests in flight, requests due beyond it are dropped
+	Concurrency int `toml:"concurrency" yaml:"concurrency"`
+
+	Setup   []Action `toml:"setup" yaml:"setup"`
+	Actions []Action `toml:"actions" yaml:"actions"`
+}
+
+// Action is a message or a query of the scenario.
+type Action struct {
+	// Message is the name of a registered message, sent as a transaction
+	Message string `toml:"message" yaml:"message"`
+	// Query is the name of a registered query
+	Query string `toml:"query" yaml:"query"`
+	// Group is the group of the message or the query, game by default
+	Group string `toml:"group" yaml:"group"`
+	// Body is the JSON body of the message or the query
+	Body map[string]any `toml:"body" yaml:"body"`
+	// Weight is how often the action is picked relative to the others, 1 by default
+	Weight int `toml:"weight" yaml:"weight"`
+}
+
+// Duration is a time.Duration written like 30s or 1m30s.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses the duration, for TOML.
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return eris.Errorf("invalid duration %q", text)
+	}
+	d.Duration = duration
+	return nil
+}
+
+// UnmarshalYAML parses the duration, for YAML.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.UnmarshalText([]byte(node.Value))
+}
+
+// LoadScenario reads a scenario from a TOML file, or a YAML one when its extension is .yaml or .yml, and
+// fills in the defaults.
+func LoadScenario(path string) (*Scenario, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to read the scenario %s", path)
+	}
+
+	scenario := &Scenario{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(scenario); err != nil {
+			return nil, eris.Wrapf(err, "invalid scenario %s", path)
+		}
+	default:
+		metadata, err := toml.Decode(string(content), scenario)
+		if err != nil {
+			return nil, eris.Wrapf(err, "invalid scenario %s", path)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return nil, eris.Errorf("invalid scenario %s: unknown key %s", path, undecoded[0])
+		}
+	}
+
+	scenario.setDefaults()
+	if err := scenario.Validate(); err != nil {
+		return nil, eris.Wrapf(err, "invalid scenario %s", path)
+	}
+	return scenario, nil
+}
+
+func (s *Scenario) setDefaults() {
+	if s.Personas == 0 {
+		s.Personas = defaultPersonas
+	}
+	if s.PersonaTag == "" {
+		s.PersonaTag = defaultPersonaTag
+	}
+	if s.Rate == 0 {
+		s.Rate = defaultRate
+	}
+	if s.Duration.Duration == 0 {
+		s.Duration.Duration = defaultDuration
+	}
+	if s.Concurrency == 0 {
+		s.Concurrency = defaultConcurrency
+	}
+	for i := range s.Setup {
+		s.Setup[i].setDefaults()
+	}
+	for i := range s.Actions {
+		s.Actions[i].setDefaults()
+	}
+}
+
+func (a *Action) setDefaults() {
+	if a.Group == "" {
+		a.Group = "game"
+	}
+	if a.Weight == 0 {
+		a.Weight = 1
+	}
+}
+
+// Validate returns an error if the scenario can't run.
+func (s *Scenario) Validate() error {
+	switch {
+	case s.Personas < 0:
+		return eris.Errorf("personas must be positive, got %d", s.Personas)
+	case s.Rate < 0:
+		return eris.Errorf("rate must be positive, got %g", s.Rate)
+	case s.Duration.Duration < 0 || s.RampUp.Duration < 0:
+		return eris.New("duration and ramp_up must be positive")
+	case s.Concurrency < 0:
+		return eris.Errorf("concurrency must be positive, got %d", s.Concurrency)
+	case len(s.Actions) == 0:
+		return eris.New("at least one action is required")
+	}
+	for _, action := range s.Setup {
+		if action.Message == "" {
+			return eris.New("setup actions must be messages")
+		}
+		if err := action.Validate(); err != nil {
+			return eris.Wrap(err, "setup")
+		}
+	}
+	for _, action := range s.Actions {
+		if err := action.Validate(); err != nil {
+			return eris.Wrap(err, "actions")
+		}
+	}
+	return nil
+}
+
+// Validate returns an error unless the action is either a message or a query.
+func (a Action) Validate() error {
+	if (a.Message == "") == (a.Query == "") {
+		return eris.New("an action must have either a message or a query")
+	}
+	if a.Weight < 0 {
+		return eris.Errorf("weight of %s must be positive, got %d", a.Name(), a.Weight)
+	}
+	return nil
+}
+
+// Name returns the name of the message or the query.
+func (a Action) Name() string {
+	if a.Message != "" {
+		return a.Message
+	}
+	return a.Query
+}
+
+// path returns the path of the endpoint of Cardinal handling the action.
+func (a Action) path() string {
+	if a.Message != "" {
+		return "/tx/" + a.Group + "/" + a.Message
+	}
+	return "/query/" + a.Group + "/" + a.Query
+}
+
+// render returns the body with the placeholders of its string values replaced.
+func render(value any, persona string, randomPersona func() string) any {
+	switch v := value.(type) {
+	case string:
+		v = strings.ReplaceAll(v, personaPlaceholder, persona)
+		if strings.Contains(v, randomPersonaPlaceholder) {
+			v = strings.ReplaceAll(v, randomPersonaPlaceholder, randomPersona())
+		}
+		return v
+	case map[string]any:
+		rendered := make(map[string]any, len(v))
+		for key, item := range v {
+			rendered[key] = render(item, persona, randomPersona)
+		}
+		return rendered
+	case []any:
+		rendered := make([]any, len(v))
+		for i, item := range v {
+			rendered[i] = render(item, persona, randomPersona)
+		}
+		return rendered
+	default:
+		return v
+	}
+}
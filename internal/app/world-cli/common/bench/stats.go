@@ -0,0 +1,198 @@
+package bench
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	kindMessage = "message"
+	kindQuery   = "query"
+
+	// maxTopErrors is how many distinct errors an action reports
+	maxTopErrors = 3
+)
+
+// Report is the result of a run.
+type Report struct {
+	URL        string  `json:"url"`
+	Personas   int     `json:"personas"`
+	TargetRate float64 `json:"targetRate"`
+	// Seconds is how long the load lasted, ramp up included
+	Seconds float64 `json:"seconds"`
+
+	Requests int `json:"requests"`
+	Errors   int `json:"errors"`
+	// Dropped counts the requests that were due while concurrency requests were in flight
+	Dropped int `json:"dropped"`
+	// Throughput is the number of successful requests per second
+	Throughput float64 `json:"throughput"`
+	ErrorRate  float64 `json:"errorRate"`
+
+	// TickRate is the number of ticks per second Cardinal ran during the load
+	TickRate float64 `json:"tickRate"`
+	// Receipts tells whether the receipts of the transactions were tracked, which tick lags need
+	Receipts bool `json:"receipts"`
+	// TickLag is how many ticks after the one they were queued for the messages ran
+	TickLag Percentiles `json:"tickLag"`
+	// Unconfirmed counts the messages whose receipt was never seen
+	Unconfirmed int `json:"unconfirmed"`
+	// SetupErrors counts the failed persona creations and setup messages
+	SetupErrors int `json:"setupErrors"`
+
+	Actions []ActionReport `json:"actions"`
+}
+
+// ActionReport is the result of an action of the scenario.
+type ActionReport struct {
+	Name       string  `json:"name"`
+	Kind       string  `json:"kind"`
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	ErrorRate  float64 `json:"errorRate"`
+	Throughput float64 `json:"throughput"`
+	// LatencyMs is the time Cardinal took to reply, in milliseconds
+	LatencyMs Percentiles `json:"latencyMs"`
+	// ReceiptErrors counts the messages whose system returned an error
+	ReceiptErrors int `json:"receiptErrors,omitempty"`
+	// ReceiptLatencyMs is the time until the receipt of the message was seen, in milliseconds
+	ReceiptLatencyMs Percentiles `json:"receiptLatencyMs"`
+	// TickLag is how many ticks after the one it was queued for the message ran
+	TickLag   Percentiles  `json:"tickLag"`
+	TopErrors []ErrorCount `json:"topErrors,omitempty"`
+}
+
+// Percentiles summarize a distribution.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// ErrorCount is how many times an error happened.
+type ErrorCount struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
+
+// actionStats records the requests of an action.
+type actionStats struct {
+	mu   sync.Mutex
+	name string
+	kind string
+
+	requests         int
+	errors           map[string]int
+	latencies        []float64
+	receiptErrors    int
+	receiptLatencies []float64
+	tickLags         []float64
+}
+
+func newActionStats(action Action) *actionStats {
+	kind := kindMessage
+	if action.Query != "" {
+		kind = kindQuery
+	}
+	return &actionStats{name: action.Name(), kind: kind, errors: make(map[string]int)}
+}
+
+// request records a request and its reply time, or its error.
+func (s *actionStats) request(latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if err != nil {
+		s.errors[err.Error()]++
+		return
+	}
+	s.latencies = append(s.latencies, milliseconds(latency))
+}
+
+// receipt records the receipt of a message.
+func (s *actionStats) receipt(latency time.Duration, tickLag uint64, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receiptLatencies = append(s.receiptLatencies, milliseconds(latency))
+	s.tickLags = append(s.tickLags, float64(tickLag))
+	if failed {
+		s.receiptErrors++
+	}
+}
+
+// counts returns the number of requests and of failed ones.
+func (s *actionStats) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errors := 0
+	for _, count := range s.errors {
+		errors += count
+	}
+	return s.requests, errors
+}
+
+func (s *actionStats) report(seconds float64) ActionReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests, errors := s.requests, 0
+	topErrors := make([]ErrorCount, 0, len(s.errors))
+	for err, count := range s.errors {
+		errors += count
+		topErrors = append(topErrors, ErrorCount{Error: err, Count: count})
+	}
+	slices.SortFunc(topErrors, func(a, b ErrorCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Error, b.Error))
+	})
+	if len(topErrors) > maxTopErrors {
+		topErrors = topErrors[:maxTopErrors]
+	}
+	return ActionReport{
+		Name:             s.name,
+		Kind:             s.kind,
+		Requests:         requests,
+		Errors:           errors,
+		ErrorRate:        ratio(errors, requests),
+		Throughput:       perSecond(requests-errors, seconds),
+		LatencyMs:        percentiles(s.latencies),
+		ReceiptErrors:    s.receiptErrors,
+		ReceiptLatencyMs: percentiles(s.receiptLatencies),
+		TickLag:          percentiles(s.tickLags),
+		TopErrors:        topErrors,
+	}
+}
+
+// percentiles summarizes the values with the nearest-rank method.
+func percentiles(values []float64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return Percentiles{P50: rank(0.5), P90: rank(0.9), P99: rank(0.99), Max: sorted[len(sorted)-1]} //nolint:mnd // percentiles
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func ratio(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+func perSecond(count int, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(count) / seconds
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pelletier/go-toml"
+	"github.com/rotisserie/eris"
+	"golang.org/x/mod/semver"
+)
+
+// buildHeader is the toml header holding the build settings of the Cardinal image.
+const buildHeader = "build"
+
+var platformRegexp = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// CardinalBuild holds the build settings of the Cardinal image from the [build] section of world.toml.
+type CardinalBuild struct {
+	// Dockerfile is the path of a project Dockerfile replacing the embedded one, relative to the root directory
+	Dockerfile string `toml:"dockerfile"`
+	// ExtraStages is the path of a file with build stages appended to the Dockerfile
+	ExtraStages string `toml:"extra_stages"`
+	// Target is the build target of the image, defaults to runtime
+	Target string `toml:"target"`
+	// DebugTarget is the build target of the image when debugging, defaults to runtime-debug
+	DebugTarget string `toml:"debug_target"`
+	// Args are build args passed to the Dockerfile, values are converted to strings
+	Args map[string]any `toml:"args"`
+	// Platforms are the platforms of a multi-arch image, e.g. ["linux/amd64", "linux/arm64"]. The image is
+	// built for the platform of the Docker daemon when empty
+	Platforms []string `toml:"platforms"`
+	// SSH forwards the SSH agent to the build so private modules can be fetched over SSH
+	SSH bool `toml:"ssh"`
+	// AllowInsecureSecrets allows passing the GitHub token as a build arg when BuildKit is not available,
+	// which stores it in the image history
+	AllowInsecureSecrets bool `toml:"allow_insecure_secrets"`
+
+	// NoCache disables the layer cache of the build
+	NoCache bool `toml:"-"`
+	// Version is the semantic version the image is tagged with, in addition to the git commit
+	Version string `toml:"-"`
+
+	// DockerfileContent is the content of Dockerfile, empty when the embedded Dockerfile is used
+	DockerfileContent string `toml:"-"`
+	// ExtraStagesContent is the content of ExtraStages
+	ExtraStagesContent string `toml:"-"`
+}
+
+// SetArg sets a build arg, overriding the value from world.toml.
+func (b *CardinalBuild) SetArg(key, value string) {
+	if b.Args == nil {
+		b.Args = make(map[string]any)
+	}
+	b.Args[key] = value
+}
+
+// BuildArgs returns the build args as strings.
+func (b *CardinalBuild) BuildArgs() map[string]string {
+	args := make(map[string]string, len(b.Args))
+	for key, val := range b.Args {
+		args[key] = fmt.Sprintf("%v", val)
+	}
+	return args
+}
+
+// SetPlatforms sets the platforms of the image, overriding the value from world.toml.
+func (b *CardinalBuild) SetPlatforms(platforms []string) error {
+	for _, platform := range platforms {
+		if err := ValidatePlatform(platform); err != nil {
+			return err
+		}
+	}
+	b.Platforms = platforms
+	return nil
+}
+
+// SetVersion sets the semantic version the image is tagged with, e.g. v1.2.0 or 1.2.0-rc.1.
+func (b *CardinalBuild) SetVersion(version string) error {
+	if !semver.IsValid(version) && !semver.IsValid("v"+version) {
+		return eris.Errorf("invalid version %q, must be a semantic version like v1.2.0", version)
+	}
+	b.Version = version
+	return nil
+}
+
+// ValidatePlatform returns an error if platform is not in the os/arch[/variant] format.
+func ValidatePlatform(platform string) error {
+	if !platformRegexp.MatchString(platform) {
+		return eris.Errorf("invalid platform %q, must be os/arch[/variant], e.g. linux/amd64", platform)
+	}
+	return nil
+}
+
+// loadCardinalBuild reads the [build] section of the config file into cfg.CardinalBuild, along with the
+// Dockerfiles it references.
+func loadCardinalBuild(cfg *Config, section any) error {
+	m, ok := section.(map[string]any)
+	if !ok {
+		return eris.Errorf("[%s] must be a table", buildHeader)
+	}
+	tree, err := toml.TreeFromMap(m)
+	if err != nil {
+		return eris.Wrapf(err, "invalid [%s]", buildHeader)
+	}
+	if err := tree.Unmarshal(&cfg.CardinalBuild); err != nil {
+		return eris.Wrapf(err, "invalid [%s]", buildHeader)
+	}
+
+	for _, platform := range cfg.CardinalBuild.Platforms {
+		if err := ValidatePlatform(platform); err != nil {
+			return eris.Wrapf(err, "[%s]", buildHeader)
+		}
+	}
+
+	if cfg.CardinalBuild.Dockerfile != "" {
+		content, err := os.ReadFile(filepath.Join(cfg.RootDir, cfg.CardinalBuild.Dockerfile))
+		if err != nil {
+			return eris.Wrapf(err, "[%s] failed to read dockerfile", buildHeader)
+		}
+		cfg.CardinalBuild.DockerfileContent = string(content)
+	}
+	if cfg.CardinalBuild.ExtraStages != "" {
+		content, err := os.ReadFile(filepath.Join(cfg.RootDir, cfg.CardinalBuild.ExtraStages))
+		if err != nil {
+			return eris.Wrapf(err, "[%s] failed to read extra_stages", buildHeader)
+		}
+		cfg.CardinalBuild.ExtraStagesContent = string(content)
+	}
+
+	return nil
+}
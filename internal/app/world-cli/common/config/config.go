@@ -14,6 +14,12 @@ import (
 const (
 	WorldCLIConfigFileEnvVariable = "WORLD_CLI_CONFIG_FILE"
 	WorldCLIConfigFilename        = "world.toml"
+
+	// portsHeader is the toml header holding host port overrides for the local stack.
+	portsHeader = "ports"
+	// portOffsetKey is the key in the ports section holding the port offset of the shard.
+	portOffsetKey = "offset"
+	maxPort       = 65535
 )
 
 var (
@@ -23,18 +29,47 @@ var (
 	dockerEnvHeaders = []string{"cardinal", "evm", "nakama", "common"}
 
 	configFile string // Config file flag value
+
+	// ErrNoConfigFile is returned when no world.toml is found in the current directory or its parents.
+	ErrNoConfigFile = eris.New("No config file found")
 )
 
 type Config struct {
-	RootDir   string
-	GameDir   string
-	Detach    bool
+	RootDir string
+	GameDir string
+	Detach  bool
+	// Wait makes a detached start block until the stack is ready, for at most Timeout seconds
+	Wait      bool
 	Build     bool
 	Debug     bool
 	DevDA     bool
 	Telemetry bool
+	// CardinalOnHost is set when Cardinal runs natively on the host with world cardinal dev, so the
+	// containers reach it through the host
+	CardinalOnHost bool
+	// Grafana starts Grafana with the telemetry services, with the dashboards of the World CLI
+	Grafana   bool
 	Timeout   int
 	DockerEnv map[string]string
+	// Ports overrides the host ports published by the local stack, keyed by the names used in the
+	// [ports] section of world.toml.
+	Ports map[string]int
+	// PortOffset is added to every default host port so several shards can run side by side.
+	PortOffset int
+	// Services are the user defined services declared in the [services] section of world.toml.
+	Services []ServiceConfig
+	// CardinalBuild are the build settings of the Cardinal image from the [build] section of world.toml.
+	CardinalBuild CardinalBuild
+	// Runtime selects the container runtime from the [runtime] section of world.toml.
+	Runtime Runtime
+	// Resources are the resource limits and restart policies of the [resources] section of world.toml,
+	// keyed by service name.
+	Resources map[string]ServiceResources
+	// Dev are the hot reload settings of world cardinal dev from the [dev] section of world.toml.
+	Dev Dev
+	// NakamaFlags are extra command line flags of Nakama from the [nakama.flags] table of world.toml, keyed
+	// by flag name without dashes.
+	NakamaFlags map[string]string
 }
 
 // GetConfig returns a Config object. If a filename is provided, it will be used as the config file.
@@ -53,6 +88,17 @@ func GetConfig(filename *string) (*Config, error) {
 	return cfg, nil
 }
 
+// GetRuntimeConfig returns the config of the world.toml of the current directory, or an empty config outside
+// of a project. It is used by the commands that use the container runtime without running a shard, so they
+// still follow the [runtime] section of world.toml.
+func GetRuntimeConfig() (*Config, error) {
+	cfg, err := GetConfig(nil)
+	if eris.Is(err, ErrNoConfigFile) {
+		return &Config{DockerEnv: map[string]string{}}, nil
+	}
+	return cfg, err
+}
+
 // findAndLoadConfigFile searches for a config file based on the following priorities:
 // 1. A config file set via a flag
 // 2. A config file set via an environment variable
@@ -90,12 +136,13 @@ func findAndLoadConfigFile() (*Config, error) {
 		}
 	}
 
-	return nil, eris.New("No config file found")
+	return nil, ErrNoConfigFile
 }
 
 func loadConfigFromFile(filename string) (*Config, error) {
 	cfg := Config{
 		DockerEnv: map[string]string{},
+		Ports:     map[string]int{},
 	}
 	file, err := os.Open(filename)
 	if err != nil {
@@ -132,6 +179,12 @@ func loadConfigFromFile(filename string) (*Config, error) {
 			continue
 		}
 		for key, val := range m.(map[string]any) {
+			if header == nakamaHeader && key == nakamaFlagsKey {
+				if err := loadNakamaFlags(&cfg, val); err != nil {
+					return nil, err
+				}
+				continue
+			}
 			if _, okay := cfg.DockerEnv[key]; okay {
 				return nil, eris.Errorf("duplicate env variable %q", key)
 			}
@@ -139,7 +192,80 @@ func loadConfigFromFile(filename string) (*Config, error) {
 		}
 	}
 
+	// Check the Nakama config file and modules of the project.
+	if err := validateNakama(&cfg); err != nil {
+		return nil, err
+	}
+
+	// Load the host port overrides.
+	if ports, ok := data[portsHeader]; ok {
+		if err := loadPorts(&cfg, ports); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the build settings of the Cardinal image.
+	if build, ok := data[buildHeader]; ok {
+		if err := loadCardinalBuild(&cfg, build); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the container runtime selection.
+	if runtime, ok := data[runtimeHeader]; ok {
+		if err := loadRuntime(&cfg, runtime); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the hot reload settings of world cardinal dev.
+	if dev, ok := data[devHeader]; ok {
+		if err := loadDev(&cfg, dev); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the user defined services.
+	if services, ok := data[servicesHeader]; ok {
+		if err := loadServices(&cfg, services); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the resource limits, which can refer to the user defined services.
+	if resources, ok := data[resourcesHeader]; ok {
+		if err := loadResources(&cfg, resources); err != nil {
+			return nil, err
+		}
+	}
+
 	logger.Debugf("successfully loaded config from %q", filename)
 
 	return &cfg, nil
 }
+
+// loadPorts reads the [ports] section of the config file into cfg.Ports.
+func loadPorts(cfg *Config, section any) error {
+	m, ok := section.(map[string]any)
+	if !ok {
+		return eris.Errorf("[%s] must be a table", portsHeader)
+	}
+	for key, val := range m {
+		port, ok := val.(int64)
+		if !ok {
+			return eris.Errorf("[%s] %s must be an integer", portsHeader, key)
+		}
+		if key == portOffsetKey {
+			if port < 0 || port >= maxPort {
+				return eris.Errorf("[%s] %s must be between 0 and %d, got %d", portsHeader, key, maxPort-1, port)
+			}
+			cfg.PortOffset = int(port)
+			continue
+		}
+		if port < 1 || port > maxPort {
+			return eris.Errorf("[%s] %s must be between 1 and %d, got %d", portsHeader, key, maxPort, port)
+		}
+		cfg.Ports[key] = int(port)
+	}
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/pelletier/go-toml"
 	"gotest.tools/v3/assert"
@@ -262,3 +265,495 @@ func TestCanParseExampleConfig(t *testing.T) {
 	assert.Equal(t, "my-world-1", cfg.DockerEnv["CARDINAL_NAMESPACE"])
 	assert.Equal(t, "world-engine", cfg.DockerEnv["CHAIN_ID"])
 }
+
+func TestCanOverrideHostPorts(t *testing.T) {
+	content := `
+[cardinal]
+CARDINAL_NAMESPACE="alpha"
+
+[ports]
+cardinal = 5050
+redis = 6380
+`
+	filename := makeTempConfigWithContent(t, content)
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.Equal(t, 5050, cfg.Ports["cardinal"])
+	assert.Equal(t, 6380, cfg.Ports["redis"])
+
+	// ports are not exported as docker env variables
+	_, ok := cfg.DockerEnv["cardinal"]
+	assert.Check(t, !ok)
+}
+
+func TestInvalidHostPortsProduceError(t *testing.T) {
+	testCases := []struct {
+		name string
+		toml string
+	}{
+		{
+			name: "port is not an integer",
+			toml: `
+[ports]
+cardinal = "5050"
+`,
+		},
+		{
+			name: "port is out of range",
+			toml: `
+[ports]
+redis = 70000
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		filename := makeTempConfigWithContent(t, tc.toml)
+		_, err := GetConfig(&filename)
+		assert.Check(t, err != nil, "in %q", tc.name)
+	}
+}
+
+func TestCanSetPortOffset(t *testing.T) {
+	content := `
+[cardinal]
+CARDINAL_NAMESPACE="alpha"
+
+[ports]
+offset = 100
+`
+	filename := makeTempConfigWithContent(t, content)
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.Equal(t, 100, cfg.PortOffset)
+
+	// the offset is not a port override
+	_, ok := cfg.Ports["offset"]
+	assert.Check(t, !ok)
+}
+
+func TestCanSelectShardByNameOrDirectory(t *testing.T) {
+	root := t.TempDir()
+	assert.NilError(t, os.Mkdir(path.Join(root, ".git"), 0o755))
+	for _, shard := range []string{"alpha", "beta"} {
+		assert.NilError(t, os.MkdirAll(path.Join(root, "shards", shard), 0o755))
+		makeConfigAtPath(t, path.Join(root, "shards", shard, WorldCLIConfigFilename), shard+"-ns")
+	}
+	t.Chdir(path.Join(root, "shards", "alpha"))
+
+	shards, err := FindShards()
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(shards))
+
+	cfg, err := GetShardConfig("beta-ns")
+	assert.NilError(t, err)
+	assert.Equal(t, "beta-ns", getNamespace(t, cfg))
+
+	cfg, err = GetShardConfig(path.Join("shards", "alpha"))
+	assert.NilError(t, err)
+	assert.Equal(t, "alpha-ns", getNamespace(t, cfg))
+
+	_, err = GetShardConfig("gamma")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestInvalidShardIsSkipped(t *testing.T) {
+	root := t.TempDir()
+	assert.NilError(t, os.Mkdir(path.Join(root, ".git"), 0o755))
+	for _, shard := range []string{"alpha", "broken"} {
+		assert.NilError(t, os.MkdirAll(path.Join(root, "shards", shard), 0o755))
+	}
+	makeConfigAtPath(t, path.Join(root, "shards", "alpha", WorldCLIConfigFilename), "alpha-ns")
+	assert.NilError(t, os.WriteFile(path.Join(root, "shards", "broken", WorldCLIConfigFilename),
+		[]byte("[ports]\nredis = 70000\n"), 0o600))
+	t.Chdir(root)
+
+	// The other shards are still found and can be selected
+	shards, err := FindShards()
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(shards))
+	assert.Equal(t, "alpha-ns", shards[0].Name)
+	cfg, err := GetShardConfig("alpha-ns")
+	assert.NilError(t, err)
+	assert.Equal(t, "alpha-ns", getNamespace(t, cfg))
+
+	// Selecting the invalid shard fails with its error
+	_, err = GetShardConfig(path.Join("shards", "broken"))
+	assert.ErrorContains(t, err, path.Join("shards", "broken", WorldCLIConfigFilename))
+	_, err = GetShardConfig("broken")
+	assert.ErrorContains(t, err, "failed to load")
+}
+
+func TestCanDeclareServices(t *testing.T) {
+	content := `
+[cardinal]
+CARDINAL_NAMESPACE="alpha"
+
+[services.minio]
+image = "minio/minio:latest"
+command = ["server", "/data"]
+ports = ["9000", "9101:9001"]
+volumes = ["minio-data:/data", "./config:/etc/minio:ro"]
+depends_on = ["redis"]
+
+[services.minio.env]
+MINIO_ROOT_USER = "admin"
+MINIO_PORT = 9000
+
+[services.minio.healthcheck]
+test = ["CMD", "curl", "-f", "http://localhost:9000/minio/health/live"]
+interval = "5s"
+retries = 3
+
+[services.payments]
+build = { context = "./payments", target = "runtime" }
+depends_on = ["minio"]
+metrics = { port = 9102 }
+`
+	filename := makeTempConfigWithContent(t, content)
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(cfg.Services))
+
+	minio := cfg.Services[0]
+	assert.Equal(t, "minio", minio.Name)
+	assert.Equal(t, "minio/minio:latest", minio.Image)
+	assert.DeepEqual(t, []string{"server", "/data"}, minio.Command)
+	assert.DeepEqual(t, []string{"MINIO_PORT=9000", "MINIO_ROOT_USER=admin"}, minio.EnvList())
+
+	ports, err := minio.PublishedPorts()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []ServicePort{{Host: 9000, Container: 9000}, {Host: 9101, Container: 9001}}, ports)
+
+	mounts, err := minio.Mounts()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []ServiceVolume{
+		{Source: "minio-data", Target: "/data"},
+		{Source: "./config", Target: "/etc/minio", Bind: true, ReadOnly: true},
+	}, mounts)
+
+	assert.Equal(t, 3, minio.Healthcheck.Retries)
+	interval, _, _, err := minio.Healthcheck.Durations()
+	assert.NilError(t, err)
+	assert.Equal(t, 5*time.Second, interval)
+
+	payments := cfg.Services[1]
+	assert.Equal(t, "./payments", payments.Build.Context)
+	assert.Equal(t, "runtime", payments.Build.Target)
+	assert.Equal(t, 9102, payments.Metrics.Port)
+	assert.Assert(t, minio.Metrics == nil)
+
+	// services are not exported as docker env variables
+	_, ok := cfg.DockerEnv["minio"]
+	assert.Check(t, !ok)
+}
+
+func TestInvalidServicesProduceError(t *testing.T) {
+	testCases := []struct {
+		name string
+		toml string
+	}{
+		{
+			name: "no image or build",
+			toml: `
+[services.minio]
+ports = ["9000"]
+`,
+		},
+		{
+			name: "reserved name",
+			toml: `
+[services.redis]
+image = "redis:7"
+`,
+		},
+		{
+			name: "invalid port",
+			toml: `
+[services.minio]
+image = "minio/minio"
+ports = ["abc:9000"]
+`,
+		},
+		{
+			name: "invalid volume",
+			toml: `
+[services.minio]
+image = "minio/minio"
+volumes = ["data"]
+`,
+		},
+		{
+			name: "reserved volume name",
+			toml: `
+[services.minio]
+image = "minio/minio"
+volumes = ["redis-data:/data"]
+`,
+		},
+		{
+			name: "unknown dependency",
+			toml: `
+[services.minio]
+image = "minio/minio"
+depends_on = ["postgres"]
+`,
+		},
+		{
+			name: "dependency cycle",
+			toml: `
+[services.a]
+image = "a"
+depends_on = ["b"]
+
+[services.b]
+image = "b"
+depends_on = ["a"]
+`,
+		},
+		{
+			name: "invalid metrics path",
+			toml: `
+[services.minio]
+image = "minio/minio"
+metrics = { port = 9000, path = "metrics" }
+`,
+		},
+		{
+			name: "invalid healthcheck duration",
+			toml: `
+[services.minio]
+image = "minio/minio"
+healthcheck = { test = ["CMD", "true"], interval = "soon" }
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		filename := makeTempConfigWithContent(t, tc.toml)
+		_, err := GetConfig(&filename)
+		assert.Check(t, err != nil, "in %q", tc.name)
+	}
+}
+
+func TestCanConfigureCardinalBuild(t *testing.T) {
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(path.Join(dir, "cardinal.Dockerfile"), []byte("FROM scratch AS runtime\n"), 0o600))
+	assert.NilError(t, os.WriteFile(path.Join(dir, "extra.Dockerfile"), []byte("FROM runtime AS custom\n"), 0o600))
+	content := `
+[build]
+dockerfile = "cardinal.Dockerfile"
+extra_stages = "extra.Dockerfile"
+target = "custom"
+
+[build.args]
+GOFLAGS = "-mod=vendor"
+`
+	filename := path.Join(dir, WorldCLIConfigFilename)
+	assert.NilError(t, os.WriteFile(filename, []byte(content), 0o600))
+
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.Equal(t, "custom", cfg.CardinalBuild.Target)
+	assert.Equal(t, "FROM scratch AS runtime\n", cfg.CardinalBuild.DockerfileContent)
+	assert.Equal(t, "FROM runtime AS custom\n", cfg.CardinalBuild.ExtraStagesContent)
+
+	cfg.CardinalBuild.SetArg("LEVEL", "debug")
+	assert.DeepEqual(t, map[string]string{"GOFLAGS": "-mod=vendor", "LEVEL": "debug"}, cfg.CardinalBuild.BuildArgs())
+}
+
+func TestMissingBuildDockerfileProducesError(t *testing.T) {
+	content := `
+[build]
+dockerfile = "does-not-exist.Dockerfile"
+`
+	filename := makeTempConfigWithContent(t, content)
+	_, err := GetConfig(&filename)
+	assert.ErrorContains(t, err, "failed to read dockerfile")
+}
+
+func TestCanConfigureBuildPlatforms(t *testing.T) {
+	content := `
+[build]
+platforms = ["linux/amd64", "linux/arm64/v8"]
+`
+	filename := makeTempConfigWithContent(t, content)
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"linux/amd64", "linux/arm64/v8"}, cfg.CardinalBuild.Platforms)
+
+	assert.NilError(t, cfg.CardinalBuild.SetPlatforms([]string{"linux/arm64"}))
+	assert.DeepEqual(t, []string{"linux/arm64"}, cfg.CardinalBuild.Platforms)
+	assert.ErrorContains(t, cfg.CardinalBuild.SetPlatforms([]string{"amd64"}), "invalid platform")
+
+	filename = makeTempConfigWithContent(t, "[build]\nplatforms = [\"linux\"]\n")
+	_, err = GetConfig(&filename)
+	assert.ErrorContains(t, err, "invalid platform")
+}
+
+func TestCanSelectRuntime(t *testing.T) {
+	content := `
+[runtime]
+name = "docker"
+context = "build-box"
+`
+	filename := makeTempConfigWithContent(t, content)
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.Equal(t, Runtime{Name: RuntimeDocker, Context: "build-box"}, cfg.Runtime)
+
+	filename = makeTempConfigWithContent(t, "[runtime]\nname = \"containerd\"\n")
+	_, err = GetConfig(&filename)
+	assert.ErrorContains(t, err, "invalid runtime")
+
+	filename = makeTempConfigWithContent(t, "[runtime]\nname = \"podman\"\ncontext = \"build-box\"\n")
+	_, err = GetConfig(&filename)
+	assert.ErrorContains(t, err, "can't be used with Podman")
+}
+
+func TestRuntimeConfigFollowsTheProject(t *testing.T) {
+	// Outside of a project the runtime is detected
+	t.Chdir(t.TempDir())
+	cfg, err := GetRuntimeConfig()
+	assert.NilError(t, err)
+	assert.Equal(t, Runtime{}, cfg.Runtime)
+
+	dir := t.TempDir()
+	content := "[runtime]\nname = \"podman\"\n"
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, WorldCLIConfigFilename), []byte(content), 0600))
+	t.Chdir(dir)
+	cfg, err = GetRuntimeConfig()
+	assert.NilError(t, err)
+	assert.Equal(t, RuntimePodman, cfg.Runtime.Name)
+
+	// An invalid world.toml isn't ignored
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, WorldCLIConfigFilename), []byte("[runtime"), 0600))
+	_, err = GetRuntimeConfig()
+	assert.Check(t, err != nil)
+}
+
+func TestCanConfigureResources(t *testing.T) {
+	content := `
+[resources.cardinal]
+cpus = 2
+memory = "2g"
+restart = "on-failure:5"
+ulimits = { nofile = 65536, nproc = "1024:2048", core = { soft = 0, hard = 1024 } }
+
+[resources.worker]
+cpus = 0.5
+restart = "always"
+
+[services.worker]
+image = "worker:latest"
+`
+	filename := makeTempConfigWithContent(t, content)
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ServiceResources{
+		CPUs:       2,
+		Memory:     2 << 30,
+		Restart:    RestartOnFailure,
+		MaxRetries: 5,
+		Ulimits: map[string]Ulimit{
+			"nofile": {Soft: 65536, Hard: 65536},
+			"nproc":  {Soft: 1024, Hard: 2048},
+			"core":   {Soft: 0, Hard: 1024},
+		},
+	}, cfg.Resources["cardinal"])
+	assert.DeepEqual(t, ServiceResources{CPUs: 0.5, Restart: RestartAlways}, cfg.Resources["worker"])
+}
+
+func TestInvalidResourcesProduceError(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"unknown service", "[resources.db]\ncpus = 1\n", "unknown service"},
+		{"unknown key", "[resources.redis]\ncpu = 1\n", `unknown key "cpu"`},
+		{"negative cpus", "[resources.redis]\ncpus = -1\n", "cpus must be positive"},
+		{"invalid memory", "[resources.redis]\nmemory = \"lots\"\n", "invalid memory"},
+		{"tiny memory", "[resources.redis]\nmemory = \"1m\"\n", "at least 6m"},
+		{"invalid restart", "[resources.redis]\nrestart = \"sometimes\"\n", "invalid restart"},
+		{"retries without on-failure", "[resources.redis]\nrestart = \"always:3\"\n", "only on-failure"},
+		{"soft above hard", "[resources.redis]\nulimits = { nofile = \"10:5\" }\n", "greater than the hard limit"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := makeTempConfigWithContent(t, tc.content)
+			_, err := GetConfig(&filename)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestCanConfigureNakama(t *testing.T) {
+	content := `
+[nakama]
+NAKAMA_LOG_LEVEL = "DEBUG"
+NAKAMA_CONFIG_FILE = "nakama.yml"
+NAKAMA_MODULES_DIR = "modules"
+NAKAMA_GO_PLUGIN = "plugin"
+
+[nakama.flags]
+"session.token_expiry_sec" = 7200
+"runtime.js_entrypoint" = "project/index.js"
+`
+	filename := makeTempConfigWithContent(t, content)
+	root := filepath.Dir(filename)
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "nakama.yml"), []byte("name: game\n"), 0o600))
+	assert.NilError(t, os.MkdirAll(filepath.Join(root, "modules"), 0o755))
+	assert.NilError(t, os.MkdirAll(filepath.Join(root, "plugin"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "plugin", "go.mod"), []byte("module plugin\n"), 0o600))
+
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.Equal(t, "DEBUG", cfg.DockerEnv["NAKAMA_LOG_LEVEL"])
+	assert.Equal(t, "plugin", cfg.DockerEnv[NakamaGoPluginKey])
+	assert.DeepEqual(t, map[string]string{
+		"session.token_expiry_sec": "7200",
+		"runtime.js_entrypoint":    "project/index.js",
+	}, cfg.NakamaFlags)
+	_, ok := cfg.DockerEnv[nakamaFlagsKey]
+	assert.Assert(t, !ok)
+}
+
+func TestInvalidNakamaConfigProducesError(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"missing config file", "[nakama]\nNAKAMA_CONFIG_FILE = \"missing.yml\"\n", "is not a file"},
+		{"missing modules", "[nakama]\nNAKAMA_MODULES_DIR = \"missing\"\n", "is not a directory"},
+		{"plugin outside root", "[nakama]\nNAKAMA_GO_PLUGIN = \"../plugin\"\n", "inside the root directory"},
+		{"plugin without go.mod", "[nakama]\nNAKAMA_GO_PLUGIN = \"plugin\"\n", "with a go.mod"},
+		{"reserved flag", "[nakama.flags]\n\"database.address\" = \"x\"\n", "use DB_PASSWORD instead"},
+		{"dashed flag", "[nakama.flags]\n\"--logger.level\" = \"DEBUG\"\n", "invalid flag"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := makeTempConfigWithContent(t, tc.content)
+			_, err := GetConfig(&filename)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestCanConfigureDevReload(t *testing.T) {
+	filename := makeTempConfigWithContent(t, "[dev]\nignore = [\"migrations\", \"web/*.js\"]\ndebounce_ms = 500\n")
+	cfg, err := GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, append(slices.Clone(DefaultDevIgnore), "migrations", "web/*.js"), cfg.DevIgnore())
+	assert.Equal(t, 500, cfg.DevDebounceMs())
+
+	filename = makeTempConfigWithContent(t, "[dev]\nignore = [\"[\"]\n")
+	_, err = GetConfig(&filename)
+	assert.ErrorContains(t, err, "invalid ignore glob")
+
+	filename = makeTempConfigWithContent(t, "[cardinal]\nCARDINAL_NAMESPACE = \"alpha\"\n")
+	cfg, err = GetConfig(&filename)
+	assert.NilError(t, err)
+	assert.Equal(t, defaultDevDebounceMs, cfg.DevDebounceMs())
+}
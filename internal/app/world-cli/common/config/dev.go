@@ -0,0 +1,76 @@
+package config
+
+import (
+	"path"
+
+	"github.com/pelletier/go-toml"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	// devHeader is the toml header of the settings of world cardinal dev.
+	devHeader = "dev"
+
+	defaultDevDebounceMs = 300
+)
+
+// DefaultDevIgnore are the files of GameDir whose changes never reload Cardinal: hidden files and editor
+// backups, tests, and the directories the game doesn't build from.
+//
+//nolint:gochecknoglobals // read-only defaults
+var DefaultDevIgnore = []string{".*", "*~", "*_test.go", "assets", "tmp", "vendor"}
+
+// Dev are the settings of the hot reload of world cardinal dev, from the [dev] section of world.toml.
+type Dev struct {
+	// Ignore are the globs of the files whose changes don't reload Cardinal, in addition to DefaultDevIgnore.
+	// A glob with a slash matches the path relative to GameDir, a glob without a slash matches the name of
+	// the file or of any of its parent directories.
+	Ignore []string `toml:"ignore"`
+	// DebounceMs is how long changes must settle before Cardinal is rebuilt, in milliseconds
+	DebounceMs int `toml:"debounce_ms"`
+}
+
+// Validate returns an error if a glob is malformed or the debounce is negative.
+func (d Dev) Validate() error {
+	for _, glob := range d.Ignore {
+		if _, err := path.Match(glob, ""); err != nil {
+			return eris.Errorf("invalid ignore glob %q", glob)
+		}
+	}
+	if d.DebounceMs < 0 {
+		return eris.Errorf("debounce_ms must not be negative, got %d", d.DebounceMs)
+	}
+	return nil
+}
+
+// loadDev reads the [dev] section of the config file into cfg.Dev.
+func loadDev(cfg *Config, section any) error {
+	m, ok := section.(map[string]any)
+	if !ok {
+		return eris.Errorf("[%s] must be a table", devHeader)
+	}
+	tree, err := toml.TreeFromMap(m)
+	if err != nil {
+		return eris.Wrapf(err, "invalid [%s]", devHeader)
+	}
+	if err := tree.Unmarshal(&cfg.Dev); err != nil {
+		return eris.Wrapf(err, "invalid [%s]", devHeader)
+	}
+	if err := cfg.Dev.Validate(); err != nil {
+		return eris.Wrapf(err, "[%s]", devHeader)
+	}
+	return nil
+}
+
+// DevIgnore returns every glob of the files whose changes don't reload Cardinal.
+func (c *Config) DevIgnore() []string {
+	return append(append([]string{}, DefaultDevIgnore...), c.Dev.Ignore...)
+}
+
+// DevDebounceMs returns how long changes must settle before Cardinal is rebuilt, in milliseconds.
+func (c *Config) DevDebounceMs() int {
+	if c.Dev.DebounceMs == 0 {
+		return defaultDevDebounceMs
+	}
+	return c.Dev.DebounceMs
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+const (
+	// nakamaHeader is the toml header of the Nakama environment, also holding its project files and flags.
+	nakamaHeader = "nakama"
+	// nakamaFlagsKey is the table of the [nakama] section holding extra command line flags of Nakama.
+	nakamaFlagsKey = "flags"
+)
+
+// Keys of the [nakama] section pointing to project files, relative to the root directory.
+const (
+	// NakamaConfigFileKey is a Nakama config file replacing the config of the Nakama image
+	NakamaConfigFileKey = "NAKAMA_CONFIG_FILE"
+	// NakamaModulesDirKey is a directory of Lua, JavaScript and Go plugin runtime modules
+	NakamaModulesDirKey = "NAKAMA_MODULES_DIR"
+	// NakamaGoPluginKey is the directory of a Go module built as a Nakama Go plugin
+	NakamaGoPluginKey = "NAKAMA_GO_PLUGIN"
+)
+
+// nakamaReservedFlags are the flags set from other keys of world.toml, with the key to use instead.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var nakamaReservedFlags = map[string]string{
+	"config":           NakamaConfigFileKey,
+	"database.address": "DB_PASSWORD",
+}
+
+// nakamaFlagRegexp matches the name of a Nakama flag without its dashes.
+//
+//nolint:gochecknoglobals // compiled once
+var nakamaFlagRegexp = regexp.MustCompile(`^[a-z0-9_]+(\.[a-z0-9_]+)*$`)
+
+// loadNakamaFlags reads the [nakama.flags] table into cfg.NakamaFlags, e.g. "session.token_expiry_sec" = 7200.
+func loadNakamaFlags(cfg *Config, table any) error {
+	m, ok := table.(map[string]any)
+	if !ok {
+		return eris.Errorf("[%s.%s] must be a table", nakamaHeader, nakamaFlagsKey)
+	}
+	cfg.NakamaFlags = make(map[string]string, len(m))
+	for flag, val := range m {
+		if !nakamaFlagRegexp.MatchString(flag) {
+			return eris.Errorf("[%s.%s] invalid flag %q, must be a Nakama flag without dashes, e.g. "+
+				"session.token_expiry_sec", nakamaHeader, nakamaFlagsKey, flag)
+		}
+		if key, ok := nakamaReservedFlags[flag]; ok {
+			return eris.Errorf("[%s.%s] %s is set by the World CLI, use %s instead", nakamaHeader, nakamaFlagsKey,
+				flag, key)
+		}
+		if _, ok := val.(map[string]any); ok {
+			return eris.Errorf("[%s.%s] %s must be a value, write nested flags with dots", nakamaHeader,
+				nakamaFlagsKey, flag)
+		}
+		cfg.NakamaFlags[flag] = fmt.Sprintf("%v", val)
+	}
+	return nil
+}
+
+// validateNakama checks the project files of Nakama set in the [nakama] section.
+func validateNakama(cfg *Config) error {
+	if path := cfg.DockerEnv[NakamaConfigFileKey]; path != "" {
+		if info, err := os.Stat(filepath.Join(cfg.RootDir, path)); err != nil || info.IsDir() {
+			return eris.Errorf("[%s] %s %q is not a file", nakamaHeader, NakamaConfigFileKey, path)
+		}
+	}
+	if path := cfg.DockerEnv[NakamaModulesDirKey]; path != "" {
+		if info, err := os.Stat(filepath.Join(cfg.RootDir, path)); err != nil || !info.IsDir() {
+			return eris.Errorf("[%s] %s %q is not a directory", nakamaHeader, NakamaModulesDirKey, path)
+		}
+	}
+	if path := cfg.DockerEnv[NakamaGoPluginKey]; path != "" {
+		// The plugin is built from the root directory, like Cardinal
+		if filepath.IsAbs(path) || strings.HasPrefix(filepath.Clean(path), "..") {
+			return eris.Errorf("[%s] %s %q must be inside the root directory", nakamaHeader, NakamaGoPluginKey, path)
+		}
+		if _, err := os.Stat(filepath.Join(cfg.RootDir, path, "go.mod")); err != nil {
+			return eris.Errorf("[%s] %s %q must be a Go module directory with a go.mod", nakamaHeader,
+				NakamaGoPluginKey, path)
+		}
+	}
+	return nil
+}
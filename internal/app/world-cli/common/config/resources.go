@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	// resourcesHeader is the toml header holding the resource limits of the services of the local stack.
+	resourcesHeader = "resources"
+	// minMemory is the smallest memory limit accepted by Docker.
+	minMemory = 6 * units.MiB
+)
+
+// Restart policies of the [resources.<service>] sections.
+const (
+	RestartNo            = "no"
+	RestartAlways        = "always"
+	RestartUnlessStopped = "unless-stopped"
+	RestartOnFailure     = "on-failure"
+)
+
+// ServiceResources are the resource limits and restart policy of a service from a [resources.<service>]
+// section of world.toml, e.g.
+//
+//	[resources.cardinal]
+//	cpus = 2
+//	memory = "2g"
+//	restart = "on-failure:5"
+//	ulimits = { nofile = 65536 }
+type ServiceResources struct {
+	// CPUs is the number of CPUs the service can use, e.g. 1.5, 0 for no limit
+	CPUs float64
+	// Memory is the memory limit in bytes, 0 for no limit
+	Memory int64
+	// Restart is the restart policy, empty to keep the default policy of the service
+	Restart string
+	// MaxRetries is the maximum number of restarts of the on-failure policy, 0 for no maximum
+	MaxRetries int
+	// Ulimits are the ulimits of the service keyed by name, e.g. nofile
+	Ulimits map[string]Ulimit
+}
+
+// Ulimit is the soft and hard limit of a ulimit.
+type Ulimit struct {
+	Soft int64
+	Hard int64
+}
+
+// loadResources reads the [resources] section of the config file into cfg.Resources. It must run after the
+// user defined services are loaded, since their names are valid sections.
+func loadResources(cfg *Config, section any) error {
+	m, ok := section.(map[string]any)
+	if !ok {
+		return eris.Errorf("[%s] must be a table", resourcesHeader)
+	}
+
+	known := slices.Clone(BuiltinServiceNames)
+	for _, svc := range cfg.Services {
+		known = append(known, svc.Name)
+	}
+
+	cfg.Resources = make(map[string]ServiceResources, len(m))
+	for name, val := range m {
+		sectionName := fmt.Sprintf("[%s.%s]", resourcesHeader, name)
+		if !slices.Contains(known, name) {
+			return eris.Errorf("%s unknown service, must be one of: %s", sectionName, strings.Join(known, ", "))
+		}
+		table, ok := val.(map[string]any)
+		if !ok {
+			return eris.Errorf("%s must be a table", sectionName)
+		}
+		resources, err := parseServiceResources(table)
+		if err != nil {
+			return eris.Wrap(err, sectionName)
+		}
+		cfg.Resources[name] = resources
+	}
+	return nil
+}
+
+func parseServiceResources(table map[string]any) (ServiceResources, error) {
+	var resources ServiceResources
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var err error
+		switch val := table[key]; key {
+		case "cpus":
+			resources.CPUs, err = parseCPUs(val)
+		case "memory":
+			resources.Memory, err = parseMemory(val)
+		case "restart":
+			resources.Restart, resources.MaxRetries, err = parseRestart(val)
+		case "ulimits":
+			resources.Ulimits, err = parseUlimits(val)
+		default:
+			err = eris.Errorf("unknown key %q, must be cpus, memory, restart or ulimits", key)
+		}
+		if err != nil {
+			return ServiceResources{}, err
+		}
+	}
+	return resources, nil
+}
+
+func parseCPUs(val any) (float64, error) {
+	var cpus float64
+	switch v := val.(type) {
+	case int64:
+		cpus = float64(v)
+	case float64:
+		cpus = v
+	default:
+		return 0, eris.New("cpus must be a number")
+	}
+	if cpus <= 0 {
+		return 0, eris.Errorf("cpus must be positive, got %v", cpus)
+	}
+	return cpus, nil
+}
+
+// parseMemory parses a memory limit in bytes, or a size with a unit such as "512m" or "2g".
+func parseMemory(val any) (int64, error) {
+	var memory int64
+	switch v := val.(type) {
+	case int64:
+		memory = v
+	case string:
+		var err error
+		if memory, err = units.RAMInBytes(v); err != nil {
+			return 0, eris.Wrapf(err, "invalid memory %q", v)
+		}
+	default:
+		return 0, eris.New(`memory must be a size such as "512m" or "2g"`)
+	}
+	if memory < minMemory {
+		return 0, eris.Errorf("memory must be at least 6m, got %s", units.BytesSize(float64(memory)))
+	}
+	return memory, nil
+}
+
+// parseRestart parses a restart policy in the form of docker run --restart, e.g. "on-failure:5".
+func parseRestart(val any) (string, int, error) {
+	spec, ok := val.(string)
+	if !ok {
+		return "", 0, eris.New("restart must be a string")
+	}
+	policy, retries, hasRetries := strings.Cut(spec, ":")
+	switch policy {
+	case RestartNo, RestartAlways, RestartUnlessStopped:
+		if hasRetries {
+			return "", 0, eris.Errorf("invalid restart %q, only on-failure accepts a maximum of retries", spec)
+		}
+		return policy, 0, nil
+	case RestartOnFailure:
+		if !hasRetries {
+			return policy, 0, nil
+		}
+		maxRetries, err := strconv.Atoi(retries)
+		if err != nil || maxRetries < 0 {
+			return "", 0, eris.Errorf("invalid restart %q, the maximum of retries must be a positive integer", spec)
+		}
+		return policy, maxRetries, nil
+	default:
+		return "", 0, eris.Errorf("invalid restart %q, must be %s, %s, %s or %s[:max retries]", spec,
+			RestartNo, RestartAlways, RestartUnlessStopped, RestartOnFailure)
+	}
+}
+
+// parseUlimits parses ulimits given as a single limit, e.g. nofile = 65536, or as soft and hard limits,
+// e.g. nofile = "1024:65536" or nofile = { soft = 1024, hard = 65536 }.
+func parseUlimits(val any) (map[string]Ulimit, error) {
+	m, ok := val.(map[string]any)
+	if !ok {
+		return nil, eris.New("ulimits must be a table")
+	}
+	ulimits := make(map[string]Ulimit, len(m))
+	for name, limit := range m {
+		ulimit, err := parseUlimit(limit)
+		if err != nil {
+			return nil, eris.Wrapf(err, "invalid ulimit %s", name)
+		}
+		ulimits[name] = ulimit
+	}
+	return ulimits, nil
+}
+
+func parseUlimit(val any) (Ulimit, error) {
+	var ulimit Ulimit
+	switch v := val.(type) {
+	case int64:
+		ulimit = Ulimit{Soft: v, Hard: v}
+	case string:
+		soft, hard, _ := strings.Cut(v, ":")
+		if hard == "" {
+			hard = soft
+		}
+		var err error
+		if ulimit.Soft, err = strconv.ParseInt(soft, 10, 64); err != nil {
+			return Ulimit{}, eris.Errorf("%q must be limit or soft:hard", v)
+		}
+		if ulimit.Hard, err = strconv.ParseInt(hard, 10, 64); err != nil {
+			return Ulimit{}, eris.Errorf("%q must be limit or soft:hard", v)
+		}
+	case map[string]any:
+		soft, softOK := v["soft"].(int64)
+		hard, hardOK := v["hard"].(int64)
+		if !softOK || !hardOK || len(v) != 2 {
+			return Ulimit{}, eris.New("must set the integers soft and hard")
+		}
+		ulimit = Ulimit{Soft: soft, Hard: hard}
+	default:
+		return Ulimit{}, eris.New("must be an integer, a string soft:hard or a table with soft and hard")
+	}
+	if ulimit.Soft > ulimit.Hard {
+		return Ulimit{}, eris.Errorf("soft limit %d is greater than the hard limit %d", ulimit.Soft, ulimit.Hard)
+	}
+	return ulimit, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"github.com/pelletier/go-toml"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	// runtimeHeader is the toml header selecting the container runtime of the local stack.
+	runtimeHeader = "runtime"
+
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Runtime selects the container runtime from the [runtime] section of world.toml. The runtime is detected
+// when the section is empty.
+type Runtime struct {
+	// Name is the container runtime, docker or podman
+	Name string `toml:"name"`
+	// Context is the name of a Docker context, e.g. a remote host over SSH
+	Context string `toml:"context"`
+}
+
+// Validate returns an error if the runtime name is unknown or a Docker context is used with Podman.
+func (r Runtime) Validate() error {
+	switch r.Name {
+	case "", RuntimeDocker, RuntimePodman:
+	default:
+		return eris.Errorf("invalid runtime %q, must be %s or %s", r.Name, RuntimeDocker, RuntimePodman)
+	}
+	if r.Name == RuntimePodman && r.Context != "" {
+		return eris.Errorf("the Docker context %q can't be used with Podman, set CONTAINER_HOST to use a "+
+			"remote Podman", r.Context)
+	}
+	return nil
+}
+
+// loadRuntime reads the [runtime] section of the config file into cfg.Runtime.
+func loadRuntime(cfg *Config, section any) error {
+	m, ok := section.(map[string]any)
+	if !ok {
+		return eris.Errorf("[%s] must be a table", runtimeHeader)
+	}
+	tree, err := toml.TreeFromMap(m)
+	if err != nil {
+		return eris.Wrapf(err, "invalid [%s]", runtimeHeader)
+	}
+	if err := tree.Unmarshal(&cfg.Runtime); err != nil {
+		return eris.Wrapf(err, "invalid [%s]", runtimeHeader)
+	}
+	if err := cfg.Runtime.Validate(); err != nil {
+		return eris.Wrapf(err, "[%s]", runtimeHeader)
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"github.com/rotisserie/eris"
+)
+
+// servicesHeader is the toml header holding the user defined services of the local stack.
+const servicesHeader = "services"
+
+// BuiltinServiceNames are the services of the local stack managed by the World CLI. User defined
+// services can't reuse these names, but can depend on them.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var BuiltinServiceNames = []string{
+	"cardinal", "redis", "nakama", "nakama-db", "jaeger", "otel-collector", "prometheus", "grafana",
+	"redis-exporter", "evm", "celestia-devnet",
+}
+
+// BuiltinVolumeNames are the named volumes of the built-in services, scoped to the namespace like the volumes of
+// user defined services, which can't reuse these names.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var BuiltinVolumeNames = []string{"redis-data", "grafana-data"}
+
+var serviceNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ServiceConfig is a user defined service declared in a [services.<name>] section of world.toml.
+type ServiceConfig struct {
+	// Name is the name of the [services.<name>] section
+	Name string `toml:"-"`
+	// Image is the image to run, or the tag of the built image when Build is set
+	Image   string   `toml:"image"`
+	Build   *Build   `toml:"build"`
+	Command []string `toml:"command"`
+	// Env is the environment of the container, values are converted to strings
+	Env map[string]any `toml:"env"`
+	// Ports are published ports in the form "host:container" or "container"
+	Ports []string `toml:"ports"`
+	// Volumes are mounts in the form "name:/path" for a named volume or "./dir:/path" for a bind mount
+	Volumes     []string     `toml:"volumes"`
+	Healthcheck *Healthcheck `toml:"healthcheck"`
+	// DependsOn are the services that must be running, and healthy if they have a healthcheck,
+	// before this service starts
+	DependsOn []string `toml:"depends_on"`
+	// Metrics is the Prometheus endpoint of the service, scraped when telemetry is enabled
+	Metrics *Metrics `toml:"metrics"`
+}
+
+// Metrics is the Prometheus metrics endpoint of a user defined service.
+type Metrics struct {
+	// Port is the container port serving the metrics
+	Port int `toml:"port"`
+	// Path is the path of the metrics, /metrics by default
+	Path string `toml:"path"`
+}
+
+// Build is the build context of a user defined service.
+type Build struct {
+	// Context is the build context directory, relative to the root directory
+	Context string `toml:"context"`
+	// Dockerfile is the path of the Dockerfile inside the build context
+	Dockerfile string `toml:"dockerfile"`
+	Target     string `toml:"target"`
+	// Args are build args passed to the Dockerfile, values are converted to strings
+	Args map[string]any `toml:"args"`
+}
+
+// Healthcheck is the healthcheck of a user defined service. Durations use the Go duration format, e.g. "5s".
+type Healthcheck struct {
+	// Test is the command to run, e.g. ["CMD", "curl", "-f", "http://localhost"]
+	Test        []string `toml:"test"`
+	Interval    string   `toml:"interval"`
+	Timeout     string   `toml:"timeout"`
+	StartPeriod string   `toml:"start_period"`
+	Retries     int      `toml:"retries"`
+}
+
+// ServicePort is a port published by a user defined service.
+type ServicePort struct {
+	Host      int
+	Container int
+}
+
+// ServiceVolume is a mount of a user defined service.
+type ServiceVolume struct {
+	// Source is a volume name, or a host path for bind mounts
+	Source   string
+	Target   string
+	Bind     bool
+	ReadOnly bool
+}
+
+// PublishedPorts parses the ports of the service. A port without a host part is published on the same host port.
+func (s ServiceConfig) PublishedPorts() ([]ServicePort, error) {
+	ports := make([]ServicePort, 0, len(s.Ports))
+	for _, spec := range s.Ports {
+		hostPart, containerPart, found := strings.Cut(spec, ":")
+		if !found {
+			containerPart = hostPart
+		}
+		containerPort, err := parsePort(containerPart)
+		if err != nil {
+			return nil, eris.Wrapf(err, "invalid port %q", spec)
+		}
+		hostPort := containerPort
+		if found {
+			if hostPort, err = parsePort(hostPart); err != nil {
+				return nil, eris.Wrapf(err, "invalid port %q", spec)
+			}
+		}
+		ports = append(ports, ServicePort{Host: hostPort, Container: containerPort})
+	}
+	return ports, nil
+}
+
+// Mounts parses the volumes of the service. Sources starting with ".", "/" or "~" are bind mounts,
+// anything else is a named volume.
+func (s ServiceConfig) Mounts() ([]ServiceVolume, error) {
+	volumes := make([]ServiceVolume, 0, len(s.Volumes))
+	for _, spec := range s.Volumes {
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || !strings.HasPrefix(parts[1], "/") {
+			return nil, eris.Errorf("invalid volume %q, must be source:/container/path[:ro]", spec)
+		}
+		volume := ServiceVolume{
+			Source: parts[0],
+			Target: parts[1],
+			Bind:   strings.ContainsAny(parts[0][:1], "./~"),
+		}
+		if len(parts) == 3 {
+			if parts[2] != "ro" && parts[2] != "rw" {
+				return nil, eris.Errorf("invalid volume %q, mode must be ro or rw", spec)
+			}
+			volume.ReadOnly = parts[2] == "ro"
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+// Durations returns the parsed interval, timeout and start period of the healthcheck.
+func (h Healthcheck) Durations() (time.Duration, time.Duration, time.Duration, error) {
+	interval, err := parseDuration(h.Interval)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	timeout, err := parseDuration(h.Timeout)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	startPeriod, err := parseDuration(h.StartPeriod)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return interval, timeout, startPeriod, nil
+}
+
+func parseDuration(val string) (time.Duration, error) {
+	if val == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, eris.Wrapf(err, "invalid healthcheck duration %q", val)
+	}
+	return d, nil
+}
+
+func parsePort(val string) (int, error) {
+	port, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, err
+	}
+	if port < 1 || port > maxPort {
+		return 0, eris.Errorf("port must be between 1 and %d", maxPort)
+	}
+	return port, nil
+}
+
+// EnvList returns the environment of the service as a list of KEY=value pairs, sorted by key.
+func (s ServiceConfig) EnvList() []string {
+	env := make([]string, 0, len(s.Env))
+	for key, val := range s.Env {
+		env = append(env, fmt.Sprintf("%s=%v", key, val))
+	}
+	sort.Strings(env)
+	return env
+}
+
+// loadServices reads the [services] section of the config file into cfg.Services.
+func loadServices(cfg *Config, section any) error {
+	m, ok := section.(map[string]any)
+	if !ok {
+		return eris.Errorf("[%s] must be a table", servicesHeader)
+	}
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		table, ok := m[name].(map[string]any)
+		if !ok {
+			return eris.Errorf("[%s.%s] must be a table", servicesHeader, name)
+		}
+		tree, err := toml.TreeFromMap(table)
+		if err != nil {
+			return eris.Wrapf(err, "invalid [%s.%s]", servicesHeader, name)
+		}
+		svc := ServiceConfig{Name: name}
+		if err := tree.Unmarshal(&svc); err != nil {
+			return eris.Wrapf(err, "invalid [%s.%s]", servicesHeader, name)
+		}
+		cfg.Services = append(cfg.Services, svc)
+	}
+
+	return validateServices(cfg.Services)
+}
+
+func validateServices(services []ServiceConfig) error {
+	known := slices.Clone(BuiltinServiceNames)
+	for _, svc := range services {
+		known = append(known, svc.Name)
+	}
+
+	for _, svc := range services {
+		section := fmt.Sprintf("[%s.%s]", servicesHeader, svc.Name)
+		if !serviceNameRegexp.MatchString(svc.Name) {
+			return eris.Errorf("%s name must only contain lowercase letters, digits, '_' and '-'", section)
+		}
+		if slices.Contains(BuiltinServiceNames, svc.Name) {
+			return eris.Errorf("%s name is reserved for a built-in service", section)
+		}
+		if svc.Image == "" && svc.Build == nil {
+			return eris.Errorf("%s must set either image or build", section)
+		}
+		if svc.Build != nil && svc.Build.Context == "" {
+			return eris.Errorf("%s build.context must be set", section)
+		}
+		if _, err := svc.PublishedPorts(); err != nil {
+			return eris.Wrap(err, section)
+		}
+		volumes, err := svc.Mounts()
+		if err != nil {
+			return eris.Wrap(err, section)
+		}
+		for _, volume := range volumes {
+			if !volume.Bind && slices.Contains(BuiltinVolumeNames, volume.Source) {
+				return eris.Errorf("%s volume name %q is reserved for a built-in service", section, volume.Source)
+			}
+		}
+		if svc.Healthcheck != nil {
+			if len(svc.Healthcheck.Test) == 0 {
+				return eris.Errorf("%s healthcheck.test must be set", section)
+			}
+			if _, _, _, err := svc.Healthcheck.Durations(); err != nil {
+				return eris.Wrap(err, section)
+			}
+		}
+		if svc.Metrics != nil {
+			if svc.Metrics.Port < 1 || svc.Metrics.Port > maxPort {
+				return eris.Errorf("%s metrics.port must be between 1 and %d", section, maxPort)
+			}
+			if svc.Metrics.Path != "" && !strings.HasPrefix(svc.Metrics.Path, "/") {
+				return eris.Errorf("%s metrics.path must start with /", section)
+			}
+		}
+		for _, dep := range svc.DependsOn {
+			if dep == svc.Name {
+				return eris.Errorf("%s can't depend on itself", section)
+			}
+			if !slices.Contains(known, dep) {
+				return eris.Errorf("%s depends on unknown service %q", section, dep)
+			}
+		}
+	}
+
+	return checkServiceCycles(services)
+}
+
+// checkServiceCycles returns an error if the dependencies of the services form a cycle.
+func checkServiceCycles(services []ServiceConfig) error {
+	deps := make(map[string][]string, len(services))
+	for _, svc := range services {
+		deps[svc.Name] = svc.DependsOn
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(services))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return eris.Errorf("[%s] dependency cycle: %s", servicesHeader, strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	for _, svc := range services {
+		if err := visit(svc.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
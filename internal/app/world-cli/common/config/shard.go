@@ -0,0 +1,157 @@
+package config
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+)
+
+// Shard is a game shard defined by a world.toml file.
+type Shard struct {
+	// Name is the CARDINAL_NAMESPACE of the shard
+	Name string
+	// Dir is the directory of the config file relative to the repository root
+	Dir        string
+	ConfigFile string
+	// err is why its world.toml failed to load, the shard is then named after its directory
+	err error
+}
+
+// skippedShardDirs are never searched for world.toml files.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var skippedShardDirs = []string{"node_modules", "vendor"}
+
+// FindShards returns every shard defined by a world.toml file inside the repository
+// that contains the current directory. Invalid world.toml files are skipped with a warning.
+func FindShards() ([]Shard, error) {
+	shards, err := findShards()
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([]Shard, 0, len(shards))
+	for _, shard := range shards {
+		if shard.err != nil {
+			warnInvalidShard(shard)
+			continue
+		}
+		valid = append(valid, shard)
+	}
+	return valid, nil
+}
+
+// findShards returns every shard of the repository, including those whose world.toml is invalid.
+func findShards() ([]Shard, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root := findRepoRoot(cwd)
+
+	shards := make([]Shard, 0)
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || slices.Contains(skippedShardDirs, d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != WorldCLIConfigFilename {
+			return nil
+		}
+
+		dir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		shard := Shard{Name: filepath.Base(filepath.Dir(path)), Dir: dir, ConfigFile: path}
+		// One invalid world.toml doesn't prevent using the other shards
+		cfg, err := loadConfigFromFile(path)
+		if err != nil {
+			shard.err = eris.Wrapf(err, "failed to load %s", path)
+		} else if name := cfg.DockerEnv["CARDINAL_NAMESPACE"]; name != "" {
+			shard.Name = name
+		}
+		shards = append(shards, shard)
+		return nil
+	})
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to search for shards")
+	}
+
+	return shards, nil
+}
+
+// GetShardConfig returns the config of the shard with the given name. A shard can be selected
+// either by its CARDINAL_NAMESPACE or by the directory of its world.toml. Only the world.toml of the
+// selected shard must be valid, the invalid ones of other shards are skipped with a warning.
+func GetShardConfig(name string) (*Config, error) {
+	shards, err := findShards()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Shard
+	for _, shard := range shards {
+		if shard.Name == name || shard.Dir == filepath.Clean(name) {
+			matches = append(matches, shard)
+		} else if shard.err != nil {
+			warnInvalidShard(shard)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		names := make([]string, 0, len(shards))
+		for _, shard := range shards {
+			if shard.err == nil {
+				names = append(names, shard.Name)
+			}
+		}
+		return nil, eris.Errorf("shard %q not found, available shards: (%s)", name, strings.Join(names, ", "))
+	case 1:
+		if matches[0].err != nil {
+			return nil, matches[0].err
+		}
+		cfg, err := loadConfigFromFile(matches[0].ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Build = true
+		return cfg, nil
+	default:
+		files := make([]string, 0, len(matches))
+		for _, shard := range matches {
+			files = append(files, shard.ConfigFile)
+		}
+		return nil, eris.Errorf("shard %q is defined more than once: (%s)", name, strings.Join(files, ", "))
+	}
+}
+
+// warnInvalidShard warns that the shard is skipped because its world.toml is invalid.
+func warnInvalidShard(shard Shard) {
+	logger.Warnf("Skipping the shard of %s, its config is invalid: %v", shard.ConfigFile, shard.err)
+}
+
+// findRepoRoot returns the closest parent directory containing a .git entry, or dir itself if there is none.
+func findRepoRoot(dir string) string {
+	for curr := dir; ; {
+		if _, err := os.Stat(filepath.Join(curr, ".git")); err == nil {
+			return curr
+		}
+		parent := filepath.Dir(curr)
+		if parent == curr {
+			return dir
+		}
+		curr = parent
+	}
+}
@@ -1,10 +1,12 @@
 package dependency
 
 import (
+	"context"
 	"errors"
 	"os/exec"
 
 	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/endpoint"
 )
 
 var (
@@ -21,16 +23,19 @@ Learn how to install Git: https://github.com/git-guides/install-git`,
 Learn how to install Go: https://go.dev/doc/install`,
 	}
 	Docker = Dependency{
-		Name: "Docker",
-		Cmd:  exec.Command("docker", "--version"),
-		Help: `Docker is required to build and run World Engine game shards.
-Learn how to install Docker: https://docs.docker.com/engine/install/`,
+		Name:      "Docker or Podman",
+		CheckFunc: checkRuntimeInstalled,
+		Help: `Docker or Podman is required to build and run World Engine game shards.
+Learn how to install Docker: https://docs.docker.com/engine/install/
+Learn how to install Podman: https://podman.io/docs/installation`,
 	}
 	DockerDaemon = Dependency{
-		Name: "Docker daemon is running",
-		Cmd:  exec.Command("docker", "info"),
-		Help: `Docker daemon needs to be running.
-If you use Docker Desktop, make sure that you have ran it`,
+		Name:      "Container runtime is running",
+		CheckFunc: checkRuntimeRunning,
+		Help: `The Docker daemon or the Podman socket needs to be running.
+If you use Docker Desktop, make sure that you have ran it.
+If you use Podman, start its socket with: podman machine start (macOS, Windows)
+or: systemctl --user enable --now podman.socket (Linux)`,
 	}
 	AlwaysFail = Dependency{
 		Name: "Always fails",
@@ -42,11 +47,17 @@ If you use Docker Desktop, make sure that you have ran it`,
 type Dependency struct {
 	Name string
 	Cmd  *exec.Cmd
-	Help string
+	// CheckFunc replaces Cmd for the dependencies that can't be checked with a single command
+	CheckFunc func() error
+	Help      string
 }
 
 func (d Dependency) Check() error {
-	if err := d.Cmd.Run(); err != nil {
+	run := d.CheckFunc
+	if run == nil {
+		run = d.Cmd.Run
+	}
+	if err := run(); err != nil {
 		return eris.Wrapf(err, "dependency check for %q failed", d.Name)
 	}
 	return nil
@@ -59,3 +70,23 @@ func Check(deps ...Dependency) error {
 	}
 	return errors.Join(errs...)
 }
+
+// checkRuntimeInstalled checks the CLI of the selected container runtime is installed. A remote Docker
+// context still needs the docker CLI, it runs the SSH connection.
+func checkRuntimeInstalled() error {
+	ep, err := endpoint.Current()
+	if err != nil {
+		return err
+	}
+	_, err = exec.LookPath(ep.Runtime)
+	return err
+}
+
+// checkRuntimeRunning checks the selected container runtime answers on its endpoint.
+func checkRuntimeRunning() error {
+	ep, err := endpoint.Current()
+	if err != nil {
+		return err
+	}
+	return endpoint.Ping(context.Background(), ep)
+}
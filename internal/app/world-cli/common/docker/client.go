@@ -3,15 +3,18 @@ package docker
 import (
 	"bytes"
 	"context"
-	"encoding/binary"
 	"io"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/rotisserie/eris"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/endpoint"
 	"pkg.world.dev/world-cli/internal/pkg/logger"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
 )
 
 const (
@@ -44,39 +47,61 @@ var (
 	}
 )
 
+// runtimeCheckTimeout is how long NewClient waits for the container runtime to report its name.
+const runtimeCheckTimeout = 5 * time.Second
+
 type Client struct {
-	client *client.Client
-	cfg    *config.Config
+	client   *client.Client
+	cfg      *config.Config
+	endpoint endpoint.Endpoint
 }
 
-func NewClient(cfg *config.Config) (*Client, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewClient returns a client of the container runtime selected by the command line flags, the [runtime]
+// section of world.toml or the environment.
+func NewClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	ep, err := endpoint.Resolve(cfg.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := endpoint.NewAPIClient(ep)
 	if err != nil {
 		return nil, eris.Wrap(err, "Failed to create docker client")
 	}
 
-	// Set BuildkitSupport
-	service.SetBuildkitSupport(checkBuildkitSupport(cli))
+	// Set BuildkitSupport, Podman builds with Buildah and has no BuildKit session API
+	service.SetBuildkitSupport(!isPodman(ctx, cli) && checkBuildkitSupport(cli))
 
 	return &Client{
-		client: cli,
-		cfg:    cfg,
+		client:   cli,
+		cfg:      cfg,
+		endpoint: ep,
 	}, nil
 }
 
+// Endpoint returns the endpoint of the container runtime of the client.
+func (c *Client) Endpoint() endpoint.Endpoint {
+	return c.endpoint
+}
+
+// isPodman returns true when the API is served by Podman, including behind a Docker socket or context.
+func isPodman(ctx context.Context, cli *client.Client) bool {
+	// An unreachable runtime fails later with a clearer error, don't hang on it here
+	ctx, cancel := context.WithTimeout(ctx, runtimeCheckTimeout)
+	defer cancel()
+	runtimeName, _, err := endpoint.ServerRuntime(ctx, cli)
+	return err == nil && runtimeName == config.RuntimePodman
+}
+
 func (c *Client) Close() error {
 	return c.client.Close()
 }
 
 func (c *Client) Build(ctx context.Context,
-	pushTo string,
-	pushAuth string,
+	push PushOptions,
 	serviceBuilders ...service.Builder) error {
-	namespace := c.cfg.DockerEnv["CARDINAL_NAMESPACE"]
-
-	err := c.processVolume(ctx, CREATE, namespace)
-	if err != nil {
-		return eris.Wrap(err, "Failed to create volume")
+	// Build the Nakama Go plugin of the project against the version of the Nakama image
+	if err := c.resolveNakamaVersion(ctx, serviceBuilders); err != nil {
+		return err
 	}
 
 	// get all services
@@ -86,6 +111,22 @@ func (c *Client) Build(ctx context.Context,
 		dockerServices = append(dockerServices, ds)
 	}
 
+	// Fail early when the daemon can't build for the requested platforms
+	if err := c.checkPlatformSupport(ctx); err != nil {
+		return err
+	}
+
+	err := c.processVolumes(ctx, CREATE, dockerServices...)
+	if err != nil {
+		return eris.Wrap(err, "Failed to create volume")
+	}
+
+	// Pin the base images so the build is reproducible
+	err = c.pinBaseImages(ctx, dockerServices)
+	if err != nil {
+		return eris.Wrap(err, "Failed to pin base images")
+	}
+
 	// Pull all images before starting containers
 	err = c.pullImages(ctx, dockerServices...)
 	if err != nil {
@@ -98,8 +139,8 @@ func (c *Client) Build(ctx context.Context,
 		return eris.Wrap(err, "Failed to build images")
 	}
 
-	if pushTo != "" {
-		err := c.pushImages(ctx, pushTo, pushAuth, dockerServices...)
+	if len(push.Targets) > 0 {
+		err := c.pushImages(ctx, push, dockerServices...)
 		if err != nil {
 			return eris.Wrap(err, "Failed to push images")
 		}
@@ -109,9 +150,33 @@ func (c *Client) Build(ctx context.Context,
 
 func (c *Client) Start(ctx context.Context,
 	serviceBuilders ...service.Builder) error {
+	// Build the Nakama Go plugin of the project against the version of the Nakama image, which the Nakama
+	// service is built with
+	if c.cfg.Build {
+		if err := c.resolveNakamaVersion(ctx, serviceBuilders); err != nil {
+			return err
+		}
+	}
+
+	// get all services, for the checks and the start alike
+	dockerServices := make([]service.Service, 0, len(serviceBuilders))
+	for _, sb := range serviceBuilders {
+		dockerServices = append(dockerServices, sb(c.cfg))
+	}
+
+	// Fail on a dependency that would never start before touching any container
+	if err := checkDependencies(dockerServices); err != nil {
+		return err
+	}
+
+	// Make sure every host port can be bound before touching any container
+	if err := c.verifyPorts(ctx, dockerServices); err != nil {
+		return err
+	}
+
 	defer func() {
 		if !c.cfg.Detach {
-			err := c.Stop(context.Background(), serviceBuilders...)
+			err := c.stopServices(context.Background(), dockerServices)
 			if err != nil {
 				logger.Error("Failed to stop containers", err)
 			}
@@ -124,16 +189,17 @@ func (c *Client) Start(ctx context.Context,
 		return eris.Wrap(err, "Failed to create network")
 	}
 
-	err = c.processVolume(ctx, CREATE, namespace)
+	err = c.processVolumes(ctx, CREATE, dockerServices...)
 	if err != nil {
 		return eris.Wrap(err, "Failed to create volume")
 	}
 
-	// get all services
-	dockerServices := make([]service.Service, 0)
-	for _, sb := range serviceBuilders {
-		ds := sb(c.cfg)
-		dockerServices = append(dockerServices, ds)
+	// Pin the base images so the build is reproducible
+	if c.cfg.Build {
+		err = c.pinBaseImages(ctx, dockerServices)
+		if err != nil {
+			return eris.Wrap(err, "Failed to pin base images")
+		}
 	}
 
 	// Pull all images before starting containers
@@ -151,16 +217,29 @@ func (c *Client) Start(ctx context.Context,
 	}
 
 	// Start all containers
+	startedAt := time.Now()
 	err = c.processMultipleContainers(ctx, START, dockerServices...)
 	if err != nil {
 		return eris.Wrap(err, "Failed to start containers")
 	}
 
-	// log containers if not detached
-	if !c.cfg.Detach {
-		c.logMultipleContainers(ctx, dockerServices...)
+	if c.cfg.Detach {
+		if c.cfg.Wait {
+			return c.waitReadyAndReport(ctx, startedAt, dockerServices...)
+		}
+		return nil
 	}
 
+	// Report the readiness of the stack in the log stream
+	go func() {
+		if err := c.waitReadyAndReport(ctx, startedAt, dockerServices...); err != nil && ctx.Err() == nil {
+			printer.Errorf("%v\n", err)
+		}
+	}()
+
+	// log containers if not detached
+	c.logMultipleContainers(ctx, dockerServices...)
+
 	return nil
 }
 
@@ -172,6 +251,11 @@ func (c *Client) Stop(ctx context.Context, serviceBuilders ...service.Builder) e
 		dockerServices = append(dockerServices, ds)
 	}
 
+	return c.stopServices(ctx, dockerServices)
+}
+
+// stopServices stops the containers of the given services.
+func (c *Client) stopServices(ctx context.Context, dockerServices []service.Service) error {
 	// Stop all containers
 	err := c.processMultipleContainers(ctx, STOP, dockerServices...)
 	if err != nil {
@@ -195,7 +279,7 @@ func (c *Client) Purge(ctx context.Context, serviceBuilders ...service.Builder)
 		return eris.Wrap(err, "Failed to remove containers")
 	}
 
-	err = c.processVolume(ctx, REMOVE, c.cfg.DockerEnv["CARDINAL_NAMESPACE"])
+	err = c.processVolumes(ctx, REMOVE, dockerServices...)
 	if err != nil {
 		return err
 	}
@@ -203,6 +287,19 @@ func (c *Client) Purge(ctx context.Context, serviceBuilders ...service.Builder)
 	return nil
 }
 
+// Remove removes the containers of the services and keeps their volumes.
+func (c *Client) Remove(ctx context.Context, serviceBuilders ...service.Builder) error {
+	dockerServices := make([]service.Service, 0, len(serviceBuilders))
+	for _, sb := range serviceBuilders {
+		dockerServices = append(dockerServices, sb(c.cfg))
+	}
+
+	if err := c.processMultipleContainers(ctx, REMOVE, dockerServices...); err != nil {
+		return eris.Wrap(err, "Failed to remove containers")
+	}
+	return nil
+}
+
 func (c *Client) Restart(ctx context.Context,
 	serviceBuilders ...service.Builder) error {
 	// stop containers
@@ -215,61 +312,95 @@ func (c *Client) Restart(ctx context.Context,
 }
 
 func (c *Client) Exec(ctx context.Context, containerID string, cmd []string) (string, error) {
+	// Keep stdout only, stderr is discarded
+	var outputBuf bytes.Buffer
+	if _, err := c.ExecWithOptions(ctx, containerID, cmd, ExecOptions{Stdout: &outputBuf}); err != nil {
+		return "", err
+	}
+
+	// Return the output as a string
+	return outputBuf.String(), nil
+}
+
+// ExecOptions are the streams and terminal of a command run by ExecWithOptions.
+type ExecOptions struct {
+	Env []string
+	// Stdin is sent to the command when set, the streams without a writer are discarded
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// TTY runs the command in a terminal of ConsoleSize (height, width), its output is then all on Stdout
+	TTY         bool
+	ConsoleSize *[2]uint
+}
+
+// ExecWithOptions runs a command in a running container and returns its exit code.
+func (c *Client) ExecWithOptions(ctx context.Context, containerID string, cmd []string,
+	opts ExecOptions) (int, error) {
 	// Create Exec Instance
 	exec := container.ExecOptions{
 		Cmd:          cmd,
+		Env:          opts.Env,
+		Tty:          opts.TTY,
+		ConsoleSize:  opts.ConsoleSize,
+		AttachStdin:  opts.Stdin != nil,
 		AttachStdout: true,
 		AttachStderr: true,
 	}
 
 	execIDResp, err := c.client.ContainerExecCreate(ctx, containerID, exec)
 	if err != nil {
-		return "", eris.Wrapf(err, "Failed to create exec instance")
+		return 0, eris.Wrapf(err, "Failed to create exec instance")
 	}
 
 	// Start Exec Instance
-	resp, err := c.client.ContainerExecAttach(ctx, execIDResp.ID, container.ExecAttachOptions{})
+	resp, err := c.client.ContainerExecAttach(ctx, execIDResp.ID, container.ExecAttachOptions{
+		Tty:         opts.TTY,
+		ConsoleSize: opts.ConsoleSize,
+	})
 	if err != nil {
-		return "", eris.Wrapf(err, "Failed to start exec instance")
+		return 0, eris.Wrapf(err, "Failed to start exec instance")
 	}
 	defer resp.Close()
 
-	// Read and demultiplex the output
-	var outputBuf bytes.Buffer
-	header := make([]byte, 8)
-
-	for {
-		_, err := io.ReadFull(resp.Reader, header)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return "", eris.Wrapf(err, "Failed to read exec output")
-		}
+	// Send stdin, closing it when done so the command sees the end of its input
+	if opts.Stdin != nil {
+		go func() {
+			_, _ = io.Copy(resp.Conn, opts.Stdin)
+			_ = resp.CloseWrite()
+		}()
+	}
 
-		stream := header[0]
-		size := binary.BigEndian.Uint32(header[4:8])
+	stdout, stderr := opts.Stdout, opts.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
 
-		if stream == 1 { // stdout
-			if _, err := io.CopyN(&outputBuf, resp.Reader, int64(size)); err != nil {
-				return "", eris.Wrapf(err, "Failed to read stdout")
-			}
-		} else {
-			// Skip stderr or other streams
-			if _, err := io.CopyN(io.Discard, resp.Reader, int64(size)); err != nil {
-				return "", eris.Wrapf(err, "Failed to read stderr")
-			}
-		}
+	// Read and demultiplex the output, a terminal has a single raw stream
+	if opts.TTY {
+		_, err = io.Copy(stdout, resp.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, resp.Reader)
+	}
+	if err != nil {
+		return 0, eris.Wrapf(err, "Failed to read exec output")
 	}
 
-	// Return the output as a string
-	return outputBuf.String(), nil
+	inspect, err := c.client.ContainerExecInspect(ctx, execIDResp.ID)
+	if err != nil {
+		return 0, eris.Wrapf(err, "Failed to inspect exec instance")
+	}
+	return inspect.ExitCode, nil
 }
 
-func (c *Client) Save(ctx context.Context, imageName string) (io.ReadCloser, error) {
-	reader, err := c.client.ImageSave(ctx, []string{imageName})
+// Save returns a tar archive of the given images, with the layers shared by the images saved once.
+func (c *Client) Save(ctx context.Context, imageNames ...string) (io.ReadCloser, error) {
+	reader, err := c.client.ImageSave(ctx, imageNames)
 	if err != nil {
-		return nil, eris.Wrapf(err, "Failed to save image")
+		return nil, eris.Wrapf(err, "Failed to save images")
 	}
 
 	return reader, nil
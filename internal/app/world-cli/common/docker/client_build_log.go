@@ -0,0 +1,359 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/opencontainers/go-digest"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+const (
+	// buildLogDirName is the directory of the build logs, inside the World CLI config directory
+	buildLogDirName = "logs"
+	// buildLogsKept is the number of build logs kept for every image, older logs are removed
+	buildLogsKept = 10
+	// buildLogTailLines is the number of output lines of the failing step shown in the failure summary
+	buildLogTailLines = 20
+	// maxStepOutputLines is the number of output lines kept in memory for every step
+	maxStepOutputLines = 500
+	// buildSourceDir is the directory the Dockerfiles copy the sources to, see cardinal.Dockerfile
+	buildSourceDir = "/go/src/app/"
+	// buildLogTimeFormat is the format of the build time ending the build log names, which sorts chronologically
+	buildLogTimeFormat = "20060102-150405.000"
+)
+
+// goCompileErrorPattern matches the errors of the Go compiler and go vet, e.g. "system/move.go:12:3: undefined: x".
+//
+//nolint:gochecknoglobals // compiled once
+var goCompileErrorPattern = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+// CompileError is an error of the Go compiler, located in the sources of the build.
+type CompileError struct {
+	// File is the path of the file on this machine
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e CompileError) String() string {
+	location := fmt.Sprintf("%s:%d", e.File, e.Line)
+	if e.Column > 0 {
+		location += ":" + strconv.Itoa(e.Column)
+	}
+	return location + ": " + e.Message
+}
+
+// BuildFailure describes why the build of an image failed.
+type BuildFailure struct {
+	Image string
+	// Step is the Dockerfile step that failed, empty when the build failed before running any step
+	Step string
+	// Output is the last lines of the output of the failing step
+	Output        []string
+	CompileErrors []CompileError
+	// LogPath is the path of the full build log, empty when it couldn't be written
+	LogPath string
+}
+
+// buildLog saves the full output of an image build to a file, and keeps the output of every step in memory
+// to describe a failure.
+type buildLog struct {
+	mu        sync.Mutex
+	file      *os.File
+	path      string
+	image     string
+	sourceDir string
+
+	// steps maps the digest of a BuildKit vertex to its step
+	steps     map[digest.Digest]*buildStep
+	stepCount int
+	// current is the running step of a build without BuildKit
+	current *buildStep
+
+	failedStep *buildStep
+	err        string
+}
+
+type buildStep struct {
+	number  int
+	name    string
+	started bool
+	output  []string
+	// partial is the end of the output not terminated by a newline yet
+	partial string
+}
+
+// newBuildLog creates the log of a build of the given image from the given source directory. The build goes
+// on without a log file when it can't be created.
+func newBuildLog(imageName, sourceDir string) *buildLog {
+	l := &buildLog{
+		image:     imageName,
+		sourceDir: sourceDir,
+		steps:     make(map[digest.Digest]*buildStep),
+	}
+
+	dir, err := BuildLogDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0o755)
+	}
+	if err == nil {
+		pruneBuildLogs(dir, imageName, buildLogsKept-1)
+		l.path = filepath.Join(dir, fmt.Sprintf("%s-%s.log", buildLogPrefix(imageName),
+			time.Now().Format(buildLogTimeFormat)))
+		l.file, err = os.Create(l.path)
+	}
+	if err != nil {
+		l.path = ""
+		if logger.VerboseMode {
+			logger.Printf("Not saving the build log of %s: %v\r\n", imageName, err)
+		}
+		return l
+	}
+
+	l.writef("Build of %s started at %s\n", imageName, time.Now().Format(time.RFC3339))
+	return l
+}
+
+// addStatus records a BuildKit status update.
+func (l *buildLog) addStatus(resp *controlapi.StatusResponse) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, vertex := range resp.Vertexes {
+		step := l.vertexStep(vertex.Digest)
+		step.name = vertex.Name
+		if vertex.Started != nil && !step.started {
+			step.started = true
+			l.writef("#%d %s\n", step.number, step.name)
+		}
+		if vertex.Cached && vertex.Completed != nil {
+			l.writef("#%d CACHED\n", step.number)
+		}
+		if vertex.Error != "" && l.failedStep == nil {
+			l.failedStep = step
+			l.writef("#%d ERROR: %s\n", step.number, vertex.Error)
+		}
+	}
+	for _, vertexLog := range resp.Logs {
+		step := l.vertexStep(vertexLog.Vertex)
+		for _, line := range step.addOutput(string(vertexLog.Msg)) {
+			l.writef("#%d %s\n", step.number, line)
+		}
+	}
+}
+
+func (l *buildLog) vertexStep(dgst digest.Digest) *buildStep {
+	step, ok := l.steps[dgst]
+	if !ok {
+		l.stepCount++
+		step = &buildStep{number: l.stepCount}
+		l.steps[dgst] = step
+	}
+	return step
+}
+
+// addStream records the output of a build without BuildKit, where every step starts with a "Step n/m" line.
+func (l *buildLog) addStream(stream string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writef("%s", stream)
+	for _, line := range strings.Split(strings.TrimRight(stream, "\n"), "\n") {
+		if strings.HasPrefix(line, "Step ") {
+			l.stepCount++
+			l.current = &buildStep{number: l.stepCount, name: line, started: true}
+			continue
+		}
+		if l.current != nil {
+			l.current.addOutput(line + "\n")
+		}
+	}
+}
+
+// addError records the error that stopped the build.
+func (l *buildLog) addError(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = err.Error()
+	if l.failedStep == nil {
+		l.failedStep = l.current
+	}
+	l.writef("ERROR: %s\n", l.err)
+}
+
+// failed returns true when the build stopped with an error.
+func (l *buildLog) failed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err != ""
+}
+
+// addOutput adds output to the step and returns the complete lines it contained.
+func (s *buildStep) addOutput(output string) []string {
+	lines := strings.Split(s.partial+output, "\n")
+	s.partial = lines[len(lines)-1]
+	lines = lines[:len(lines)-1]
+	s.output = append(s.output, lines...)
+	if len(s.output) > maxStepOutputLines {
+		s.output = slices.Clone(s.output[len(s.output)-maxStepOutputLines:])
+	}
+	return lines
+}
+
+// lines returns the output of the step, including the end of the output not terminated by a newline.
+func (s *buildStep) lines() []string {
+	if s.partial == "" {
+		return s.output
+	}
+	return append(slices.Clone(s.output), s.partial)
+}
+
+func (l *buildLog) writef(format string, args ...any) {
+	if l.file == nil {
+		return
+	}
+	if _, err := fmt.Fprintf(l.file, format, args...); err != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// close ends the log with the outcome of the build.
+func (l *buildLog) close(stats *buildStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == "" {
+		l.writef("Build of %s succeeded, %s\n", l.image, stats)
+	}
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// failure describes the failure of the build.
+func (l *buildLog) failure() BuildFailure {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	failure := BuildFailure{Image: l.image, LogPath: l.path}
+	if l.failedStep == nil {
+		return failure
+	}
+
+	failure.Step = l.failedStep.name
+	output := l.failedStep.lines()
+	failure.Output = output[max(0, len(output)-buildLogTailLines):]
+	failure.CompileErrors = parseCompileErrors(output, l.sourceDir)
+	return failure
+}
+
+// printBuildFailure prints the failing step of a build, the end of its output and the compile errors it
+// contains, so most failures can be fixed without reading the full log.
+func printBuildFailure(failure BuildFailure) {
+	printer.NewLine(1)
+	printer.Errorf("Failed to build %s\n", failure.Image)
+	if failure.Step != "" {
+		printer.Infof("Failing step: %s\n", failure.Step)
+	}
+	if len(failure.Output) > 0 {
+		printer.Infof("Last %d lines of output:\n", len(failure.Output))
+		for _, line := range failure.Output {
+			printer.Infof("  %s\n", line)
+		}
+	}
+	if len(failure.CompileErrors) > 0 {
+		printer.Infoln("Compile errors:")
+		for _, compileErr := range failure.CompileErrors {
+			printer.Infof("  %s\n", compileErr)
+		}
+	}
+	if failure.LogPath != "" {
+		printer.Infof("Full build log: %s (replay it with world cardinal build --show-log)\n", failure.LogPath)
+	}
+}
+
+// parseCompileErrors returns the Go compiler errors in the output of a build step, with their files resolved
+// in the source directory of the build.
+func parseCompileErrors(output []string, sourceDir string) []CompileError {
+	errs := make([]CompileError, 0)
+	for _, line := range output {
+		match := goCompileErrorPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		file := strings.TrimPrefix(match[1], buildSourceDir)
+		if !filepath.IsAbs(file) && sourceDir != "" {
+			file = filepath.Join(sourceDir, file)
+		}
+		errs = append(errs, CompileError{File: file, Line: lineNumber, Column: column, Message: match[4]})
+	}
+	return errs
+}
+
+// BuildLogDir returns the directory of the build logs.
+func BuildLogDir() (string, error) {
+	dir, err := config.GetCLIConfigDir()
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to get the World CLI config directory")
+	}
+	return filepath.Join(dir, buildLogDirName), nil
+}
+
+// LatestBuildLog returns the path of the most recent build log of the given image.
+func LatestBuildLog(imageName string) (string, error) {
+	dir, err := BuildLogDir()
+	if err != nil {
+		return "", err
+	}
+	logs := buildLogs(dir, imageName)
+	if len(logs) == 0 {
+		return "", eris.Errorf("no build log of %s found in %s", imageName, dir)
+	}
+	return logs[len(logs)-1], nil
+}
+
+// buildLogs returns the paths of the build logs of the given image, from oldest to newest.
+func buildLogs(dir, imageName string) []string {
+	prefix := buildLogPrefix(imageName) + "-"
+	logs, err := filepath.Glob(filepath.Join(dir, prefix+"*.log"))
+	if err != nil {
+		return nil
+	}
+	// Skip the logs of other images starting with the same name, e.g. game-v2 for game
+	logs = slices.DeleteFunc(logs, func(path string) bool {
+		buildTime := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix), ".log")
+		_, err := time.Parse(buildLogTimeFormat, buildTime)
+		return err != nil
+	})
+	slices.Sort(logs)
+	return logs
+}
+
+// pruneBuildLogs removes the build logs of the given image except the keep most recent ones.
+func pruneBuildLogs(dir, imageName string, keep int) {
+	logs := buildLogs(dir, imageName)
+	for _, path := range logs[:max(0, len(logs)-keep)] {
+		if err := os.Remove(path); err != nil && logger.VerboseMode {
+			logger.Printf("Failed to remove the old build log %s: %v\r\n", path, err)
+		}
+	}
+}
+
+// buildLogPrefix returns the prefix of the build log names of an image, the image name with the characters
+// not allowed in file names replaced.
+func buildLogPrefix(imageName string) string {
+	return "build-" + strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(imageName)
+}
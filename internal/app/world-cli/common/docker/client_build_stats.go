@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+)
+
+const (
+	// buildDurationPrecision is the precision of the build durations shown to the user
+	buildDurationPrecision = 100 * time.Millisecond
+	percentScale           = 100
+)
+
+// buildStats counts the steps of an image build and how many of them were served from the cache.
+type buildStats struct {
+	mu    sync.Mutex
+	start time.Time
+	end   time.Time
+
+	// vertexes maps the digest of a completed BuildKit vertex to whether it was cached
+	vertexes map[string]bool
+
+	// legacySteps and legacyCached count the steps of a build without BuildKit
+	legacySteps  int
+	legacyCached int
+}
+
+func newBuildStats() *buildStats {
+	return &buildStats{
+		start:    time.Now(),
+		vertexes: make(map[string]bool),
+	}
+}
+
+// addVertexes records the completed steps of a BuildKit status update. Internal steps, like loading
+// the build context, are not counted.
+func (s *buildStats) addVertexes(resp *controlapi.StatusResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, vertex := range resp.Vertexes {
+		if vertex.Completed == nil || strings.HasPrefix(vertex.Name, "[internal]") {
+			continue
+		}
+		s.vertexes[vertex.Digest.String()] = vertex.Cached
+	}
+}
+
+// addStream records a line of the output of a build without BuildKit.
+func (s *buildStats) addStream(stream string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(stream, "Step"):
+		s.legacySteps++
+	case strings.Contains(stream, "Using cache"):
+		s.legacyCached++
+	}
+}
+
+func (s *buildStats) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end = time.Now()
+}
+
+// counts returns the number of cached steps and the total number of steps.
+func (s *buildStats) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.vertexes) == 0 {
+		return s.legacyCached, s.legacySteps
+	}
+	cached := 0
+	for _, isCached := range s.vertexes {
+		if isCached {
+			cached++
+		}
+	}
+	return cached, len(s.vertexes)
+}
+
+func (s *buildStats) String() string {
+	cached, total := s.counts()
+	s.mu.Lock()
+	duration := s.end.Sub(s.start).Round(buildDurationPrecision)
+	s.mu.Unlock()
+	if total == 0 {
+		return fmt.Sprintf("built in %s", duration)
+	}
+	percent := cached * percentScale / total
+	return fmt.Sprintf("built in %s, %d/%d steps cached (%d%%)", duration, cached, total, percent)
+}
@@ -8,11 +8,14 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"slices"
 	"sync"
 	"time"
 
 	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-units"
 	"github.com/rotisserie/eris"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
 	"pkg.world.dev/world-cli/internal/pkg/printer"
@@ -23,6 +26,12 @@ import (
 
 type processType int
 
+const (
+	// dependencyTimeout is how long a container waits for the containers it depends on
+	dependencyTimeout      = 2 * time.Minute
+	dependencyPollInterval = 1 * time.Second
+)
+
 func (c *Client) processMultipleContainers(ctx context.Context, processType processType,
 	services ...service.Service) error {
 	// Collect the names of the services
@@ -112,28 +121,136 @@ func (c *Client) processMultipleContainers(ctx context.Context, processType proc
 	return nil
 }
 
-func (c *Client) startContainer(ctx context.Context, service service.Service) error {
+func (c *Client) startContainer(ctx context.Context, dockerService service.Service) error {
+	// Label the container so the shard it belongs to can be found later
+	dockerService.Labels = c.containerLabels(dockerService)
+	// Limit the resources of the container and set its restart policy from world.toml
+	service.ApplyResources(c.cfg, &dockerService)
+
 	// Check if the container exists
-	exist, err := c.containerExists(ctx, service.Name)
-	if err != nil {
-		return eris.Wrapf(err, "Failed to check if container %s exists", service.Name)
-	} else if !exist {
+	info, err := c.client.ContainerInspect(ctx, dockerService.Name)
+	exist := err == nil
+	if err != nil && !cerrdefs.IsNotFound(err) {
+		return eris.Wrapf(err, "Failed to check if container %s exists", dockerService.Name)
+	}
+
+	// Recreate the container when world.toml changed what is only set when it is created
+	if exist && containerOutdated(info, dockerService) {
+		if err := c.removeContainer(ctx, dockerService.Name); err != nil {
+			return err
+		}
+		exist = false
+	}
+
+	if !exist {
 		// Create the container if it does not exist
-		_, err := c.client.ContainerCreate(ctx, &service.Config, &service.HostConfig,
-			&service.NetworkingConfig, &service.Platform, service.Name)
+		_, err := c.client.ContainerCreate(ctx, &dockerService.Config, &dockerService.HostConfig,
+			&dockerService.NetworkingConfig, &dockerService.Platform, dockerService.Name)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Write the generated files, which may have changed since the container was created
+	if err := c.copyServiceFiles(ctx, dockerService); err != nil {
+		return err
+	}
+
+	// Wait for the services this one depends on
+	for _, dependency := range dockerService.DependsOn {
+		if err := c.waitForContainer(ctx, dependency); err != nil {
+			return err
+		}
+	}
+
 	// Start the container
-	if err := c.client.ContainerStart(ctx, service.Name, container.StartOptions{}); err != nil {
+	if err := c.client.ContainerStart(ctx, dockerService.Name, container.StartOptions{}); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// checkDependencies returns an error when a service depends on one that is not started with it, which it would
+// otherwise wait for until dependencyTimeout.
+func checkDependencies(dockerServices []service.Service) error {
+	started := make(map[string]bool, len(dockerServices))
+	for _, dockerService := range dockerServices {
+		started[dockerService.Name] = true
+	}
+	for _, dockerService := range dockerServices {
+		for _, dependency := range dockerService.DependsOn {
+			if !started[dependency] {
+				return eris.Errorf("%s depends on %s, which is not started by this command, "+
+					"check the flags and [services] section of world.toml", dockerService.Name, dependency)
+			}
+		}
+	}
+	return nil
+}
+
+// waitForContainer waits until the container is running, and healthy if it has a healthcheck.
+func (c *Client) waitForContainer(ctx context.Context, containerName string) error {
+	ctx, cancel := context.WithTimeout(ctx, dependencyTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := c.client.ContainerInspect(ctx, containerName)
+		if err != nil && !cerrdefs.IsNotFound(err) {
+			return eris.Wrapf(err, "Failed to inspect container %s", containerName)
+		}
+		if err == nil && info.State != nil && info.State.Running &&
+			(info.State.Health == nil || info.State.Health.Status == container.Healthy) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return eris.Errorf("Timed out waiting for dependency %s to be ready", containerName)
+		case <-ticker.C:
+		}
+	}
+}
+
+// containerOutdated returns true when the resources, the restart policy, the entrypoint or the mounts of the
+// container differ from those of the service, which a container only gets when it is created. Nakama, for
+// one, gets its flags through its entrypoint and its config file and modules through mounts.
+func containerOutdated(info container.InspectResponse, dockerService service.Service) bool {
+	if info.ContainerJSONBase == nil || info.HostConfig == nil {
+		return false
+	}
+	current, wanted := info.HostConfig, dockerService.HostConfig
+	if current.NanoCPUs != wanted.NanoCPUs ||
+		current.Memory != wanted.Memory ||
+		restartPolicyMode(current.RestartPolicy) != restartPolicyMode(wanted.RestartPolicy) ||
+		current.RestartPolicy.MaximumRetryCount != wanted.RestartPolicy.MaximumRetryCount ||
+		!slices.EqualFunc(current.Ulimits, wanted.Ulimits, func(a, b *units.Ulimit) bool { return *a == *b }) {
+		return true
+	}
+	if !slices.EqualFunc(current.Mounts, wanted.Mounts, sameMount) {
+		return true
+	}
+	// Without an entrypoint of its own, the container has the one of its image
+	return len(dockerService.Entrypoint) > 0 && info.Config != nil &&
+		!slices.Equal(info.Config.Entrypoint, dockerService.Entrypoint)
+}
+
+// sameMount returns true when both mounts mount the same source at the same target the same way.
+func sameMount(a, b mount.Mount) bool {
+	return a.Type == b.Type && a.Source == b.Source && a.Target == b.Target && a.ReadOnly == b.ReadOnly
+}
+
+// restartPolicyMode returns the mode of the restart policy, with the default of the daemon for an empty one.
+func restartPolicyMode(policy container.RestartPolicy) container.RestartPolicyMode {
+	if policy.Name == "" {
+		return container.RestartPolicyDisabled
+	}
+	return policy.Name
+}
+
 func (c *Client) containerExists(ctx context.Context, containerName string) (bool, error) {
 	_, err := c.client.ContainerInspect(ctx, containerName)
 	if err != nil {
@@ -189,6 +306,11 @@ func (c *Client) removeContainer(ctx context.Context, containerName string) erro
 func (c *Client) logMultipleContainers(ctx context.Context, services ...service.Service) {
 	var wg sync.WaitGroup
 
+	// Report the restarts and crash loops along with the logs, until the logs end
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
+	go c.watchRestarts(watchCtx, services...)
+
 	// Start logging output for each container
 	for i, dockerService := range services {
 		wg.Add(1)
@@ -0,0 +1,179 @@
+package docker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+)
+
+// loadedImagePrefixes are the prefixes of the lines the Docker daemon reports for every loaded image.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var loadedImagePrefixes = []string{"Loaded image: ", "Loaded image ID: "}
+
+// Export saves the images of the given services to a tar archive, compressed with gzip when the path ends
+// with .gz or .tgz. Built images are saved with every tag of the build, and the other images are pulled
+// first when they are missing. It returns the saved references.
+func (c *Client) Export(ctx context.Context, path string, serviceBuilders ...service.Builder) ([]string, error) {
+	dockerServices := make([]service.Service, 0, len(serviceBuilders))
+	for _, sb := range serviceBuilders {
+		dockerServices = append(dockerServices, sb(c.cfg))
+	}
+
+	// The build dependencies are not pulled, the archive only holds the images the stack runs
+	runServices := make([]service.Service, 0, len(dockerServices))
+	for _, dockerService := range dockerServices {
+		dockerService.Dependencies = nil
+		runServices = append(runServices, dockerService)
+	}
+	if err := c.pullImages(ctx, runServices...); err != nil {
+		return nil, eris.Wrap(err, "Failed to pull images")
+	}
+
+	refs := c.exportReferences(runServices)
+	for _, ref := range refs {
+		if _, err := c.client.ImageInspect(ctx, ref); err != nil {
+			return nil, eris.Wrapf(err, "Image %s not found, build it first", ref)
+		}
+	}
+
+	reader, err := c.Save(ctx, refs...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if err := writeImageArchive(reader, path); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// MissingBuiltImages returns the images of the given services that are built by the CLI but are not on the
+// machine, e.g. because they were neither built nor loaded from an archive.
+func (c *Client) MissingBuiltImages(ctx context.Context, serviceBuilders ...service.Builder) ([]string, error) {
+	var missing []string
+	for _, sb := range serviceBuilders {
+		dockerService := sb(c.cfg)
+		if !dockerService.NeedsBuild() || slices.Contains(missing, dockerService.Image) {
+			continue
+		}
+		if _, err := c.client.ImageInspect(ctx, dockerService.Image); err != nil {
+			if !cerrdefs.IsNotFound(err) {
+				return nil, eris.Wrapf(err, "Failed to inspect image %s", dockerService.Image)
+			}
+			missing = append(missing, dockerService.Image)
+		}
+	}
+	return missing, nil
+}
+
+// exportReferences returns the sorted references of the images of the given services.
+func (c *Client) exportReferences(dockerServices []service.Service) []string {
+	refs := make([]string, 0, len(dockerServices))
+	for _, dockerService := range dockerServices {
+		if dockerService.NeedsBuild() {
+			tags, _ := c.imageTags(dockerService)
+			refs = append(refs, tags...)
+			continue
+		}
+		refs = append(refs, dockerService.Image)
+	}
+	slices.Sort(refs)
+	return slices.Compact(refs)
+}
+
+// writeImageArchive writes an image archive to the given path. The archive is written to a temporary file
+// first, so an interrupted export never leaves a truncated archive behind.
+func writeImageArchive(archive io.Reader, path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return eris.Wrapf(err, "Failed to create %s", path)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var writer io.Writer = file
+	var gzipWriter *gzip.Writer
+	if isGzipArchive(path) {
+		gzipWriter = gzip.NewWriter(file)
+		writer = gzipWriter
+	}
+	if _, err := io.Copy(writer, archive); err != nil {
+		return eris.Wrapf(err, "Failed to write %s", path)
+	}
+	if gzipWriter != nil {
+		if err := gzipWriter.Close(); err != nil {
+			return eris.Wrapf(err, "Failed to compress %s", path)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return eris.Wrapf(err, "Failed to write %s", path)
+	}
+
+	if err := os.Chmod(file.Name(), 0o644); err != nil { //nolint:gosec // copied to other machines
+		return eris.Wrapf(err, "Failed to write %s", path)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return eris.Wrapf(err, "Failed to write %s", path)
+	}
+	return nil
+}
+
+func isGzipArchive(path string) bool {
+	return strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// Load imports the images of an archive written by Export or docker save, compressed or not, and returns
+// the loaded references.
+func (c *Client) Load(ctx context.Context, path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to open %s", path)
+	}
+	defer file.Close()
+
+	// The Docker daemon decompresses the archive
+	resp, err := c.client.ImageLoad(ctx, bufio.NewReader(file), client.ImageLoadWithQuiet(true))
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to load %s", path)
+	}
+	defer resp.Body.Close()
+
+	return readLoadedImages(resp.Body)
+}
+
+// readLoadedImages returns the references reported by the Docker daemon while loading an archive.
+func readLoadedImages(body io.Reader) ([]string, error) {
+	loaded := make([]string, 0)
+	decoder := json.NewDecoder(body)
+	for decoder.More() {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			return nil, eris.Wrap(err, "Failed to read the load output")
+		}
+		if msg.Error != nil {
+			return nil, eris.New(msg.Error.Message)
+		}
+		for _, line := range strings.Split(msg.Stream, "\n") {
+			for _, prefix := range loadedImagePrefixes {
+				if ref, ok := strings.CutPrefix(line, prefix); ok {
+					loaded = append(loaded, strings.TrimSpace(ref))
+				}
+			}
+		}
+	}
+	return loaded, nil
+}
@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+)
+
+// copyServiceFiles writes the files of the service into its container.
+func (c *Client) copyServiceFiles(ctx context.Context, dockerService service.Service) error {
+	if len(dockerService.Files) == 0 {
+		return nil
+	}
+	archive, err := filesArchive(dockerService.Files, time.Now())
+	if err != nil {
+		return err
+	}
+	err = c.client.CopyToContainer(ctx, dockerService.Name, "/", archive, container.CopyToContainerOptions{})
+	if err != nil {
+		return eris.Wrapf(err, "Failed to copy files to container %s", dockerService.Name)
+	}
+	return nil
+}
+
+// filesArchive returns a tar archive of the files keyed by their absolute path. The daemon creates their
+// missing parent directories.
+func filesArchive(files map[string]string, modTime time.Time) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     strings.TrimPrefix(name, "/"),
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			ModTime:  modTime,
+		}); err != nil {
+			return nil, eris.Wrap(err, "Failed to write archive")
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			return nil, eris.Wrap(err, "Failed to write archive")
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, eris.Wrap(err, "Failed to write archive")
+	}
+	return &buf, nil
+}
@@ -29,6 +29,10 @@ import (
 	"pkg.world.dev/world-cli/internal/pkg/tea/style"
 )
 
+// generatedDockerfileName is the name of the Dockerfile added to the build context, chosen so it
+// doesn't clash with a Dockerfile of the project.
+const generatedDockerfileName = ".world-cli.Dockerfile"
+
 func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Service) error { //nolint:gocognit, funlen
 	// Filter all services that need to be built
 	var (
@@ -36,7 +40,7 @@ func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Serv
 		imagesName     []string
 	)
 	for _, dockerService := range dockerServices {
-		if dockerService.Dockerfile != "" {
+		if dockerService.NeedsBuild() {
 			serviceToBuild = append(serviceToBuild, dockerService)
 			imagesName = append(imagesName, dockerService.Image)
 		}
@@ -45,6 +49,13 @@ func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Serv
 		return nil
 	}
 
+	// Make sure every build target exists before starting any build
+	for _, dockerService := range serviceToBuild {
+		if err := dockerService.ValidateBuildTarget(); err != nil {
+			return err
+		}
+	}
+
 	// Log verbose information about the build process
 	if logger.VerboseMode {
 		logger.Printf("Starting Docker build process for %d services\r\n", len(serviceToBuild))
@@ -62,6 +73,18 @@ func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Serv
 
 	p := program.NewTeaProgram(multispinner.CreateSpinner(imagesName, cancel))
 
+	// Collect the cache statistics and the log of every build
+	stats := make(map[string]*buildStats, len(serviceToBuild))
+	logs := make(map[string]*buildLog, len(serviceToBuild))
+	for _, dockerService := range serviceToBuild {
+		stats[dockerService.Image] = newBuildStats()
+		sourceDir := dockerService.BuildContext
+		if sourceDir == "" {
+			sourceDir = c.cfg.RootDir
+		}
+		logs[dockerService.Image] = newBuildLog(dockerService.Image, sourceDir)
+	}
+
 	for _, ds := range serviceToBuild {
 		// Capture dockerService in the loop
 		dockerService := ds
@@ -113,6 +136,7 @@ func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Serv
 			}
 			buildResponse, err := c.buildImage(ctx, dockerService)
 			if err != nil {
+				logs[dockerService.Image].addError(err)
 				if logger.VerboseMode {
 					logger.Printf("Error building image for %s: %v\r\n", dockerService.Name, err)
 				}
@@ -133,7 +157,8 @@ func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Serv
 			if logger.VerboseMode {
 				logger.Printf("Processing build logs for service: %s\r\n", dockerService.Name)
 			}
-			err = c.readBuildLog(ctx, buildResponse.Body, p, dockerService.Image)
+			err = c.readBuildLog(ctx, buildResponse.Body, p, dockerService.Image,
+				stats[dockerService.Image], logs[dockerService.Image])
 			if err != nil {
 				if logger.VerboseMode {
 					logger.Printf("Error reading build logs for %s: %v\r\n", dockerService.Name, err)
@@ -155,8 +180,18 @@ func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Serv
 		errs = append(errs, err)
 	}
 
-	// If there were any errors, return them as a combined error
+	for _, imageName := range imagesName {
+		logs[imageName].close(stats[imageName])
+	}
+
+	// If there were any errors, describe the failed builds and return them as a combined error
 	if len(errs) > 0 {
+		for _, imageName := range imagesName {
+			// A canceled build needs no explanation
+			if logs[imageName].failed() && ctx.Err() == nil {
+				printBuildFailure(logs[imageName].failure())
+			}
+		}
 		if logger.VerboseMode {
 			logger.Printf("Build completed with %d errors\r\n", len(errs))
 			for i, err := range errs {
@@ -169,11 +204,18 @@ func (c *Client) buildImages(ctx context.Context, dockerServices ...service.Serv
 	if logger.VerboseMode {
 		logger.Printf("All Docker builds completed successfully\r\n")
 	}
+	for _, imageName := range imagesName {
+		printer.Infof("%s %s\n", imageName, stats[imageName])
+	}
 
 	return nil
 }
 
 func (c *Client) buildImage(ctx context.Context, dockerService service.Service) (*build.ImageBuildResponse, error) {
+	if dockerService.BuildContext != "" {
+		return c.buildImageFromContext(ctx, dockerService)
+	}
+
 	if logger.VerboseMode {
 		logger.Printf("Creating build context for service: %s\r\n", dockerService.Name)
 	}
@@ -187,7 +229,7 @@ func (c *Client) buildImage(ctx context.Context, dockerService service.Service)
 		logger.Printf("Adding Dockerfile to build context (size: %d bytes)\r\n", len(dockerService.Dockerfile))
 	}
 	header := &tar.Header{
-		Name: "Dockerfile",
+		Name: generatedDockerfileName,
 		Size: int64(len(dockerService.Dockerfile)),
 	}
 	if err := tw.WriteHeader(header); err != nil {
@@ -209,42 +251,66 @@ func (c *Client) buildImage(ctx context.Context, dockerService service.Service)
 	tarReader := bytes.NewReader(buf.Bytes())
 
 	sourcePath := "."
-	githubToken := os.Getenv("ARGUS_WEV2_GITHUB_TOKEN")
-	if githubToken == "" {
-		return nil, eris.New("ARGUS_WEV2_GITHUB_TOKEN is not set")
-	}
+	tags, labels := c.imageTags(dockerService)
 
 	if logger.VerboseMode {
 		logger.Printf("Configuring build options for service: %s\r\n", dockerService.Name)
-		logger.Printf("  - Image tag: %s\r\n", dockerService.Image)
+		logger.Printf("  - Image tags: %v\r\n", tags)
 		logger.Printf("  - Build target: %s\r\n", dockerService.BuildTarget)
 		logger.Printf("  - Source path: %s\r\n", sourcePath)
+		logger.Printf("  - Platforms: %v\r\n", c.cfg.CardinalBuild.Platforms)
 		logger.Printf("  - BuildKit support: %t\r\n", service.BuildkitSupport)
 		logger.Printf("  - DOCKER_BUILDKIT env: %s\r\n", os.Getenv("DOCKER_BUILDKIT"))
 	}
 
 	buildOptions := build.ImageBuildOptions{
-		Dockerfile: "Dockerfile",
-		Tags:       []string{dockerService.Image},
+		Dockerfile: generatedDockerfileName,
+		Tags:       tags,
+		Labels:     labels,
 		Target:     dockerService.BuildTarget,
+		NoCache:    c.cfg.CardinalBuild.NoCache,
 		BuildArgs: map[string]*string{
-			"SOURCE_PATH":  &sourcePath,
-			"GITHUB_TOKEN": &githubToken,
+			"SOURCE_PATH": &sourcePath,
 		},
 	}
+	platforms := c.cfg.CardinalBuild.Platforms
+	if len(platforms) == 1 {
+		buildOptions.Platform = platforms[0]
+	} else if len(platforms) == 0 && dockerService.Platform.OS != "" {
+		// The image runs on the platform of the service, e.g. Nakama on linux/amd64
+		buildOptions.Platform = dockerService.Platform.OS + "/" + dockerService.Platform.Architecture
+	}
+	for key, val := range dockerService.BaseImages {
+		buildOptions.BuildArgs[key] = &val
+	}
+	for key, val := range dockerService.BuildArgs {
+		buildOptions.BuildArgs[key] = &val
+	}
 
-	// if service.BuildkitSupport {
-	// 	buildOptions.Version = build.BuilderBuildKit
-	// }
+	// Give the build access to private modules
+	closeSession, err := c.addBuildSecrets(ctx, dockerService, &buildOptions)
+	if err != nil {
+		return nil, err
+	}
 
 	// Build the image
 	if logger.VerboseMode {
 		logger.Printf("Starting Docker build for service: %s\r\n", dockerService.Name)
 	}
+	if len(c.multiPlatforms(dockerService)) > 0 {
+		body, err := c.buildPlatformImages(ctx, buf.Bytes(), buildOptions)
+		if err != nil {
+			closeSession()
+			return nil, err
+		}
+		return &build.ImageBuildResponse{Body: sessionBody{ReadCloser: body, closeSession: closeSession}}, nil
+	}
 	buildResponse, err := c.client.ImageBuild(ctx, tarReader, buildOptions)
 	if err != nil {
+		closeSession()
 		return nil, eris.Wrap(err, "Failed to build image")
 	}
+	buildResponse.Body = sessionBody{ReadCloser: buildResponse.Body, closeSession: closeSession}
 
 	if logger.VerboseMode {
 		logger.Printf("Docker build initiated successfully for service: %s\r\n", dockerService.Name)
@@ -253,6 +319,52 @@ func (c *Client) buildImage(ctx context.Context, dockerService service.Service)
 	return &buildResponse, nil
 }
 
+// buildImageFromContext builds the image of a service from its own build context directory,
+// using the Dockerfile found inside the context.
+func (c *Client) buildImageFromContext(ctx context.Context,
+	dockerService service.Service) (*build.ImageBuildResponse, error) {
+	if logger.VerboseMode {
+		logger.Printf("Creating build context for service %s from directory: %s\r\n",
+			dockerService.Name, dockerService.BuildContext)
+	}
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	if err := c.addFileToTarWriter(dockerService.BuildContext, tw); err != nil {
+		return nil, eris.Wrap(err, "Failed to add build context to tar writer")
+	}
+	if err := tw.Close(); err != nil {
+		return nil, eris.Wrap(err, "Failed to close tar writer")
+	}
+
+	tags, labels := c.imageTags(dockerService)
+	buildOptions := build.ImageBuildOptions{
+		Dockerfile: dockerService.DockerfilePath,
+		Tags:       tags,
+		Labels:     labels,
+		Target:     dockerService.BuildTarget,
+		NoCache:    c.cfg.CardinalBuild.NoCache,
+		BuildArgs:  make(map[string]*string, len(dockerService.BuildArgs)),
+	}
+	for key, val := range dockerService.BuildArgs {
+		buildOptions.BuildArgs[key] = &val
+	}
+
+	closeSession, err := c.addBuildSecrets(ctx, dockerService, &buildOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	buildResponse, err := c.client.ImageBuild(ctx, bytes.NewReader(buf.Bytes()), buildOptions)
+	if err != nil {
+		closeSession()
+		return nil, eris.Wrap(err, "Failed to build image")
+	}
+	buildResponse.Body = sessionBody{ReadCloser: buildResponse.Body, closeSession: closeSession}
+
+	return &buildResponse, nil
+}
+
 // The tar file is used to build the Docker image.
 func (c *Client) addFileToTarWriter(baseDir string, tw *tar.Writer) error { //nolint:gocognit
 	var fileCount int
@@ -342,7 +454,7 @@ func (c *Client) addFileToTarWriter(baseDir string, tw *tar.Writer) error { //no
 //
 //nolint:gocognit
 func (c *Client) readBuildLog(ctx context.Context,
-	reader io.Reader, p *tea.Program, imageName string) error {
+	reader io.Reader, p *tea.Program, imageName string, stats *buildStats, log *buildLog) error {
 	if logger.VerboseMode {
 		logger.Printf("Starting to read build logs for image: %s\r\n", imageName)
 	}
@@ -356,20 +468,23 @@ func (c *Client) readBuildLog(ctx context.Context,
 			if logger.VerboseMode {
 				logger.Printf("Build log reading cancelled for image: %s\r\n", imageName)
 			}
+			log.addError(eris.New("build canceled"))
 			stop = true
 		default:
 			var step string
 			var err error
 			if service.BuildkitSupport {
 				// Parse the buildkit response
-				step, err = c.parseBuildkitResp(decoder, &stop)
+				step, err = c.parseBuildkitResp(decoder, &stop, stats, log)
 			} else {
 				// Parse the non-buildkit response
-				step, err = c.parseNonBuildkitResp(decoder, &stop)
+				step, err = c.parseNonBuildkitResp(decoder, &stop, stats, log)
 			}
 
 			// Send the step to the spinner
 			if err != nil {
+				err = wrapPlatformError(err)
+				log.addError(err)
 				if logger.VerboseMode {
 					logger.Printf("Build error for image %s: %v\r\n", imageName, err)
 				}
@@ -402,6 +517,8 @@ func (c *Client) readBuildLog(ctx context.Context,
 		logger.Printf("Build log reading completed for image: %s\r\n", imageName)
 	}
 
+	stats.done()
+
 	// Send the final message to the spinner
 	p.Send(multispinner.ProcessState{
 		Icon:  style.TickIcon.Render(),
@@ -414,7 +531,8 @@ func (c *Client) readBuildLog(ctx context.Context,
 	return nil
 }
 
-func (c *Client) parseBuildkitResp(decoder *json.Decoder, stop *bool) (string, error) {
+func (c *Client) parseBuildkitResp(decoder *json.Decoder, stop *bool,
+	stats *buildStats, log *buildLog) (string, error) {
 	var msg jsonmessage.JSONMessage
 	if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
 		*stop = true
@@ -426,16 +544,19 @@ func (c *Client) parseBuildkitResp(decoder *json.Decoder, stop *bool) (string, e
 	// Handle different message types
 	switch msg.ID {
 	case "moby.buildkit.trace":
-		return c.parseBuildkitTrace(msg)
+		return c.parseBuildkitTrace(msg, stats, log)
 	case "moby.buildkit.v1":
 		return c.parseBuildkitV1(msg)
 	default:
 		// Handle other message types including errors
+		if msg.Stream != "" {
+			log.addStream(msg.Stream)
+		}
 		return c.parseBuildkitGeneric(msg)
 	}
 }
 
-func (c *Client) parseBuildkitTrace(msg jsonmessage.JSONMessage) (string, error) {
+func (c *Client) parseBuildkitTrace(msg jsonmessage.JSONMessage, stats *buildStats, log *buildLog) (string, error) {
 	var resp controlapi.StatusResponse
 
 	if msg.Aux == nil {
@@ -450,9 +571,13 @@ func (c *Client) parseBuildkitTrace(msg jsonmessage.JSONMessage) (string, error)
 		return "", nil //nolint:nilerr // ignore unmarshal errors for unknown message types
 	}
 
+	// The output of the steps comes in updates without vertexes
+	log.addStatus(&resp)
+
 	if len(resp.Vertexes) == 0 {
 		return "", nil
 	}
+	stats.addVertexes(&resp)
 
 	// Return the name of the vertex (step) that is currently being executed
 	latestVertex := resp.Vertexes[len(resp.Vertexes)-1]
@@ -542,7 +667,8 @@ func (c *Client) parseBuildkitGeneric(msg jsonmessage.JSONMessage) (string, erro
 	return "", nil
 }
 
-func (c *Client) parseNonBuildkitResp(decoder *json.Decoder, stop *bool) (string, error) { //nolint:gocognit
+func (c *Client) parseNonBuildkitResp( //nolint:gocognit
+	decoder *json.Decoder, stop *bool, stats *buildStats, log *buildLog) (string, error) {
 	var event map[string]interface{}
 	if err := decoder.Decode(&event); errors.Is(err, io.EOF) {
 		*stop = true
@@ -567,7 +693,9 @@ func (c *Client) parseNonBuildkitResp(decoder *json.Decoder, stop *bool) (string
 
 	// Check for build steps and other important information
 	if val, ok := event["stream"]; ok && val != "" {
+		log.addStream(val.(string))
 		stream := strings.TrimSpace(val.(string))
+		stats.addStream(stream)
 
 		// Check if this is a build step
 		if strings.HasPrefix(stream, "Step") {
@@ -620,7 +748,7 @@ func (c *Client) filterImages(ctx context.Context, images map[string]string, ser
 
 		// check if the image needs to be built
 		// if the service has a Dockerfile, it needs to be built
-		if service.Dockerfile == "" { //nolint:nestif // need nesting
+		if !service.NeedsBuild() { //nolint:nestif // need nesting
 			// Image does not exist and does not need to be built
 			// Add the image to the list of images to pull
 			if service.OS != "" {
@@ -807,120 +935,3 @@ func (c *Client) pullImages(ctx context.Context, services ...service.Service) er
 
 	return nil
 }
-
-// Pulls the image if it does not exist.
-func (c *Client) pushImages(ctx context.Context, pushTo string, authString string, //nolint:gocognit
-	services ...service.Service) error {
-	// Create a new progress container with a wait group
-	var wg sync.WaitGroup
-	p := mpb.New(mpb.WithWaitGroup(&wg))
-
-	// Channel to collect errors from the goroutines
-	errChan := make(chan error, len(services))
-
-	// Add a wait group counter for each image
-	wg.Add(len(services))
-
-	for _, service := range services {
-		imageName := service.Image
-
-		// check if the image exists
-		_, err := c.client.ImageInspect(ctx, imageName)
-		if err != nil {
-			return eris.New(fmt.Sprintf("Error inspecting image %s for service %s: %v\r\n",
-				imageName, service.Name, err))
-		}
-
-		bar := p.AddBar(100,
-			mpb.PrependDecorators(
-				decor.Name(fmt.Sprintf("%s %s: ", style.ForegroundPrint("Pushing", "2"), imageName)),
-				decor.Percentage(decor.WCSyncSpace),
-			),
-		)
-
-		go func() {
-			defer wg.Done()
-
-			// Start pushing the image
-			responseBody, err := c.client.ImagePush(ctx, pushTo, image.PushOptions{
-				All:          true,
-				RegistryAuth: authString,
-			})
-
-			if err != nil {
-				// Handle the error: log it and send it to the error channel
-				printer.Infof("Error pushing image %s: %v\r\n", imageName, err)
-				errChan <- eris.Wrapf(err, "error pushing image %s", imageName)
-
-				// Stop the progress bar without clearing
-				bar.Abort(false)
-				return
-			}
-			defer responseBody.Close()
-
-			// Process each event and update the progress bar
-			decoder := json.NewDecoder(responseBody)
-			var current int
-			var event map[string]interface{}
-			for decoder.More() {
-				select {
-				case <-ctx.Done():
-					// Handle context cancellation
-					printer.Infof("Pushing image %s was canceled\r\n", imageName)
-					bar.Abort(false) // Stop the progress bar without clearing
-					return
-				default:
-					if err := decoder.Decode(&event); err != nil {
-						errChan <- eris.New(fmt.Sprintf("Error decoding event for %s: %v\r\n", imageName, err))
-						continue
-					}
-
-					// Check for errorDetail and error fields
-					if errorDetail, ok := event["errorDetail"]; ok {
-						if errorMessage, okay := errorDetail.(map[string]interface{})["message"]; okay {
-							errChan <- eris.New(errorMessage.(string))
-							continue
-						}
-					} else if errorMsg, okay := event["error"]; okay {
-						errChan <- eris.New(errorMsg.(string))
-						continue
-					}
-
-					// Handle progress updates
-					if progressDetail, ok := event["progressDetail"].(map[string]interface{}); ok {
-						if total, okay := progressDetail["total"].(float64); okay && total > 0 {
-							calculatedCurrent := int(progressDetail["current"].(float64) * 100 / total)
-							if calculatedCurrent > current {
-								bar.SetCurrent(int64(calculatedCurrent))
-								current = calculatedCurrent
-							}
-						}
-					}
-				}
-			}
-
-			// Finish the progress bar
-			// Handle if the current and total is not available in the response body
-			// Usually, because docker image is already pulled from the cache
-			bar.SetCurrent(100)
-		}()
-	}
-
-	// Wait for all progress bars to complete
-	wg.Wait()
-	p.Wait()
-
-	// Close the error channel and check for errors
-	close(errChan)
-	errs := make([]error, 0)
-	for err := range errChan {
-		errs = append(errs, err)
-	}
-
-	// If there were any errors, return them as a combined error
-	if len(errs) > 0 {
-		return eris.New(fmt.Sprintf("Errors: %v", errs))
-	}
-
-	return nil
-}
@@ -1,16 +1,39 @@
 package docker
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/opencontainers/go-digest"
 	"gotest.tools/v3/assert"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
@@ -63,7 +86,7 @@ func TestMain(m *testing.M) {
 		},
 	}
 
-	dockerClient, err := NewClient(cfg)
+	dockerClient, err := NewClient(context.Background(), cfg)
 	if err != nil {
 		logger.Errorf("Failed to create docker client: %v", err)
 		os.Exit(1)
@@ -98,7 +121,7 @@ func TestStart(t *testing.T) {
 		},
 		Detach: true,
 	}
-	dockerClient, err := NewClient(cfg)
+	dockerClient, err := NewClient(context.Background(), cfg)
 	assert.NilError(t, err, "Failed to create docker client")
 	ctx := t.Context()
 	assert.NilError(t, dockerClient.Start(ctx, service.Redis), "failed to start container")
@@ -122,7 +145,7 @@ func TestStop(t *testing.T) {
 		},
 		Detach: true,
 	}
-	dockerClient, err := NewClient(cfg)
+	dockerClient, err := NewClient(context.Background(), cfg)
 	assert.NilError(t, err, "Failed to create docker client")
 	ctx := t.Context()
 	assert.NilError(t, dockerClient.Start(ctx, service.Redis), "failed to start container")
@@ -147,7 +170,7 @@ func TestRestart(t *testing.T) {
 		},
 		Detach: true,
 	}
-	dockerClient, err := NewClient(cfg)
+	dockerClient, err := NewClient(context.Background(), cfg)
 	assert.NilError(t, err, "Failed to create docker client")
 	ctx := t.Context()
 	assert.NilError(t, dockerClient.Start(ctx, service.Redis), "failed to start container")
@@ -172,7 +195,7 @@ func TestPurge(t *testing.T) {
 		},
 		Detach: true,
 	}
-	dockerClient, err := NewClient(cfg)
+	dockerClient, err := NewClient(context.Background(), cfg)
 	assert.NilError(t, err, "Failed to create docker client")
 	ctx := t.Context()
 	assert.NilError(t, dockerClient.Start(ctx, service.Redis), "failed to start container")
@@ -195,7 +218,7 @@ func TestStartUndetach(t *testing.T) {
 			"REDIS_PORT":         redisPort,
 		},
 	}
-	dockerClient, err := NewClient(cfg)
+	dockerClient, err := NewClient(context.Background(), cfg)
 	assert.NilError(t, err, "Failed to create docker client")
 	ctx, cancel := context.WithCancel(t.Context())
 	go func() {
@@ -235,7 +258,7 @@ func TestBuild(t *testing.T) {
 	}
 	cardinalService := service.Cardinal(cfg)
 	ctx := t.Context()
-	dockerClient, err := NewClient(cfg)
+	dockerClient, err := NewClient(context.Background(), cfg)
 	assert.NilError(t, err, "Failed to create docker client")
 	// Pull prerequisite images
 	assert.NilError(t, dockerClient.pullImages(ctx, cardinalService))
@@ -285,3 +308,664 @@ func cleanUp(t *testing.T, dockerClient *Client) {
 		assert.NilError(t, dockerClient.Close())
 	})
 }
+
+func TestPinBaseImagesFromLockFile(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		DockerEnv: map[string]string{
+			"CARDINAL_NAMESPACE": getUniqueNamespace(t),
+		},
+		RootDir: t.TempDir(),
+	}
+	cardinalService := service.Cardinal(cfg)
+
+	// Record a digest for every base image so nothing is resolved from a registry
+	lock := &baseImageLock{Images: make(map[string]string)}
+	for _, image := range cardinalService.BaseImages {
+		lock.Images[image] = "sha256:" + image
+	}
+	lockPath := filepath.Join(cfg.RootDir, BaseImageLockFile)
+	assert.NilError(t, writeBaseImageLock(lockPath, lock))
+
+	dockerClient, err := NewClient(context.Background(), cfg)
+	assert.NilError(t, err, "Failed to create docker client")
+	defer dockerClient.Close()
+
+	services := []service.Service{cardinalService}
+	assert.NilError(t, dockerClient.pinBaseImages(t.Context(), services))
+	for arg, image := range cardinalService.BaseImages {
+		assert.Equal(t, image+"@sha256:"+image, services[0].BaseImages[arg])
+	}
+	for _, dependency := range services[0].Dependencies {
+		assert.Check(t, strings.Contains(dependency.Image, "@sha256:"), dependency.Image)
+	}
+
+	// The lock file is left untouched when every image is already pinned
+	readLock, err := readBaseImageLock(lockPath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, lock, readLock)
+}
+
+func TestCheckDependencies(t *testing.T) {
+	redis := service.Service{Name: "alpha-redis"}
+	minio := service.Service{Name: "alpha-minio", DependsOn: []string{"alpha-redis"}}
+	assert.NilError(t, checkDependencies([]service.Service{minio, redis}))
+	assert.ErrorContains(t, checkDependencies([]service.Service{minio}),
+		"alpha-minio depends on alpha-redis, which is not started")
+}
+
+func TestContainerOutdated(t *testing.T) {
+	cfg := &config.Config{
+		DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"},
+		Resources: map[string]config.ServiceResources{"redis": {Memory: 512 << 20, Restart: "on-failure", MaxRetries: 3}},
+	}
+	redis := service.Redis(cfg)
+	service.ApplyResources(cfg, &redis)
+	info := container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{
+		HostConfig: &container.HostConfig{Resources: container.Resources{Memory: 512 << 20},
+			RestartPolicy: container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}, Mounts: redis.Mounts},
+	}}
+	assert.Assert(t, !containerOutdated(info, redis))
+
+	// The limit was raised in world.toml
+	info.HostConfig.Memory = 256 << 20
+	assert.Assert(t, containerOutdated(info, redis))
+
+	// The section was removed from world.toml
+	redis = service.Redis(&config.Config{DockerEnv: cfg.DockerEnv})
+	assert.Assert(t, containerOutdated(info, redis))
+	info.HostConfig.Memory, info.HostConfig.RestartPolicy = 0, redis.RestartPolicy
+	assert.Assert(t, !containerOutdated(info, redis))
+
+	// An unset restart policy is reported as "no"
+	redis.RestartPolicy = container.RestartPolicy{}
+	info.HostConfig.RestartPolicy = container.RestartPolicy{Name: container.RestartPolicyDisabled}
+	assert.Assert(t, !containerOutdated(info, redis))
+}
+
+func TestContainerOutdatedByNakamaFlagsAndMounts(t *testing.T) {
+	cfg := &config.Config{RootDir: "/game", DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"}}
+	nakama := service.Nakama(cfg)
+	info := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{HostConfig: &nakama.HostConfig},
+		Config:            &container.Config{Entrypoint: nakama.Entrypoint},
+	}
+	assert.Assert(t, !containerOutdated(info, nakama))
+
+	// A flag of world.toml changes the entrypoint
+	cfg.DockerEnv["NAKAMA_LOG_LEVEL"] = "DEBUG"
+	assert.Assert(t, containerOutdated(info, service.Nakama(cfg)))
+
+	// A config file adds a mount
+	delete(cfg.DockerEnv, "NAKAMA_LOG_LEVEL")
+	cfg.DockerEnv[config.NakamaConfigFileKey] = "nakama/config.yml"
+	assert.Assert(t, containerOutdated(info, service.Nakama(cfg)))
+
+	// Services without an entrypoint have the one of their image
+	redis := service.Redis(cfg)
+	info = container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{HostConfig: &redis.HostConfig},
+		Config:            &container.Config{Entrypoint: []string{"docker-entrypoint.sh"}},
+	}
+	assert.Assert(t, !containerOutdated(info, redis))
+}
+
+// newFakeEngineClient returns a client of a fake Docker Engine API served by handler.
+func newFakeEngineClient(t *testing.T, cfg *config.Config, handler http.Handler) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()),
+		client.WithVersion(api.DefaultVersion))
+	assert.NilError(t, err)
+	return &Client{client: cli, cfg: cfg}
+}
+
+func TestBuildPlatformImages(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		builds []url.Values
+		tagged []string
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/info"):
+			_, _ = w.Write([]byte(`{"OSType": "linux", "Architecture": "aarch64"}`))
+		case strings.HasSuffix(r.URL.Path, "/build"):
+			_, _ = io.Copy(io.Discard, r.Body)
+			builds = append(builds, r.URL.Query())
+			_, _ = fmt.Fprintf(w, `{"stream": "built %s\n"}`, r.URL.Query().Get("platform"))
+		case strings.HasSuffix(r.URL.Path, "/tag"):
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v"+api.DefaultVersion+"/images/"), "/tag")
+			tagged = append(tagged, name+" "+r.URL.Query().Get("repo")+":"+r.URL.Query().Get("tag"))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	cfg := &config.Config{CardinalBuild: config.CardinalBuild{Platforms: []string{"linux/amd64", "linux/arm64"}}}
+	c := newFakeEngineClient(t, cfg, handler)
+
+	body, err := c.buildPlatformImages(context.Background(), []byte("context"),
+		build.ImageBuildOptions{Tags: []string{"alpha", "alpha:abc123"}})
+	assert.NilError(t, err)
+	mu.Lock()
+	// The build of the next platform starts once the output of the previous one is read
+	assert.Equal(t, 1, len(builds))
+	mu.Unlock()
+
+	output, err := io.ReadAll(body)
+	assert.NilError(t, err)
+	assert.NilError(t, body.Close())
+	assert.Equal(t, `{"stream": "built linux/amd64\n"}{"stream": "built linux/arm64\n"}`, string(output))
+
+	// Every build is for one platform, tagged with the platform
+	assert.Equal(t, 2, len(builds))
+	for i, platform := range cfg.CardinalBuild.Platforms {
+		assert.Equal(t, platform, builds[i].Get("platform"))
+		suffix := strings.ReplaceAll(platform, "/", "-")
+		assert.DeepEqual(t, []string{"alpha:latest-" + suffix, "alpha:abc123-" + suffix}, builds[i]["t"])
+	}
+	// The image of the daemon platform is the parent of the other one
+	var amd64Labels, arm64Labels map[string]string
+	assert.NilError(t, json.Unmarshal([]byte(builds[0].Get("labels")), &amd64Labels))
+	assert.NilError(t, json.Unmarshal([]byte(builds[1].Get("labels")), &arm64Labels))
+	assert.Assert(t, arm64Labels[LabelBuild] != "")
+	assert.Equal(t, arm64Labels[LabelBuild], amd64Labels[LabelParent])
+	// The tags of the build go to the image of the platform of the daemon
+	assert.DeepEqual(t, []string{
+		"alpha:latest-linux-arm64 docker.io/library/alpha:latest",
+		"alpha:abc123-linux-arm64 docker.io/library/alpha:abc123",
+	}, tagged)
+}
+
+func TestPlatformTag(t *testing.T) {
+	assert.Equal(t, "world:latest-linux-amd64", platformTag("world", "linux/amd64"))
+	assert.Equal(t, "ghcr.io/a/b:v1-linux-arm-v7", platformTag("ghcr.io/a/b:v1", "linux/arm/v7"))
+}
+
+func TestPushManifestList(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+	repository := strings.TrimPrefix(server.URL, "http://") + "/game"
+
+	platforms := []string{"linux/amd64", "linux/arm64"}
+	images := make([]platformImage, 0, len(platforms))
+	digests := make(map[string]v1.Hash, len(platforms))
+	for _, platform := range platforms {
+		img, err := random.Image(64, 1)
+		assert.NilError(t, err)
+		ref := platformTag(repository+":v1", platform)
+		tag, err := name.ParseReference(ref)
+		assert.NilError(t, err)
+		assert.NilError(t, remote.Write(tag, img))
+		digests[platform], err = img.Digest()
+		assert.NilError(t, err)
+		images = append(images, platformImage{platform: platform, ref: ref})
+	}
+
+	digest, err := pushManifestList(context.Background(), repository+":v1", images, "")
+	assert.NilError(t, err)
+
+	tag, err := name.ParseReference(repository + ":v1")
+	assert.NilError(t, err)
+	index, err := remote.Index(tag)
+	assert.NilError(t, err)
+	indexDigest, err := index.Digest()
+	assert.NilError(t, err)
+	assert.Equal(t, indexDigest.String(), digest)
+	manifest, err := index.IndexManifest()
+	assert.NilError(t, err)
+	assert.Equal(t, types.DockerManifestList, manifest.MediaType)
+	assert.Equal(t, 2, len(manifest.Manifests))
+	for i, platform := range platforms {
+		assert.Equal(t, platform, manifest.Manifests[i].Platform.String())
+		assert.Equal(t, digests[platform], manifest.Manifests[i].Digest)
+	}
+}
+
+func TestImageTags(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "my-shard", imageRepository("my-shard"))
+	assert.Equal(t, "my-shard", imageRepository("my-shard:latest"))
+	assert.Equal(t, "registry.example.com:5000/team/app", imageRepository("registry.example.com:5000/team/app:v1"))
+
+	// Outside of a git repository the image is only tagged with its name and the version
+	cfg := &config.Config{
+		DockerEnv: map[string]string{
+			"CARDINAL_NAMESPACE": getUniqueNamespace(t),
+		},
+		RootDir: t.TempDir(),
+		CardinalBuild: config.CardinalBuild{
+			Version: "v1.2.0+build.5",
+		},
+	}
+	dockerClient, err := NewClient(context.Background(), cfg)
+	assert.NilError(t, err, "Failed to create docker client")
+	defer dockerClient.Close()
+
+	cardinalService := service.Cardinal(cfg)
+	tags, labels := dockerClient.imageTags(cardinalService)
+	assert.DeepEqual(t, []string{cardinalService.Image, cardinalService.Image + ":v1.2.0_build.5"}, tags)
+	assert.Equal(t, cfg.DockerEnv["CARDINAL_NAMESPACE"], labels[LabelNamespace])
+	assert.Equal(t, "v1.2.0+build.5", labels[LabelVersion])
+	assert.Equal(t, "", labels[LabelRevision])
+}
+
+func TestPruneImagesKeepsRecentImagesOfEveryService(t *testing.T) {
+	// Two services of one namespace, built at different times, the oldest game image is used by a container
+	summaries := []image.Summary{
+		{ID: "sha256:game3", Created: 30, Labels: map[string]string{LabelNamespace: "alpha", LabelService: "cardinal"}},
+		{ID: "sha256:game2", Created: 20, Labels: map[string]string{LabelNamespace: "alpha", LabelService: "cardinal"}},
+		{ID: "sha256:game1", Created: 10, Labels: map[string]string{LabelNamespace: "alpha", LabelService: "cardinal"}},
+		{ID: "sha256:game0", Created: 5, Labels: map[string]string{LabelNamespace: "alpha", LabelService: "cardinal"}},
+		{ID: "sha256:pay2", Created: 2, Labels: map[string]string{LabelNamespace: "alpha", LabelService: "payments"}},
+		{ID: "sha256:pay1", Created: 1, Labels: map[string]string{LabelNamespace: "alpha", LabelService: "payments"}},
+	}
+	var (
+		mu      sync.Mutex
+		removed []string
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			_ = json.NewEncoder(w).Encode(summaries)
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			_ = json.NewEncoder(w).Encode([]container.Summary{{ImageID: "sha256:game0"}})
+		case r.Method == http.MethodDelete:
+			removed = append(removed, strings.TrimPrefix(r.URL.Path, "/v"+api.DefaultVersion+"/images/"))
+			_, _ = w.Write([]byte("[]"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	c := newFakeEngineClient(t, &config.Config{}, handler)
+
+	pruned, err := c.PruneImages(context.Background(), 2)
+	assert.NilError(t, err)
+	ids := make([]string, 0, len(pruned))
+	for _, img := range pruned {
+		ids = append(ids, img.ID)
+	}
+	// The recent builds of payments are kept even though the builds of cardinal are more recent
+	assert.DeepEqual(t, []string{"sha256:game1"}, ids)
+	assert.DeepEqual(t, []string{"sha256:game1"}, removed)
+}
+
+func TestPruneImagesRemovesThePlatformImagesOfABuild(t *testing.T) {
+	cardinal := func(labels map[string]string) map[string]string {
+		labels[LabelNamespace], labels[LabelService] = "alpha", "cardinal"
+		return labels
+	}
+	// Two multi-platform builds, the arm64 image of the oldest one lost its tags to the newest one
+	summaries := []image.Summary{
+		{ID: "sha256:new", Created: 30, RepoTags: []string{"alpha:latest"},
+			Labels: cardinal(map[string]string{LabelBuild: "b2"})},
+		{ID: "sha256:new-arm64", Created: 30, RepoTags: []string{"alpha:latest-linux-arm64"},
+			Labels: cardinal(map[string]string{LabelParent: "b2"})},
+		{ID: "sha256:old", Created: 20, RepoTags: []string{"alpha:abc123"},
+			Labels: cardinal(map[string]string{LabelBuild: "b1"})},
+		{ID: "sha256:old-arm64", Created: 20, Labels: cardinal(map[string]string{LabelParent: "b1"})},
+	}
+	var removed []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			_ = json.NewEncoder(w).Encode(summaries)
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			_, _ = w.Write([]byte("[]"))
+		case r.Method == http.MethodDelete:
+			removed = append(removed, strings.TrimPrefix(r.URL.Path, "/v"+api.DefaultVersion+"/images/"))
+			_, _ = w.Write([]byte("[]"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	c := newFakeEngineClient(t, &config.Config{}, handler)
+
+	images, err := c.ListImages(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(images))
+	assert.Equal(t, "sha256:new-arm64", images[0].Platforms[0].ID)
+
+	// The build is kept or removed as a whole
+	pruned, err := c.PruneImages(context.Background(), 1)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(pruned))
+	assert.Equal(t, "sha256:old", pruned[0].ID)
+	assert.DeepEqual(t, []string{"alpha:abc123", "sha256:old-arm64"}, removed)
+}
+
+func TestRegistryServer(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, DockerHubServer, RegistryServer(""))
+	assert.Equal(t, DockerHubServer, RegistryServer("docker.io"))
+	assert.Equal(t, DockerHubServer, RegistryServer("https://index.docker.io/v1/"))
+	assert.Equal(t, "ghcr.io", RegistryServer("https://ghcr.io/"))
+
+	server, err := imageRegistryServer("ghcr.io/argus-labs/game:v1")
+	assert.NilError(t, err)
+	assert.Equal(t, "ghcr.io", server)
+	server, err = imageRegistryServer("argus/game")
+	assert.NilError(t, err)
+	assert.Equal(t, DockerHubServer, server)
+}
+
+func TestPushReferences(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		DockerEnv: map[string]string{
+			"CARDINAL_NAMESPACE": getUniqueNamespace(t),
+		},
+		RootDir: t.TempDir(),
+		CardinalBuild: config.CardinalBuild{
+			Version: "v1.2.0",
+		},
+	}
+	dockerClient, err := NewClient(context.Background(), cfg)
+	assert.NilError(t, err, "Failed to create docker client")
+	defer dockerClient.Close()
+
+	// A repository gets every tag of the build, a tagged reference only its own tag
+	refs, err := dockerClient.pushReferences(service.Cardinal(cfg),
+		[]string{"ghcr.io/argus-labs/game", "ghcr.io/argus-labs/game:prod", "ghcr.io/argus-labs/game:v1.2.0"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{
+		"ghcr.io/argus-labs/game:latest",
+		"ghcr.io/argus-labs/game:v1.2.0",
+		"ghcr.io/argus-labs/game:prod",
+	}, refs)
+
+	_, err = dockerClient.pushReferences(service.Cardinal(cfg), []string{"ghcr.io/argus-labs/game@sha256:" +
+		"0000000000000000000000000000000000000000000000000000000000000000"})
+	assert.ErrorContains(t, err, "pinned by digest")
+}
+
+func TestPushImagesTagsEveryImageBeforePushing(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		pushed []string
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/alpha/json"):
+			_, _ = w.Write([]byte(`{"Id": "sha256:alpha"}`))
+		case strings.HasSuffix(r.URL.Path, "/tag"):
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasSuffix(r.URL.Path, "/push"):
+			pushed = append(pushed, r.URL.Path)
+		default:
+			http.Error(w, `{"message": "No such image"}`, http.StatusNotFound)
+		}
+	})
+	c := newFakeEngineClient(t, &config.Config{}, handler)
+
+	// The image of the second service is missing, nothing is pushed
+	err := c.pushImages(context.Background(), PushOptions{Targets: []string{"ghcr.io/argus-labs/game:prod"}},
+		service.Service{Name: "cardinal", Config: container.Config{Image: "alpha"}, Dockerfile: "FROM scratch"},
+		service.Service{Name: "payments", Config: container.Config{Image: "payments"}, BuildContext: "./payments"})
+	assert.ErrorContains(t, err, "Error inspecting image payments for service payments")
+	assert.Equal(t, 0, len(pushed))
+}
+
+func TestMissingBuiltImages(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/images/alpha/json") {
+			_, _ = w.Write([]byte(`{"Id": "sha256:alpha"}`))
+			return
+		}
+		http.Error(w, `{"message": "No such image"}`, http.StatusNotFound)
+	})
+	c := newFakeEngineClient(t, &config.Config{}, handler)
+
+	// Only the images the CLI builds are checked, the others are pulled
+	missing, err := c.MissingBuiltImages(context.Background(),
+		func(*config.Config) service.Service {
+			return service.Service{Name: "cardinal", Config: container.Config{Image: "alpha"}, Dockerfile: "FROM scratch"}
+		},
+		func(*config.Config) service.Service {
+			return service.Service{Name: "payments", Config: container.Config{Image: "payments"}, BuildContext: "./payments"}
+		},
+		func(*config.Config) service.Service {
+			return service.Service{Name: "redis", Config: container.Config{Image: "redis:latest"}}
+		})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"payments"}, missing)
+}
+
+func TestImageArchiveRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"images.tar", "images.tar.gz"} {
+		path := filepath.Join(dir, name)
+		assert.NilError(t, writeImageArchive(strings.NewReader("image layers"), path))
+
+		file, err := os.Open(path)
+		assert.NilError(t, err)
+		defer file.Close()
+		var reader io.Reader = file
+		if isGzipArchive(path) {
+			reader, err = gzip.NewReader(file)
+			assert.NilError(t, err)
+		}
+		content, err := io.ReadAll(reader)
+		assert.NilError(t, err)
+		assert.Equal(t, "image layers", string(content))
+	}
+
+	// The temporary files are renamed into place
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(entries))
+}
+
+func TestReadLoadedImages(t *testing.T) {
+	t.Parallel()
+
+	loaded, err := readLoadedImages(strings.NewReader(
+		`{"stream":"Loaded image: redis:latest\n"}` + "\n" +
+			`{"stream":"Loaded image: game:a1b2c3d4e5f6\n"}` + "\n" +
+			`{"stream":"Loaded image ID: sha256:0123\n"}`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"redis:latest", "game:a1b2c3d4e5f6", "sha256:0123"}, loaded)
+
+	_, err = readLoadedImages(strings.NewReader(`{"errorDetail":{"message":"unexpected EOF"},"error":"unexpected EOF"}`))
+	assert.ErrorContains(t, err, "unexpected EOF")
+}
+
+func TestBuildLogFailure(t *testing.T) {
+	t.Parallel()
+
+	sourceDir := t.TempDir()
+	log := &buildLog{image: "game", sourceDir: sourceDir, steps: make(map[digest.Digest]*buildStep)}
+	started := time.Now()
+	log.addStatus(&controlapi.StatusResponse{
+		Vertexes: []*controlapi.Vertex{
+			{Digest: "sha256:mod", Name: "[build 5/7] RUN go mod download", Started: &started, Completed: &started},
+			{Digest: "sha256:build", Name: "[build 7/7] RUN go build -o /go/bin/app", Started: &started},
+		},
+	})
+	// The output is split in chunks that don't end at line boundaries
+	log.addStatus(&controlapi.StatusResponse{
+		Logs: []*controlapi.VertexLog{
+			{Vertex: "sha256:build", Msg: []byte("# pkg.world.dev/game/system\nsystem/move.go:12:3: undef")},
+			{Vertex: "sha256:build", Msg: []byte("ined: speed\n/go/src/app/main.go:40: missing return\n")},
+		},
+	})
+	log.addStatus(&controlapi.StatusResponse{
+		Vertexes: []*controlapi.Vertex{
+			{Digest: "sha256:build", Name: "[build 7/7] RUN go build -o /go/bin/app", Error: "exit code: 1"},
+		},
+	})
+	log.addError(errors.New("exit code: 1"))
+
+	assert.Assert(t, log.failed())
+	failure := log.failure()
+	assert.Equal(t, "[build 7/7] RUN go build -o /go/bin/app", failure.Step)
+	assert.DeepEqual(t, []string{
+		"# pkg.world.dev/game/system",
+		"system/move.go:12:3: undefined: speed",
+		"/go/src/app/main.go:40: missing return",
+	}, failure.Output)
+	assert.DeepEqual(t, []CompileError{
+		{File: filepath.Join(sourceDir, "system/move.go"), Line: 12, Column: 3, Message: "undefined: speed"},
+		{File: filepath.Join(sourceDir, "main.go"), Line: 40, Message: "missing return"},
+	}, failure.CompileErrors)
+}
+
+func TestBuildLogsOfImage(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"build-game-20260102-150405.000.log",
+		"build-game-20260101-150405.000.log",
+		"build-game-v2-20260103-150405.000.log",
+		"build-ghcr.io_team_game_v1-20260101-150405.000.log",
+	} {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+
+	assert.DeepEqual(t, []string{
+		filepath.Join(dir, "build-game-20260101-150405.000.log"),
+		filepath.Join(dir, "build-game-20260102-150405.000.log"),
+	}, buildLogs(dir, "game"))
+	assert.Equal(t, 1, len(buildLogs(dir, "ghcr.io/team/game:v1")))
+
+	pruneBuildLogs(dir, "game", 1)
+	assert.DeepEqual(t, []string{filepath.Join(dir, "build-game-20260102-150405.000.log")}, buildLogs(dir, "game"))
+	assert.Equal(t, 1, len(buildLogs(dir, "game-v2")))
+}
+
+func TestRestartTrackerDetectsCrashLoops(t *testing.T) {
+	tracker := newRestartTracker()
+	now := time.Now()
+
+	// The first observation is the baseline, restarts of a previous run are not reported
+	restarted, crashLoop := tracker.observe("alpha-cardinal", 4, now)
+	assert.Assert(t, !restarted && !crashLoop)
+
+	for i := 1; i < crashLoopRestarts; i++ {
+		restarted, crashLoop = tracker.observe("alpha-cardinal", 4+i, now.Add(time.Duration(i)*time.Second))
+		assert.Assert(t, restarted && !crashLoop)
+	}
+	restarted, crashLoop = tracker.observe("alpha-cardinal", 4+crashLoopRestarts, now.Add(10*time.Second))
+	assert.Assert(t, restarted && crashLoop)
+
+	// The crash loop is reported once, until the container stays up for a whole window
+	restarted, crashLoop = tracker.observe("alpha-cardinal", 5+crashLoopRestarts, now.Add(20*time.Second))
+	assert.Assert(t, restarted && !crashLoop)
+	restarted, crashLoop = tracker.observe("alpha-cardinal", 5+crashLoopRestarts, now.Add(crashLoopWindow+time.Minute))
+	assert.Assert(t, !restarted && !crashLoop)
+
+	// Restarts spread over more than a window are not a crash loop
+	for i := 1; i <= crashLoopRestarts; i++ {
+		_, crashLoop = tracker.observe("alpha-cardinal", 5+crashLoopRestarts+i,
+			now.Add(crashLoopWindow+time.Minute+time.Duration(i)*crashLoopWindow))
+		assert.Assert(t, !crashLoop)
+	}
+}
+
+func TestIsCrashLooping(t *testing.T) {
+	now := time.Now()
+	startedAt := now.Add(-10 * time.Second).Format(time.RFC3339Nano)
+
+	assert.Assert(t, isCrashLooping(&container.State{Restarting: true}, 1, now))
+	assert.Assert(t, isCrashLooping(&container.State{Running: true, StartedAt: startedAt}, crashLoopRestarts, now))
+	assert.Assert(t, !isCrashLooping(&container.State{Running: true, StartedAt: startedAt}, 1, now))
+	assert.Assert(t, !isCrashLooping(&container.State{Running: true, StartedAt: startedAt}, crashLoopRestarts,
+		now.Add(crashLoopWindow)))
+}
+
+func TestContainerReadiness(t *testing.T) {
+	testCases := []struct {
+		name    string
+		state   *container.State
+		ready   bool
+		failure string
+	}{
+		{"running without healthcheck", &container.State{Running: true}, true, ""},
+		{"healthy", &container.State{Running: true, Health: &container.Health{Status: container.Healthy}}, true, ""},
+		{"starting", &container.State{Running: true, Health: &container.Health{Status: container.Starting}}, false, ""},
+		{"unhealthy", &container.State{Running: true, Health: &container.Health{
+			Status: container.Unhealthy,
+			Log:    []*container.HealthcheckResult{{Output: "connection refused\n"}},
+		}}, false, "unhealthy: connection refused"},
+		{"restarting", &container.State{Running: true, Restarting: true, ExitCode: 2}, false, ""},
+		{"exited", &container.State{Status: container.StateExited, ExitCode: 1}, false, "exiting with code 1"},
+		{"out of memory", &container.State{Status: container.StateExited, OOMKilled: true}, false,
+			"running out of memory"},
+		{"created", &container.State{Status: container.StateCreated}, false, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := containerReadiness(tc.state)
+			assert.Equal(t, tc.ready, state.ready)
+			assert.Equal(t, tc.failure, state.failure)
+			assert.Equal(t, tc.ready || tc.failure != "", state.pending == "")
+		})
+	}
+}
+
+func TestWithNakamaStoppedRestartsNakamaOnError(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			_, _ = w.Write([]byte(`{"Id": "nakama", "State": {"Running": true}}`))
+		case strings.HasSuffix(r.URL.Path, "/stop"), strings.HasSuffix(r.URL.Path, "/start"):
+			calls = append(calls, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	cfg := &config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"}}
+	c := newFakeEngineClient(t, cfg, handler)
+
+	err := c.withNakamaStopped(context.Background(), func() error {
+		return errors.New("restore failed")
+	})
+	assert.ErrorContains(t, err, "restore failed")
+	assert.DeepEqual(t, []string{"stop", "start"}, calls)
+}
+
+func TestFilesArchive(t *testing.T) {
+	modTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	archive, err := filesArchive(map[string]string{
+		"/etc/grafana/provisioning/datasources/world.yaml": "apiVersion: 1\n",
+		"/etc/prometheus/world.yml":                        "global: {}\n",
+	}, modTime)
+	assert.NilError(t, err)
+
+	tr := tar.NewReader(archive)
+	names := make([]string, 0)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NilError(t, err)
+		content, err := io.ReadAll(tr)
+		assert.NilError(t, err)
+		assert.Equal(t, int64(len(content)), header.Size)
+		assert.Equal(t, int64(0o644), header.Mode)
+		names = append(names, header.Name)
+	}
+	assert.DeepEqual(t, []string{"etc/grafana/provisioning/datasources/world.yaml", "etc/prometheus/world.yml"}, names)
+}
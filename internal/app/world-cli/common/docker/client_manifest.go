@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/rotisserie/eris"
+)
+
+// platformImage is the pushed image of a platform of a multi-platform build.
+type platformImage struct {
+	// platform is the platform of the image, e.g. linux/arm64
+	platform string
+	// ref is the reference the image was pushed to
+	ref string
+}
+
+// rawManifest is a manifest pushed as is.
+type rawManifest struct {
+	content   []byte
+	mediaType types.MediaType
+}
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m.content, nil
+}
+
+func (m rawManifest) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
+}
+
+// platformTag returns the tag of the image of a platform of a multi-platform build, the tag of the build suffixed
+// with the platform, e.g. world:latest-linux-arm64.
+func platformTag(imageName string, platform string) string {
+	return imageRepository(imageName) + ":" + imageTag(imageName) + "-" + strings.ReplaceAll(platform, "/", "-")
+}
+
+// pushManifestList pushes to ref the manifest list of the images of every platform, already pushed, and returns
+// its digest. The Docker Engine pushes one image at a time, so the list is assembled here, like docker manifest
+// create and push do. auth is the encoded registry auth, empty for anonymous access.
+func pushManifestList(ctx context.Context, ref string, images []platformImage, auth string) (string, error) {
+	options, err := remoteOptions(ctx, auth)
+	if err != nil {
+		return "", err
+	}
+
+	list := v1.IndexManifest{SchemaVersion: 2, MediaType: types.DockerManifestList} //nolint:mnd // schema version
+	for _, img := range images {
+		desc, err := platformDescriptor(img, options)
+		if err != nil {
+			return "", err
+		}
+		// A list referring to OCI manifests must be an OCI index
+		if desc.MediaType == types.OCIManifestSchema1 {
+			list.MediaType = types.OCIImageIndex
+		}
+		list.Manifests = append(list.Manifests, desc)
+	}
+	content, err := json.Marshal(list)
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to encode the manifest list")
+	}
+
+	tag, err := name.ParseReference(ref)
+	if err != nil {
+		return "", eris.Wrapf(err, "invalid push reference %q", ref)
+	}
+	if err := remote.Put(tag, rawManifest{content: content, mediaType: list.MediaType}, options...); err != nil {
+		return "", eris.Wrapf(err, "Failed to push the manifest list of %s", ref)
+	}
+	digest, _, err := v1.SHA256(bytes.NewReader(content))
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to compute the digest of the manifest list")
+	}
+	return digest.String(), nil
+}
+
+// platformDescriptor returns the descriptor of the manifest of the pushed image of a platform. A daemon using the
+// containerd image store may push an index, whose manifest for the platform is used.
+func platformDescriptor(img platformImage, options []remote.Option) (v1.Descriptor, error) {
+	platform, err := v1.ParsePlatform(img.platform)
+	if err != nil {
+		return v1.Descriptor{}, eris.Wrapf(err, "invalid platform %q", img.platform)
+	}
+	ref, err := name.ParseReference(img.ref)
+	if err != nil {
+		return v1.Descriptor{}, eris.Wrapf(err, "invalid reference %q", img.ref)
+	}
+	pushed, err := remote.Get(ref, options...)
+	if err != nil {
+		return v1.Descriptor{}, eris.Wrapf(err, "Failed to get the manifest of %s", img.ref)
+	}
+	if !pushed.MediaType.IsIndex() {
+		desc := pushed.Descriptor
+		desc.Platform = platform
+		return desc, nil
+	}
+
+	index, err := pushed.ImageIndex()
+	if err != nil {
+		return v1.Descriptor{}, eris.Wrapf(err, "Failed to read the index of %s", img.ref)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return v1.Descriptor{}, eris.Wrapf(err, "Failed to read the index of %s", img.ref)
+	}
+	for _, desc := range manifest.Manifests {
+		if desc.Platform != nil && desc.Platform.Satisfies(*platform) {
+			return desc, nil
+		}
+	}
+	return v1.Descriptor{}, eris.Errorf("%s has no image for %s", img.ref, img.platform)
+}
+
+// remoteOptions returns the options of the registry requests, authenticated with the encoded registry auth.
+func remoteOptions(ctx context.Context, auth string) ([]remote.Option, error) {
+	authenticator := authn.Anonymous
+	if auth != "" {
+		authConfig, err := registry.DecodeAuthConfig(auth)
+		if err != nil {
+			return nil, eris.Wrap(err, "invalid registry auth")
+		}
+		authenticator = authn.FromConfig(authn.AuthConfig{
+			Username:      authConfig.Username,
+			Password:      authConfig.Password,
+			IdentityToken: authConfig.IdentityToken,
+			RegistryToken: authConfig.RegistryToken,
+		})
+	}
+	return []remote.Option{remote.WithContext(ctx), remote.WithAuth(authenticator)}, nil
+}
@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+// nakamaVersionPattern matches the version printed by nakama --version, e.g. 3.22.0+e9b0a2d9.
+//
+//nolint:gochecknoglobals // compiled once
+var nakamaVersionPattern = regexp.MustCompile(`\d+\.\d+\.\d+`)
+
+// resolveNakamaVersion sets NAKAMA_VERSION to the version of the Nakama image when the Go plugin of the
+// project is built without it. Go plugins only load in a Nakama built with the same Go and nakama-common
+// versions, which the plugin builder image of that version has.
+func (c *Client) resolveNakamaVersion(ctx context.Context, serviceBuilders []service.Builder) error {
+	if c.cfg.DockerEnv[config.NakamaGoPluginKey] == "" || c.cfg.DockerEnv[service.NakamaVersionKey] != "" {
+		return nil
+	}
+	nakama := service.Nakama(c.cfg)
+	if !slices.ContainsFunc(serviceBuilders, func(sb service.Builder) bool {
+		return sb(c.cfg).Name == nakama.Name
+	}) {
+		return nil
+	}
+
+	image := service.NakamaImage(c.cfg)
+	if err := c.pullImages(ctx, service.Service{
+		Name:     image,
+		Config:   container.Config{Image: image},
+		Platform: nakama.Platform,
+	}); err != nil {
+		return eris.Wrap(err, "Failed to pull the Nakama image")
+	}
+	output, err := c.runOnce(ctx, image, nakama.Platform, "/nakama/nakama", "--version")
+	if err != nil {
+		return eris.Wrapf(err, "Failed to get the Nakama version of %s", image)
+	}
+	version := nakamaVersionPattern.FindString(output)
+	if version == "" {
+		return eris.Errorf("Failed to get the Nakama version of %s from %q, set %s in the [nakama] section "+
+			"of world.toml", image, output, service.NakamaVersionKey)
+	}
+
+	c.cfg.DockerEnv[service.NakamaVersionKey] = version
+	printer.Infof("Building the Nakama Go plugin for Nakama %s\n", version)
+	return nil
+}
+
+// runOnce runs a command in a new container of the image and returns its output.
+func (c *Client) runOnce(ctx context.Context, image string, platform ocispec.Platform,
+	cmd ...string) (string, error) {
+	created, err := c.client.ContainerCreate(ctx, &container.Config{Image: image, Entrypoint: cmd}, nil, nil,
+		&platform, "")
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to create container")
+	}
+	defer func() {
+		_ = c.client.ContainerRemove(context.Background(), created.ID, container.RemoveOptions{Force: true})
+	}()
+
+	statusCh, errCh := c.client.ContainerWait(ctx, created.ID, container.WaitConditionNextExit)
+	if err := c.client.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return "", eris.Wrap(err, "Failed to start container")
+	}
+	select {
+	case err := <-errCh:
+		return "", eris.Wrap(err, "Failed to wait for container")
+	case <-statusCh:
+	}
+
+	logs, err := c.client.ContainerLogs(ctx, created.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to read container logs")
+	}
+	defer logs.Close()
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, logs); err != nil {
+		return "", eris.Wrap(err, "Failed to read container logs")
+	}
+	return output.String(), nil
+}
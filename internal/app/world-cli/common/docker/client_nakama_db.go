@@ -0,0 +1,270 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+// customDumpMagic starts the archives of pg_dump --format=custom, which are restored with pg_restore.
+// Other dumps are SQL scripts restored with psql.
+const customDumpMagic = "PGDMP"
+
+// DumpNakamaDB writes a dump of the Nakama database to a file, in the custom format of pg_restore or as an
+// SQL script. The database is started when it is not running.
+func (c *Client) DumpNakamaDB(ctx context.Context, path string, sql bool) error {
+	db, err := c.ensureNakamaDB(ctx)
+	if err != nil {
+		return err
+	}
+	return c.dumpNakamaDBToFile(ctx, db, path, sql)
+}
+
+// RestoreNakamaDB replaces the Nakama database with a dump of DumpNakamaDB. Nakama is stopped meanwhile and
+// started again when it was running.
+func (c *Client) RestoreNakamaDB(ctx context.Context, r io.Reader) error {
+	db, err := c.ensureNakamaDB(ctx)
+	if err != nil {
+		return err
+	}
+	return c.withNakamaStopped(ctx, func() error {
+		return c.restoreNakamaDB(ctx, db, r)
+	})
+}
+
+// StartNakamaDB starts the Nakama database when it is not running and waits until it accepts connections.
+func (c *Client) StartNakamaDB(ctx context.Context) error {
+	_, err := c.ensureNakamaDB(ctx)
+	return err
+}
+
+// NakamaDBShell runs psql on the Nakama database with the given arguments.
+func (c *Client) NakamaDBShell(ctx context.Context, args []string, opts ExecOptions) error {
+	db, err := c.ensureNakamaDB(ctx)
+	if err != nil {
+		return err
+	}
+	cmd := append([]string{"psql", "--username", service.NakamaDBUser, "--dbname", service.NakamaDBName}, args...)
+	return c.execNakamaDB(ctx, db, cmd, opts)
+}
+
+// MigrateNakama runs a nakama migrate command (up, down, redo or status) in the running Nakama container.
+// A limit above zero limits the number of migrations applied or rolled back.
+func (c *Client) MigrateNakama(ctx context.Context, command string, limit int, opts ExecOptions) error {
+	nakama := service.Nakama(c.cfg)
+	running, err := c.containerRunning(ctx, nakama.Name)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return eris.Errorf("%s is not running, start it with: world cardinal start --detach", nakama.Name)
+	}
+
+	cmd := []string{"/nakama/nakama", "migrate", command, "--database.address", service.NakamaDBAddress(c.cfg)}
+	if limit > 0 {
+		cmd = append(cmd, "--limit", strconv.Itoa(limit))
+	}
+	code, err := c.ExecWithOptions(ctx, nakama.Name, cmd, opts)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return eris.Errorf("nakama migrate %s failed with exit code %d", command, code)
+	}
+	return nil
+}
+
+// NakamaDBContainerImage returns the image the container of the Nakama database was created with, or an
+// empty string when there is no container.
+func (c *Client) NakamaDBContainerImage(ctx context.Context) (string, error) {
+	db := service.NakamaDB(c.cfg)
+	exists, err := c.containerExists(ctx, db.Name)
+	if err != nil || !exists {
+		return "", err
+	}
+	info, err := c.client.ContainerInspect(ctx, db.Name)
+	if err != nil {
+		return "", eris.Wrapf(err, "Failed to inspect container %s", db.Name)
+	}
+	return info.Config.Image, nil
+}
+
+// UpgradeNakamaDB moves the Nakama database to the image of world.toml: it dumps the database with its
+// current image to backupPath, removes its container and volume, and restores the dump with the new image.
+// The backup is kept, restore it with RestoreNakamaDB if anything goes wrong.
+func (c *Client) UpgradeNakamaDB(ctx context.Context, backupPath string) error {
+	db, err := c.ensureNakamaDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.withNakamaStopped(ctx, func() error {
+		printer.Infof("Dumping the Nakama database to %s\n", backupPath)
+		if err := c.dumpNakamaDBToFile(ctx, db, backupPath, false); err != nil {
+			return err
+		}
+
+		printer.Infof("Removing %s and its data\n", db.Name)
+		if err := c.removeContainer(ctx, db.Name); err != nil {
+			return err
+		}
+		if err := c.processVolumes(ctx, REMOVE, db); err != nil {
+			return err
+		}
+
+		printer.Infof("Restoring the dump into %s\n", service.NakamaDBImage(c.cfg))
+		upgradedDB, err := c.ensureNakamaDB(ctx)
+		if err != nil {
+			return err
+		}
+		backup, err := os.Open(backupPath)
+		if err != nil {
+			return eris.Wrap(err, "Failed to open the backup")
+		}
+		defer backup.Close()
+		return c.restoreNakamaDB(ctx, upgradedDB, backup)
+	})
+}
+
+// dumpNakamaDBToFile writes a dump of the database to a file, which is only created when the dump succeeds.
+func (c *Client) dumpNakamaDBToFile(ctx context.Context, db service.Service, path string, sql bool) error {
+	tmp := path + ".partial"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return eris.Wrap(err, "Failed to create the dump file")
+	}
+	defer os.Remove(tmp)
+
+	err = c.execNakamaDB(ctx, db, pgDumpCmd(sql), ExecOptions{Stdout: file})
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = eris.Wrap(closeErr, "Failed to write the dump file")
+	}
+	if err != nil {
+		return err
+	}
+	return eris.Wrap(os.Rename(tmp, path), "Failed to write the dump file")
+}
+
+// pgDumpCmd returns the pg_dump command dumping the Nakama database, in the custom format or as an SQL script.
+func pgDumpCmd(sql bool) []string {
+	format := "--format=custom"
+	if sql {
+		format = "--format=plain"
+	}
+	return []string{"pg_dump", "--username", service.NakamaDBUser, "--dbname", service.NakamaDBName, format}
+}
+
+// restoreNakamaDB recreates the Nakama database and restores a dump into it.
+func (c *Client) restoreNakamaDB(ctx context.Context, db service.Service, r io.Reader) error {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(len(customDumpMagic))
+	if err != nil && !eris.Is(err, io.EOF) {
+		return eris.Wrap(err, "Failed to read the dump")
+	}
+
+	// Restore into an empty database, so the dump doesn't clash with the tables Nakama created
+	for _, cmd := range [][]string{
+		{"dropdb", "--username", service.NakamaDBUser, "--if-exists", service.NakamaDBName},
+		{"createdb", "--username", service.NakamaDBUser, service.NakamaDBName},
+	} {
+		if err := c.execNakamaDB(ctx, db, cmd, ExecOptions{}); err != nil {
+			return err
+		}
+	}
+
+	cmd := []string{"psql", "--username", service.NakamaDBUser, "--dbname", service.NakamaDBName, "--quiet",
+		"--set", "ON_ERROR_STOP=1"}
+	if string(magic) == customDumpMagic {
+		cmd = []string{"pg_restore", "--username", service.NakamaDBUser, "--dbname", service.NakamaDBName,
+			"--no-owner", "--exit-on-error"}
+	}
+	return c.execNakamaDB(ctx, db, cmd, ExecOptions{Stdin: reader})
+}
+
+// execNakamaDB runs a command in the container of the Nakama database, failing with its error output when
+// it exits with an error.
+func (c *Client) execNakamaDB(ctx context.Context, db service.Service, cmd []string, opts ExecOptions) error {
+	var stderr bytes.Buffer
+	if opts.Stderr == nil {
+		opts.Stderr = &stderr
+	}
+	code, err := c.ExecWithOptions(ctx, db.Name, cmd, opts)
+	if err != nil {
+		return err
+	}
+	if code == 0 {
+		return nil
+	}
+	if output := strings.TrimSpace(stderr.String()); output != "" {
+		return eris.Errorf("%s failed with exit code %d: %s", cmd[0], code, output)
+	}
+	return eris.Errorf("%s failed with exit code %d", cmd[0], code)
+}
+
+// ensureNakamaDB starts the Nakama database when it is not running, creating it with the image of
+// world.toml when it has no container, and waits until it accepts connections.
+func (c *Client) ensureNakamaDB(ctx context.Context) (service.Service, error) {
+	db := service.NakamaDB(c.cfg)
+	running, err := c.containerRunning(ctx, db.Name)
+	if err != nil || running {
+		return db, err
+	}
+
+	exists, err := c.containerExists(ctx, db.Name)
+	if err != nil {
+		return db, err
+	}
+	if !exists {
+		if err := c.createNetworkIfNotExists(ctx, c.cfg.DockerEnv["CARDINAL_NAMESPACE"]); err != nil {
+			return db, eris.Wrap(err, "Failed to create network")
+		}
+		if err := c.processVolumes(ctx, CREATE, db); err != nil {
+			return db, eris.Wrap(err, "Failed to create volume")
+		}
+		if err := c.pullImages(ctx, db); err != nil {
+			return db, eris.Wrap(err, "Failed to pull images")
+		}
+	}
+
+	printer.Infof("Starting %s\n", db.Name)
+	if err := c.startContainer(ctx, db); err != nil {
+		return db, eris.Wrapf(err, "Failed to start %s", db.Name)
+	}
+	return db, c.WaitReady(ctx, DefaultReadyTimeout, db)
+}
+
+// withNakamaStopped runs fn with the Nakama container stopped, and starts it again if it was running, whether fn
+// fails or not.
+func (c *Client) withNakamaStopped(ctx context.Context, fn func() error) (err error) {
+	nakama := service.Nakama(c.cfg)
+	running, err := c.containerRunning(ctx, nakama.Name)
+	if err != nil {
+		return err
+	}
+	if running {
+		printer.Infof("Stopping %s\n", nakama.Name)
+		if err := c.stopContainer(ctx, nakama.Name); err != nil {
+			return err
+		}
+		defer func() {
+			printer.Infof("Starting %s\n", nakama.Name)
+			// Nakama is started again even when fn was interrupted
+			startErr := c.client.ContainerStart(context.WithoutCancel(ctx), nakama.Name, container.StartOptions{})
+			if startErr != nil {
+				err = errors.Join(err, eris.Wrapf(startErr, "Failed to start %s", nakama.Name))
+			}
+		}()
+	}
+
+	return fn()
+}
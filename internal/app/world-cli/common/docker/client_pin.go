@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+// BaseImageLockFile is the file next to world.toml recording the digests the base images are pinned to.
+// Commit it to make image builds reproducible, delete it to move to the latest base images.
+const BaseImageLockFile = "world.lock"
+
+type baseImageLock struct {
+	// Images maps an image reference to the digest it is pinned to
+	Images map[string]string `json:"images"`
+}
+
+// pinBaseImages replaces the base images of the given services with references pinned by digest.
+// Images without a digest in the lock file are resolved from their registry and added to it.
+// An image that can't be resolved is left unpinned.
+func (c *Client) pinBaseImages(ctx context.Context, dockerServices []service.Service) error {
+	lockPath := filepath.Join(c.cfg.RootDir, BaseImageLockFile)
+	lock, err := readBaseImageLock(lockPath)
+	if err != nil {
+		return err
+	}
+
+	updated := false
+	for i := range dockerServices {
+		dockerService := &dockerServices[i]
+		if !dockerService.NeedsBuild() || len(dockerService.BaseImages) == 0 {
+			continue
+		}
+
+		pinned := make(map[string]string, len(dockerService.BaseImages))
+		for arg, image := range dockerService.BaseImages {
+			digest, ok := lock.Images[image]
+			if !ok {
+				digest, err = c.resolveDigest(ctx, image)
+				if err != nil {
+					printer.Notificationf("Failed to pin %s by digest, using the tag: %v\n", image, err)
+					pinned[arg] = image
+					continue
+				}
+				lock.Images[image] = digest
+				updated = true
+			}
+			pinned[arg] = image + "@" + digest
+		}
+		dockerService.BaseImages = pinned
+
+		// Pull the pinned images instead of the tags
+		for j, dependency := range dockerService.Dependencies {
+			if digest, ok := lock.Images[dependency.Image]; ok {
+				dockerService.Dependencies[j].Image = dependency.Image + "@" + digest
+			}
+		}
+	}
+
+	if updated {
+		if err := writeBaseImageLock(lockPath, lock); err != nil {
+			logger.Warnf("Failed to write %s: %v", lockPath, err)
+		} else {
+			printer.Infof("Pinned the base images by digest in %s\n", lockPath)
+		}
+	}
+	return nil
+}
+
+func (c *Client) resolveDigest(ctx context.Context, image string) (string, error) {
+	if strings.Contains(image, "@") {
+		return "", eris.Errorf("%s is already pinned", image)
+	}
+	info, err := c.client.DistributionInspect(ctx, image, "")
+	if err != nil {
+		return "", eris.Wrapf(err, "Failed to inspect %s", image)
+	}
+	return info.Descriptor.Digest.String(), nil
+}
+
+func readBaseImageLock(path string) (*baseImageLock, error) {
+	lock := &baseImageLock{Images: make(map[string]string)}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	} else if err != nil {
+		return nil, eris.Wrapf(err, "Failed to read %s", path)
+	}
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, eris.Wrapf(err, "Failed to parse %s", path)
+	}
+	if lock.Images == nil {
+		lock.Images = make(map[string]string)
+	}
+	return lock, nil
+}
+
+func writeBaseImageLock(path string, lock *baseImageLock) error {
+	content, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644) //nolint:gosec // the lock file is meant to be committed
+}
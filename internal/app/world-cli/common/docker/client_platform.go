@@ -0,0 +1,235 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+)
+
+// binfmtDir lists the binary formats the Linux kernel can run through an emulator.
+const binfmtDir = "/proc/sys/fs/binfmt_misc"
+
+var ErrMultiPlatformRequiresBuildKit = eris.New(
+	"building for other platforms requires BuildKit, enable it with USE_DOCKER_BUILDKIT=1")
+
+// daemonArchitectures maps the architectures reported by the Docker daemon to OCI architectures.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var daemonArchitectures = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armv7l":  "arm",
+	"i386":    "386",
+	"i686":    "386",
+}
+
+// emulatorNames maps an OCI architecture to the names of the binfmt_misc entries able to run it.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var emulatorNames = map[string][]string{
+	"amd64":   {"qemu-x86_64", "rosetta"},
+	"arm64":   {"qemu-aarch64"},
+	"arm":     {"qemu-arm"},
+	"386":     {"qemu-i386"},
+	"ppc64le": {"qemu-ppc64le"},
+	"s390x":   {"qemu-s390x"},
+	"riscv64": {"qemu-riscv64"},
+}
+
+// checkPlatformSupport returns an error when the Docker daemon can't build the Cardinal image for the
+// platforms of the [build] section, so the build fails before any work is done.
+func (c *Client) checkPlatformSupport(ctx context.Context) error {
+	platforms := c.cfg.CardinalBuild.Platforms
+	if len(platforms) == 0 {
+		return nil
+	}
+	if !service.BuildkitSupport {
+		return ErrMultiPlatformRequiresBuildKit
+	}
+
+	info, err := c.client.Info(ctx)
+	if err != nil {
+		return eris.Wrap(err, "Failed to get Docker daemon info")
+	}
+
+	hostArch := info.Architecture
+	if arch, ok := daemonArchitectures[hostArch]; ok {
+		hostArch = arch
+	}
+	// Docker Desktop ships its own emulators, and the emulators of a remote daemon can't be checked
+	if strings.Contains(info.OperatingSystem, "Docker Desktop") || !c.isLocalDaemon() {
+		return nil
+	}
+	for _, platform := range platforms {
+		arch := strings.Split(platform, "/")[1]
+		if arch == hostArch || canEmulate(arch) {
+			continue
+		}
+		return eris.Errorf("the Docker daemon runs on %s and can't build for %s without emulation, install "+
+			"the QEMU emulators with: docker run --privileged --rm tonistiigi/binfmt --install %s",
+			hostArch, platform, arch)
+	}
+	return nil
+}
+
+// buildPlatformImages builds the image once for every platform of the [build] section, as the build endpoint
+// of the Docker Engine only builds for one platform at a time. The builds run one after the other and their
+// output is read as one. The image of every platform gets the tags of the build suffixed with its platform, see
+// platformTag, and the tags themselves go to the image of the platform of the daemon, so that the stack runs an
+// image the daemon can run. Pushing assembles the manifest list of the images.
+func (c *Client) buildPlatformImages(ctx context.Context, buildContext []byte,
+	buildOptions build.ImageBuildOptions) (io.ReadCloser, error) {
+	platforms := c.cfg.CardinalBuild.Platforms
+	local, err := c.localPlatform(ctx, platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	// Label the images with the build, so the images of the other platforms are listed and pruned with the image
+	// of the daemon platform
+	buildID := strconv.FormatInt(time.Now().UnixNano(), 36) //nolint:mnd // base 36
+	builds := make([]func() (io.ReadCloser, error), 0, len(platforms))
+	for _, platform := range platforms {
+		options := buildOptions
+		options.Platform = platform
+		options.Labels = maps.Clone(buildOptions.Labels)
+		if options.Labels == nil {
+			options.Labels = make(map[string]string)
+		}
+		if platform == local {
+			options.Labels[LabelBuild] = buildID
+		} else {
+			options.Labels[LabelParent] = buildID
+		}
+		options.Tags = make([]string, 0, len(buildOptions.Tags))
+		for _, tag := range buildOptions.Tags {
+			options.Tags = append(options.Tags, platformTag(tag, platform))
+		}
+		builds = append(builds, func() (io.ReadCloser, error) {
+			resp, err := c.client.ImageBuild(ctx, bytes.NewReader(buildContext), options)
+			if err != nil {
+				return nil, eris.Wrapf(err, "Failed to build image for %s", platform)
+			}
+			return resp.Body, nil
+		})
+	}
+
+	// Start the first build now, so that its errors are reported like those of a single platform build
+	first, err := builds[0]()
+	if err != nil {
+		return nil, err
+	}
+	return &platformBuildsBody{current: first, next: builds[1:], done: func() error {
+		for _, tag := range buildOptions.Tags {
+			if err := c.client.ImageTag(ctx, platformTag(tag, local), tag); err != nil {
+				return eris.Wrapf(err, "Failed to tag the %s image as %s", local, tag)
+			}
+		}
+		return nil
+	}}, nil
+}
+
+// multiPlatforms returns the platforms of the image of the service when it is built for several of them.
+func (c *Client) multiPlatforms(dockerService service.Service) []string {
+	if platforms := c.cfg.CardinalBuild.Platforms; len(platforms) > 1 && dockerService.Dockerfile != "" {
+		return platforms
+	}
+	return nil
+}
+
+// platformBuildsBody reads the output of builds one after the other, starting a build once the output of the
+// previous one is read, and calls done after the last one.
+type platformBuildsBody struct {
+	current io.ReadCloser
+	next    []func() (io.ReadCloser, error)
+	done    func() error
+}
+
+func (b *platformBuildsBody) Read(p []byte) (int, error) {
+	for {
+		n, err := b.current.Read(p)
+		if !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		// The end of a build isn't the end of the output, the next call reads the end again
+		if n > 0 {
+			return n, nil
+		}
+		if err := b.current.Close(); err != nil {
+			return 0, err
+		}
+		if len(b.next) == 0 {
+			b.current = io.NopCloser(bytes.NewReader(nil))
+			if b.done != nil {
+				done := b.done
+				b.done = nil
+				if err := done(); err != nil {
+					return 0, err
+				}
+			}
+			return 0, io.EOF
+		}
+		body, err := b.next[0]()
+		if err != nil {
+			return 0, err
+		}
+		b.current, b.next = body, b.next[1:]
+	}
+}
+
+func (b *platformBuildsBody) Close() error {
+	return b.current.Close()
+}
+
+// localPlatform returns the platform of the daemon among the platforms, or the first one.
+func (c *Client) localPlatform(ctx context.Context, platforms []string) (string, error) {
+	info, err := c.client.Info(ctx)
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to get Docker daemon info")
+	}
+	arch := info.Architecture
+	if mapped, ok := daemonArchitectures[arch]; ok {
+		arch = mapped
+	}
+	for _, platform := range platforms {
+		if strings.HasPrefix(platform+"/", info.OSType+"/"+arch+"/") {
+			return platform, nil
+		}
+	}
+	return platforms[0], nil
+}
+
+func (c *Client) isLocalDaemon() bool {
+	return runtime.GOOS == "linux" && strings.HasPrefix(c.client.DaemonHost(), "unix://")
+}
+
+// canEmulate returns true when the kernel has an emulator registered for the given architecture.
+func canEmulate(arch string) bool {
+	for _, name := range emulatorNames[arch] {
+		if _, err := os.Stat(filepath.Join(binfmtDir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapPlatformError adds a hint to build errors caused by running binaries of another architecture.
+func wrapPlatformError(err error) error {
+	if err == nil || !strings.Contains(err.Error(), "exec format error") {
+		return err
+	}
+	return eris.Wrap(err, "the build ran a binary of another architecture, install the QEMU emulators with: "+
+		"docker run --privileged --rm tonistiigi/binfmt --install all")
+}
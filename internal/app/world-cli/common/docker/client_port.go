@@ -0,0 +1,188 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/go-connections/nat"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+)
+
+const maxPort = 65535
+
+var ErrPortConflict = eris.New("host ports are not available")
+
+// PortConflict is a host port requested by a service that cannot be bound.
+type PortConflict struct {
+	Service string
+	Port    service.PublishedPort
+	// Reason explains why the port can't be used
+	Reason string
+	// Remapped is the free host port picked by AssignFreePorts, zero if the port was not remapped
+	Remapped int
+}
+
+func (p PortConflict) String() string {
+	return fmt.Sprintf("%s: host port %d (%s, [ports] %s) %s",
+		p.Service, p.Port.Host, p.Port.Label, p.Port.Key, p.Reason)
+}
+
+// CheckPorts returns the host ports of the given services that cannot be bound.
+// Ports held by containers of the same stack that are already running are not reported.
+func (c *Client) CheckPorts(ctx context.Context, serviceBuilders ...service.Builder) ([]PortConflict, error) {
+	dockerServices := make([]service.Service, 0, len(serviceBuilders))
+	for _, sb := range serviceBuilders {
+		dockerServices = append(dockerServices, sb(c.cfg))
+	}
+	return c.checkPorts(ctx, dockerServices)
+}
+
+// checkPorts returns the host ports of the given services that cannot be bound.
+func (c *Client) checkPorts(ctx context.Context, dockerServices []service.Service) ([]PortConflict, error) {
+	if err := service.ValidatePorts(c.cfg); err != nil {
+		return nil, err
+	}
+
+	conflicts := make([]PortConflict, 0)
+	requested := make(map[int]string)
+	for _, dockerService := range dockerServices {
+		running, err := c.containerRunning(ctx, dockerService.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, port := range dockerService.Ports {
+			if owner, ok := requested[port.Host]; ok {
+				conflicts = append(conflicts, PortConflict{
+					Service: dockerService.Name,
+					Port:    port,
+					Reason:  "is also requested by " + owner,
+				})
+				continue
+			}
+			requested[port.Host] = dockerService.Name
+
+			if !running && !common.IsPortAvailable(port.Host) {
+				conflicts = append(conflicts, PortConflict{
+					Service: dockerService.Name,
+					Port:    port,
+					Reason:  "is already in use",
+				})
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// AssignFreePorts moves every conflicting host port of the given services to the next free port.
+// The new ports are written to the [ports] overrides of the config so the service builders pick them up.
+func (c *Client) AssignFreePorts(ctx context.Context, serviceBuilders ...service.Builder) ([]PortConflict, error) {
+	conflicts, err := c.CheckPorts(ctx, serviceBuilders...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Host ports that are taken by the stack itself must not be picked again
+	taken := make(map[int]bool)
+	for _, sb := range serviceBuilders {
+		for _, port := range sb(c.cfg).Ports {
+			taken[port.Host] = true
+		}
+	}
+
+	if c.cfg.Ports == nil {
+		c.cfg.Ports = make(map[string]int)
+	}
+	for i, conflict := range conflicts {
+		port, err := findFreePort(conflict.Port.Host+1, taken)
+		if err != nil {
+			return nil, eris.Wrapf(err, "Failed to find a free port for %s", conflict.Port.Label)
+		}
+		taken[port] = true
+		c.cfg.Ports[conflict.Port.Key] = port
+		conflicts[i].Remapped = port
+	}
+
+	return conflicts, nil
+}
+
+func (c *Client) containerRunning(ctx context.Context, containerName string) (bool, error) {
+	info, err := c.client.ContainerInspect(ctx, containerName)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, eris.Wrapf(err, "Failed to inspect container %s", containerName)
+	}
+
+	return info.State != nil && info.State.Running, nil
+}
+
+// PublishedPort returns the host port a running container publishes the given container port on, or zero
+// when the container is not running or doesn't publish that port.
+func (c *Client) PublishedPort(ctx context.Context, containerName string, containerPort int) (int, error) {
+	info, err := c.client.ContainerInspect(ctx, containerName)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, eris.Wrapf(err, "Failed to inspect container %s", containerName)
+	}
+	if info.State == nil || !info.State.Running || info.NetworkSettings == nil {
+		return 0, nil
+	}
+	for _, binding := range info.NetworkSettings.Ports[nat.Port(fmt.Sprintf("%d/tcp", containerPort))] {
+		if port, err := strconv.Atoi(binding.HostPort); err == nil {
+			return port, nil
+		}
+	}
+	return 0, nil
+}
+
+// VerifyPorts returns an error describing every port conflict of the given services.
+func (c *Client) VerifyPorts(ctx context.Context, serviceBuilders ...service.Builder) error {
+	dockerServices := make([]service.Service, 0, len(serviceBuilders))
+	for _, sb := range serviceBuilders {
+		dockerServices = append(dockerServices, sb(c.cfg))
+	}
+	return c.verifyPorts(ctx, dockerServices)
+}
+
+// verifyPorts returns an error describing every port conflict of the given services.
+func (c *Client) verifyPorts(ctx context.Context, dockerServices []service.Service) error {
+	conflicts, err := c.checkPorts(ctx, dockerServices)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		lines = append(lines, "  - "+conflict.String())
+	}
+	return eris.Wrapf(ErrPortConflict,
+		"\n%s\nFree these ports, change them in the [ports] section of world.toml, or start with --auto-ports",
+		strings.Join(lines, "\n"))
+}
+
+func findFreePort(start int, taken map[int]bool) (int, error) {
+	for start <= maxPort {
+		port, err := common.FindUnusedPort(start, maxPort)
+		if err != nil {
+			return 0, err
+		}
+		if !taken[port] {
+			return port, nil
+		}
+		start = port + 1
+	}
+	return 0, eris.Errorf("no available port above %d", start)
+}
@@ -0,0 +1,353 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/rotisserie/eris"
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+	"pkg.world.dev/world-cli/internal/pkg/tea/style"
+)
+
+// PushOptions are the destinations of the built images.
+type PushOptions struct {
+	// Targets are the references the images are pushed to. A reference without a tag gets every tag of
+	// the build: the latest alias, the git commit and the version.
+	Targets []string
+	// Auth is the encoded registry auth to use instead of the credentials of the Docker config file
+	Auth string
+	// ResultFile is the path of a JSON file recording the digest of every pushed reference
+	ResultFile string
+}
+
+// PushResult is a pushed reference and its digest.
+type PushResult struct {
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+	// Pinned is the reference pinned by digest, for deployments that must run exactly this image
+	Pinned string `json:"pinned"`
+}
+
+// pushResultFile is the content of PushOptions.ResultFile.
+type pushResultFile struct {
+	Images []PushResult `json:"images"`
+}
+
+// pushImages tags the built images with the push targets and pushes them. The registry credentials are
+// read from the Docker config file and its credential helpers, unless push.Auth is set.
+func (c *Client) pushImages(ctx context.Context, push PushOptions, services ...service.Service) error {
+	var (
+		mu      sync.Mutex
+		results []PushResult
+		wg      sync.WaitGroup
+	)
+
+	// Resolve and tag every image before pushing any, so an error leaves no push running
+	pushes := make([]imagePush, 0, len(services))
+	for _, dockerService := range services {
+		if !dockerService.NeedsBuild() {
+			continue
+		}
+		imgPush, err := c.tagPushReferences(ctx, dockerService, push.Targets)
+		if err != nil {
+			return err
+		}
+		pushes = append(pushes, imgPush)
+	}
+
+	p := mpb.New(mpb.WithWaitGroup(&wg))
+
+	// Channel to collect errors from the goroutines
+	errChan := make(chan error, len(pushes))
+
+	for _, imgPush := range pushes {
+		refs, platforms, pushed := imgPush.refs, imgPush.platforms, imgPush.pushed
+		bars := make([]*mpb.Bar, 0, len(pushed))
+		for _, ref := range pushed {
+			bars = append(bars, p.AddBar(100,
+				mpb.PrependDecorators(
+					decor.Name(fmt.Sprintf("%s %s: ", style.ForegroundPrint("Pushing", "2"), ref)),
+					decor.Percentage(decor.WCSyncSpace),
+				),
+			))
+		}
+
+		// Push the tags of an image one after the other, so the shared layers are only uploaded once
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addResult := func(ref string, digest string) {
+				mu.Lock()
+				defer mu.Unlock()
+				results = append(results, PushResult{
+					Reference: ref,
+					Digest:    digest,
+					Pinned:    imageRepository(ref) + "@" + digest,
+				})
+			}
+			i := 0
+			for _, ref := range refs {
+				images := make([]platformImage, 0, len(platforms))
+				for range max(len(platforms), 1) {
+					digest, err := c.pushImage(ctx, pushed[i], push.Auth, bars[i])
+					if err != nil {
+						// Stop the remaining progress bars without clearing
+						for _, bar := range bars[i:] {
+							bar.Abort(false)
+						}
+						errChan <- err
+						return
+					}
+					addResult(pushed[i], digest)
+					if len(platforms) > 0 {
+						images = append(images, platformImage{platform: platforms[len(images)], ref: pushed[i]})
+					}
+					i++
+				}
+				if len(platforms) == 0 {
+					continue
+				}
+				digest, err := c.pushManifestList(ctx, ref, images, push.Auth)
+				if err != nil {
+					for _, bar := range bars[i:] {
+						bar.Abort(false)
+					}
+					errChan <- err
+					return
+				}
+				addResult(ref, digest)
+				// The platform tags of the push reference were only needed to push the manifest list
+				c.untagImages(ctx, images)
+			}
+		}()
+	}
+
+	// Wait for all progress bars to complete
+	p.Wait()
+
+	// Close the error channel and check for errors
+	close(errChan)
+	errs := make([]error, 0)
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return eris.New(fmt.Sprintf("Errors: %v", errs))
+	}
+
+	slices.SortFunc(results, func(a, b PushResult) int { return strings.Compare(a.Reference, b.Reference) })
+	for _, result := range results {
+		printer.Infof("Pushed %s\n", result.Pinned)
+	}
+
+	if push.ResultFile != "" {
+		content, err := json.MarshalIndent(pushResultFile{Images: results}, "", "  ")
+		if err != nil {
+			return eris.Wrap(err, "Failed to encode the push result")
+		}
+		if err := os.WriteFile(push.ResultFile, append(content, '\n'), 0o644); err != nil { //nolint:gosec // read by CI
+			return eris.Wrapf(err, "Failed to write %s", push.ResultFile)
+		}
+		printer.Infof("Wrote the pushed digests to %s\n", push.ResultFile)
+	}
+
+	return nil
+}
+
+// imagePush is an image tagged with its push references.
+type imagePush struct {
+	// refs are the references the image is pushed to
+	refs []string
+	// platforms are the platforms of a multi-platform build, empty for a single image
+	platforms []string
+	// pushed are the tags pushed, the refs or, for a multi-platform build, the platform tags of every ref
+	pushed []string
+}
+
+// tagPushReferences checks that the image of the service exists and tags it with its push references.
+func (c *Client) tagPushReferences(ctx context.Context, dockerService service.Service,
+	targets []string) (imagePush, error) {
+	if _, err := c.client.ImageInspect(ctx, dockerService.Image); err != nil {
+		return imagePush{}, eris.Wrapf(err, "Error inspecting image %s for service %s", dockerService.Image,
+			dockerService.Name)
+	}
+
+	refs, err := c.pushReferences(dockerService, targets)
+	if err != nil {
+		return imagePush{}, err
+	}
+	// The images of a multi-platform build are pushed one platform at a time, then their manifest list
+	imgPush := imagePush{refs: refs, platforms: c.multiPlatforms(dockerService)}
+	for _, ref := range refs {
+		if len(imgPush.platforms) == 0 {
+			if err := c.client.ImageTag(ctx, dockerService.Image, ref); err != nil {
+				return imagePush{}, eris.Wrapf(err, "Failed to tag %s as %s", dockerService.Image, ref)
+			}
+			imgPush.pushed = append(imgPush.pushed, ref)
+			continue
+		}
+		for _, platform := range imgPush.platforms {
+			local, platformRef := platformTag(dockerService.Image, platform), platformTag(ref, platform)
+			if err := c.client.ImageTag(ctx, local, platformRef); err != nil {
+				return imagePush{}, eris.Wrapf(err, "Failed to tag %s as %s", local, platformRef)
+			}
+			imgPush.pushed = append(imgPush.pushed, platformRef)
+		}
+	}
+	return imgPush, nil
+}
+
+// pushReferences returns the references the image of the given service is pushed to.
+func (c *Client) pushReferences(dockerService service.Service, targets []string) ([]string, error) {
+	localTags, _ := c.imageTags(dockerService)
+	refs := make([]string, 0, len(targets)*len(localTags))
+	for _, target := range targets {
+		named, err := reference.ParseNormalizedNamed(target)
+		if err != nil {
+			return nil, eris.Wrapf(err, "invalid push reference %q", target)
+		}
+		if _, ok := named.(reference.Digested); ok {
+			return nil, eris.Errorf("can't push to %q, a reference pinned by digest", target)
+		}
+		if _, ok := named.(reference.Tagged); ok {
+			refs = append(refs, reference.FamiliarString(named))
+			continue
+		}
+		for _, localTag := range localTags {
+			refs = append(refs, reference.FamiliarName(named)+":"+imageTag(localTag))
+		}
+	}
+
+	// Remove duplicates while keeping the order of the targets
+	seen := make(map[string]bool, len(refs))
+	return slices.DeleteFunc(refs, func(ref string) bool {
+		if seen[ref] {
+			return true
+		}
+		seen[ref] = true
+		return false
+	}), nil
+}
+
+// imageTag returns the tag of an image name, which is latest when the name has no tag.
+func imageTag(imageName string) string {
+	if named, err := reference.ParseNormalizedNamed(imageName); err == nil {
+		if tagged, ok := named.(reference.Tagged); ok {
+			return tagged.Tag()
+		}
+	}
+	return "latest"
+}
+
+// untagImages removes the tags of the pushed platform images from the local store, the images stay tagged with
+// the tags of their build.
+func (c *Client) untagImages(ctx context.Context, images []platformImage) {
+	for _, img := range images {
+		if _, err := c.client.ImageRemove(ctx, img.ref, image.RemoveOptions{}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to remove the tag %s", img.ref), err)
+		}
+	}
+}
+
+// pushManifestList pushes the manifest list of the images of a multi-platform build to ref.
+func (c *Client) pushManifestList(ctx context.Context, ref string, images []platformImage,
+	auth string) (string, error) {
+	auth, err := pushAuth(ref, auth)
+	if err != nil {
+		return "", err
+	}
+	return pushManifestList(ctx, ref, images, auth)
+}
+
+// pushAuth returns the registry auth pushing ref, the given one or the credentials of the registry.
+func pushAuth(ref string, auth string) (string, error) {
+	if auth != "" {
+		return auth, nil
+	}
+	return RegistryAuth(ref)
+}
+
+// pushImage pushes a reference and returns the digest reported by the registry.
+func (c *Client) pushImage(ctx context.Context, ref string, auth string, bar *mpb.Bar) (string, error) {
+	auth, err := pushAuth(ref, auth)
+	if err != nil {
+		return "", err
+	}
+
+	responseBody, err := c.client.ImagePush(ctx, ref, image.PushOptions{RegistryAuth: auth})
+	if err != nil {
+		return "", eris.Wrapf(err, "error pushing image %s", ref)
+	}
+	defer responseBody.Close()
+
+	// Process each event and update the progress bar
+	decoder := json.NewDecoder(responseBody)
+	var (
+		current int
+		digest  string
+	)
+	for decoder.More() {
+		if ctx.Err() != nil {
+			return "", eris.Errorf("Pushing image %s was canceled", ref)
+		}
+
+		var event struct {
+			Error       string `json:"error"`
+			ErrorDetail *struct {
+				Message string `json:"message"`
+			} `json:"errorDetail"`
+			ProgressDetail struct {
+				Current float64 `json:"current"`
+				Total   float64 `json:"total"`
+			} `json:"progressDetail"`
+			Aux *struct {
+				Digest string `json:"Digest"`
+			} `json:"aux"`
+		}
+		if err := decoder.Decode(&event); err != nil {
+			return "", eris.Wrapf(err, "Error decoding event for %s", ref)
+		}
+
+		// Check for errorDetail and error fields
+		if event.ErrorDetail != nil && event.ErrorDetail.Message != "" {
+			return "", eris.New(event.ErrorDetail.Message)
+		} else if event.Error != "" {
+			return "", eris.New(event.Error)
+		}
+
+		// The digest of the pushed manifest comes with the last event
+		if event.Aux != nil && event.Aux.Digest != "" {
+			digest = event.Aux.Digest
+		}
+
+		// Handle progress updates
+		if total := event.ProgressDetail.Total; total > 0 {
+			calculatedCurrent := int(event.ProgressDetail.Current * 100 / total)
+			if calculatedCurrent > current {
+				bar.SetCurrent(int64(calculatedCurrent))
+				current = calculatedCurrent
+			}
+		}
+	}
+
+	if digest == "" {
+		return "", eris.Errorf("the registry didn't report the digest of %s", ref)
+	}
+
+	// Finish the progress bar
+	// Handle if the current and total is not available in the response body
+	// Usually, because the layers are already in the registry
+	bar.SetCurrent(100)
+	return digest, nil
+}
@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+const (
+	// DefaultReadyTimeout is how long a start waits for the stack to be ready when no timeout is set
+	DefaultReadyTimeout = 5 * time.Minute
+	readyPollInterval   = 500 * time.Millisecond
+)
+
+// readiness is the readiness of a container.
+type readiness struct {
+	ready bool
+	// failure is why the container will not become ready without help, empty while it may still get ready
+	failure string
+	// pending describes what the container is waiting for
+	pending string
+}
+
+// containerReadiness returns the readiness of a container: running, and healthy if it has a healthcheck.
+func containerReadiness(state *container.State) readiness {
+	switch {
+	case state == nil:
+		return readiness{pending: "not created"}
+	case state.Restarting:
+		return readiness{pending: "restarting after " + exitReason(state)}
+	case !state.Running && (state.Status == container.StateExited || state.Status == container.StateDead):
+		return readiness{failure: exitReason(state)}
+	case !state.Running:
+		return readiness{pending: string(state.Status)}
+	case state.Health == nil:
+		return readiness{ready: true}
+	}
+
+	switch state.Health.Status {
+	case container.Healthy:
+		return readiness{ready: true}
+	case container.Unhealthy:
+		failure := "unhealthy"
+		if probes := state.Health.Log; len(probes) > 0 {
+			if output := strings.TrimSpace(probes[len(probes)-1].Output); output != "" {
+				failure += ": " + output
+			}
+		}
+		return readiness{failure: failure}
+	default:
+		return readiness{pending: "health " + string(state.Health.Status)}
+	}
+}
+
+// WaitReady waits until every container of the services is running, and healthy if it has a healthcheck.
+// It fails as soon as a container exits or is unhealthy, and after the timeout.
+func (c *Client) WaitReady(ctx context.Context, timeout time.Duration, services ...service.Service) error {
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pending := make([]string, 0)
+		for _, dockerService := range services {
+			info, err := c.client.ContainerInspect(ctx, dockerService.Name)
+			if err != nil && !cerrdefs.IsNotFound(err) && ctx.Err() == nil {
+				return eris.Wrapf(err, "Failed to inspect container %s", dockerService.Name)
+			}
+			state := readiness{pending: "not created"}
+			if err == nil {
+				state = containerReadiness(info.State)
+			}
+			if state.failure != "" {
+				return eris.Errorf("%s is not ready: %s, check its logs with: docker logs %s",
+					dockerService.Name, state.failure, dockerService.Name)
+			}
+			if !state.ready {
+				pending = append(pending, fmt.Sprintf("%s (%s)", dockerService.Name, state.pending))
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if eris.Is(ctx.Err(), context.DeadlineExceeded) {
+				return eris.Errorf("Timed out after %s waiting for %s", timeout, strings.Join(pending, ", "))
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitReadyAndReport waits for the stack to be ready and prints how long it took and its endpoints.
+func (c *Client) waitReadyAndReport(ctx context.Context, startedAt time.Time,
+	services ...service.Service) error {
+	timeout := time.Duration(c.cfg.Timeout) * time.Second
+	if err := c.WaitReady(ctx, timeout, services...); err != nil {
+		return err
+	}
+
+	printer.NewLine(1)
+	printer.Successf("Stack ready in %.1fs\n", time.Since(startedAt).Seconds())
+	for _, dockerService := range services {
+		for _, port := range dockerService.Ports {
+			printer.Infof("  %s → localhost:%d\n", port.Label, port.Host)
+		}
+	}
+	printer.NewLine(1)
+	return nil
+}
@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"context"
+	"strings"
+
+	"github.com/distribution/reference"
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/rotisserie/eris"
+)
+
+// DockerHubServer is the key of the Docker Hub credentials in the Docker config file.
+const DockerHubServer = "https://index.docker.io/v1/"
+
+// RegistryServer returns the key of the credentials of the given registry in the Docker config file.
+// An empty server is Docker Hub.
+func RegistryServer(server string) string {
+	server = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://"), "/")
+	switch server {
+	case "", "docker.io", "index.docker.io", "index.docker.io/v1", "registry-1.docker.io":
+		return DockerHubServer
+	}
+	return server
+}
+
+// imageRegistryServer returns the key of the credentials of the registry hosting the given image.
+func imageRegistryServer(imageName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", eris.Wrapf(err, "invalid image reference %q", imageName)
+	}
+	return RegistryServer(reference.Domain(named)), nil
+}
+
+// RegistryAuth returns the encoded credentials of the registry hosting the given image, read from the
+// Docker config file or the credential helpers it configures, like docker push does. It returns an empty
+// string when there are no credentials for the registry.
+func RegistryAuth(imageName string) (string, error) {
+	server, err := imageRegistryServer(imageName)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := dockerconfig.Load(dockerconfig.Dir())
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to load the Docker config file")
+	}
+	authConfig, err := cfg.GetAuthConfig(server)
+	if err != nil {
+		return "", eris.Wrapf(err, "Failed to get the credentials of %s", server)
+	}
+	if authConfig.Username == "" && authConfig.IdentityToken == "" && authConfig.RegistryToken == "" {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      authConfig.Username,
+		Password:      authConfig.Password,
+		ServerAddress: server,
+		IdentityToken: authConfig.IdentityToken,
+		RegistryToken: authConfig.RegistryToken,
+	})
+}
+
+// Login checks the credentials with the registry and stores them like docker login does, in the credential
+// store of the Docker config file when one is configured, or in the file itself.
+func (c *Client) Login(ctx context.Context, server, username, password string) (string, error) {
+	server = RegistryServer(server)
+	resp, err := c.client.RegistryLogin(ctx, registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: server,
+	})
+	if err != nil {
+		return "", eris.Wrapf(err, "Failed to log in to %s", server)
+	}
+
+	authConfig := types.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: server,
+	}
+	// Store the token instead of the password when the registry issues one
+	if resp.IdentityToken != "" {
+		authConfig.Password = ""
+		authConfig.IdentityToken = resp.IdentityToken
+	}
+
+	cfg, err := dockerconfig.Load(dockerconfig.Dir())
+	if err != nil {
+		return "", eris.Wrap(err, "Failed to load the Docker config file")
+	}
+	if err := cfg.GetCredentialsStore(server).Store(authConfig); err != nil {
+		return "", eris.Wrapf(err, "Failed to store the credentials of %s", server)
+	}
+	return resp.Status, nil
+}
+
+// Logout removes the stored credentials of the registry, like docker logout does.
+func Logout(server string) error {
+	server = RegistryServer(server)
+	cfg, err := dockerconfig.Load(dockerconfig.Dir())
+	if err != nil {
+		return eris.Wrap(err, "Failed to load the Docker config file")
+	}
+	if err := cfg.GetCredentialsStore(server).Erase(server); err != nil {
+		return eris.Wrapf(err, "Failed to remove the credentials of %s", server)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+const (
+	// crashLoopRestarts is the number of restarts within crashLoopWindow that make a crash loop
+	crashLoopRestarts = 3
+	crashLoopWindow   = 2 * time.Minute
+	// restartPollInterval is how often the restart counts of the containers are checked
+	restartPollInterval = 2 * time.Second
+)
+
+// restartTracker detects the restarts of containers from their restart count, and crash loops when
+// a container restarts crashLoopRestarts times within crashLoopWindow.
+type restartTracker struct {
+	counts map[string]int
+	// restarts are the times the restarts were observed within the window, by container
+	restarts map[string][]time.Time
+	// looping are the containers whose crash loop was already reported
+	looping map[string]bool
+}
+
+func newRestartTracker() *restartTracker {
+	return &restartTracker{
+		counts:   make(map[string]int),
+		restarts: make(map[string][]time.Time),
+		looping:  make(map[string]bool),
+	}
+}
+
+// observe records the restart count of a container. It returns whether the container restarted since the
+// last observation, and whether a crash loop started, which is reported once until the container stays up
+// for a whole window.
+func (t *restartTracker) observe(name string, restartCount int, now time.Time) (bool, bool) {
+	previous, seen := t.counts[name]
+	t.counts[name] = restartCount
+
+	restarts := t.restarts[name]
+	for len(restarts) > 0 && now.Sub(restarts[0]) > crashLoopWindow {
+		restarts = restarts[1:]
+	}
+	restarted := seen && restartCount > previous
+	if restarted {
+		for range min(restartCount-previous, crashLoopRestarts) {
+			restarts = append(restarts, now)
+		}
+	}
+	t.restarts[name] = restarts
+
+	if len(restarts) == 0 {
+		t.looping[name] = false
+	}
+	if len(restarts) < crashLoopRestarts || t.looping[name] {
+		return restarted, false
+	}
+	t.looping[name] = true
+	return restarted, true
+}
+
+// watchRestarts reports the restarts and crash loops of the containers of the services in the log stream,
+// until the context is done.
+func (c *Client) watchRestarts(ctx context.Context, services ...service.Service) {
+	tracker := newRestartTracker()
+	ticker := time.NewTicker(restartPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, dockerService := range services {
+			info, err := c.client.ContainerInspect(ctx, dockerService.Name)
+			if err != nil || info.State == nil {
+				continue
+			}
+			restarted, crashLoop := tracker.observe(dockerService.Name, info.RestartCount, time.Now())
+			if restarted {
+				printer.Errorf("[%s] restarted after %s, %d restarts so far\n", dockerService.Name,
+					exitReason(info.State), info.RestartCount)
+			}
+			if crashLoop {
+				printer.Errorf("[%s] is crash looping: %d restarts in the last %s. %s\n", dockerService.Name,
+					crashLoopRestarts, crashLoopWindow, crashLoopHint(info.State))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// exitReason describes why the container last stopped.
+func exitReason(state *container.State) string {
+	if state.OOMKilled {
+		return "running out of memory"
+	}
+	return fmt.Sprintf("exiting with code %d", state.ExitCode)
+}
+
+// crashLoopHint suggests how to fix the crash loop of a container.
+func crashLoopHint(state *container.State) string {
+	if state.OOMKilled {
+		return "It exceeds its memory limit, raise memory in its [resources] section of world.toml"
+	}
+	return "Check its logs above"
+}
+
+// isCrashLooping returns true when the container is waiting to be restarted, or restarted crashLoopRestarts
+// times and its last start is within crashLoopWindow.
+func isCrashLooping(state *container.State, restartCount int, now time.Time) bool {
+	if state == nil {
+		return false
+	}
+	if state.Restarting {
+		return true
+	}
+	startedAt, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+	return err == nil && restartCount >= crashLoopRestarts && now.Sub(startedAt) < crashLoopWindow
+}
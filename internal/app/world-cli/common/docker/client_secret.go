@@ -0,0 +1,157 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/secrets/secretsprovider"
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+var ErrSSHRequiresBuildKit = eris.New("SSH agent forwarding requires BuildKit, enable it with USE_DOCKER_BUILDKIT=1")
+
+// githubTokenEnvVariables are the env variables holding the GitHub token for private modules, in order of preference.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var githubTokenEnvVariables = []string{"ARGUS_WEV2_GITHUB_TOKEN", "GITHUB_TOKEN"}
+
+func githubToken() string {
+	for _, env := range githubTokenEnvVariables {
+		if token := os.Getenv(env); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// netrcPath returns the path of the .netrc file of the user, or an empty string if there is none.
+func netrcPath() string {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// addBuildSecrets gives the build access to the credentials for private modules. With BuildKit, the GitHub
+// token and the .netrc file are passed as secrets and the SSH agent is forwarded through a build session,
+// so nothing is stored in the image. Services whose build doesn't fetch private modules get none of them. The
+// returned function ends the session once the build is done.
+func (c *Client) addBuildSecrets(ctx context.Context, dockerService service.Service,
+	buildOptions *build.ImageBuildOptions) (func(), error) {
+	if !dockerService.Secrets {
+		return func() {}, nil
+	}
+	if !service.BuildkitSupport {
+		return func() {}, c.addLegacyBuildSecrets(buildOptions)
+	}
+
+	// Gather the secrets before creating the session, so a failure doesn't leave it open
+	secrets := make(map[string][]byte)
+	if token := githubToken(); token != "" {
+		secrets[service.SecretGitHubToken] = []byte(token)
+	}
+	if path := netrcPath(); path != "" {
+		netrc, err := os.ReadFile(path)
+		if err != nil {
+			return nil, eris.Wrapf(err, "Failed to read %s", path)
+		}
+		secrets[service.SecretNetrc] = netrc
+	}
+
+	var agent session.Attachable
+	if c.cfg.CardinalBuild.SSH {
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			return nil, eris.New("SSH agent forwarding requires a running SSH agent, SSH_AUTH_SOCK is not set")
+		}
+		var err error
+		agent, err = sshprovider.NewSSHAgentProvider([]sshprovider.AgentConfig{{ID: "default"}})
+		if err != nil {
+			return nil, eris.Wrap(err, "Failed to forward the SSH agent")
+		}
+	}
+
+	sess, err := session.NewSession(ctx, "world-cli", "")
+	if err != nil {
+		return nil, eris.Wrap(err, "Failed to create build session")
+	}
+	sess.Allow(secretsprovider.FromMap(secrets))
+	if agent != nil {
+		sess.Allow(agent)
+	}
+
+	dialer := func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
+		return c.client.DialHijack(ctx, "/session", proto, meta)
+	}
+	go func() {
+		if err := sess.Run(ctx, dialer); err != nil {
+			logger.Error("Build session failed", err)
+		}
+	}()
+
+	if logger.VerboseMode {
+		logger.Printf("Started build session %s with %d secrets (ssh: %t)\r\n",
+			sess.ID(), len(secrets), c.cfg.CardinalBuild.SSH)
+	}
+
+	buildOptions.Version = build.BuilderBuildKit
+	buildOptions.SessionID = sess.ID()
+	return func() {
+		if err := sess.Close(); err != nil {
+			logger.Error("Failed to close build session", err)
+		}
+	}, nil
+}
+
+// addLegacyBuildSecrets passes the GitHub token as a build arg, which is only done when explicitly allowed.
+// Otherwise the build goes on without it, only private modules fail to download.
+func (c *Client) addLegacyBuildSecrets(buildOptions *build.ImageBuildOptions) error {
+	if c.cfg.CardinalBuild.SSH {
+		return ErrSSHRequiresBuildKit
+	}
+
+	token := githubToken()
+	if token == "" {
+		return nil
+	}
+	if !c.cfg.CardinalBuild.AllowInsecureSecrets {
+		printer.Notificationf("Building without the GitHub token, as BuildKit is not available and a build arg " +
+			"would store it in the image history. Enable BuildKit with USE_DOCKER_BUILDKIT=1, or pass it anyway " +
+			"with --insecure-build-secrets or allow_insecure_secrets in the [build] section of world.toml\n")
+		return nil
+	}
+
+	printer.Notificationf("Passing the GitHub token as a build arg, it will be stored in the image history\n")
+	if buildOptions.BuildArgs == nil {
+		buildOptions.BuildArgs = make(map[string]*string)
+	}
+	buildOptions.BuildArgs["GITHUB_TOKEN"] = &token
+	return nil
+}
+
+// sessionBody closes the build session once the build output has been read.
+type sessionBody struct {
+	io.ReadCloser
+	closeSession func()
+}
+
+func (b sessionBody) Close() error {
+	defer b.closeSession()
+	return b.ReadCloser.Close()
+}
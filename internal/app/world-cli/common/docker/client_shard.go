@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+)
+
+// Labels set on every container created by the World CLI.
+const (
+	LabelNamespace = "dev.world.cli.namespace"
+	LabelRootDir   = "dev.world.cli.root-dir"
+	LabelService   = "dev.world.cli.service"
+)
+
+// Shard is a game shard with at least one running container.
+type Shard struct {
+	Namespace string
+	RootDir   string
+	// Containers are the names of the running containers of the shard
+	Containers []string
+	// Ports are the host ports published by the running containers, keyed by container name
+	Ports map[string][]uint16
+	// Restarts are the restart counts of the containers that restarted, keyed by container name
+	Restarts map[string]int
+	// CrashLooping are the containers that keep restarting
+	CrashLooping []string
+}
+
+func (c *Client) containerLabels(dockerService service.Service) map[string]string {
+	labels := make(map[string]string, len(dockerService.Labels)+3)
+	maps.Copy(labels, dockerService.Labels)
+	labels[LabelNamespace] = c.cfg.DockerEnv["CARDINAL_NAMESPACE"]
+	labels[LabelRootDir] = c.cfg.RootDir
+	labels[LabelService] = dockerService.Name
+	return labels
+}
+
+// ListShards returns every shard that has running containers, sorted by namespace.
+func (c *Client) ListShards(ctx context.Context) ([]Shard, error) {
+	containers, err := c.client.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelNamespace)),
+	})
+	if err != nil {
+		return nil, eris.Wrap(err, "Failed to list containers")
+	}
+
+	shards := make(map[string]*Shard)
+	for _, ctr := range containers {
+		namespace := ctr.Labels[LabelNamespace]
+		shard, ok := shards[namespace]
+		if !ok {
+			shard = &Shard{
+				Namespace: namespace,
+				RootDir:   ctr.Labels[LabelRootDir],
+				Ports:     make(map[string][]uint16),
+				Restarts:  make(map[string]int),
+			}
+			shards[namespace] = shard
+		}
+
+		name := strings.TrimPrefix(ctr.Labels[LabelService], namespace+"-")
+		shard.Containers = append(shard.Containers, name)
+		if info, err := c.client.ContainerInspect(ctx, ctr.ID); err == nil {
+			if info.RestartCount > 0 {
+				shard.Restarts[name] = info.RestartCount
+			}
+			if isCrashLooping(info.State, info.RestartCount, time.Now()) {
+				shard.CrashLooping = append(shard.CrashLooping, name)
+			}
+		}
+		for _, port := range ctr.Ports {
+			if port.PublicPort != 0 && !slices.Contains(shard.Ports[name], port.PublicPort) {
+				shard.Ports[name] = append(shard.Ports[name], port.PublicPort)
+			}
+		}
+	}
+
+	result := make([]Shard, 0, len(shards))
+	for _, namespace := range slices.Sorted(maps.Keys(shards)) {
+		shard := shards[namespace]
+		slices.Sort(shard.Containers)
+		slices.Sort(shard.CrashLooping)
+		result = append(result, *shard)
+	}
+	return result, nil
+}
@@ -0,0 +1,250 @@
+package docker
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/teacmd"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+	"pkg.world.dev/world-cli/internal/pkg/printer"
+)
+
+// Labels set on every image built by the World CLI, along with LabelNamespace and LabelRootDir.
+const (
+	LabelRevision = "org.opencontainers.image.revision"
+	LabelVersion  = "org.opencontainers.image.version"
+	LabelDirty    = "dev.world.cli.dirty"
+	// LabelBuild is the id of a multi-platform build on the image of the daemon platform, and LabelParent the
+	// same id on the images of the other platforms, which are listed and pruned along with it
+	LabelBuild  = "dev.world.cli.build"
+	LabelParent = "dev.world.cli.parent"
+)
+
+// Image is an image built by the World CLI.
+type Image struct {
+	ID        string
+	Namespace string
+	// Service is the service the image was built for, e.g. cardinal or a service of the [services] section
+	Service string
+	// Tags are the tags of the image, empty once every tag moved to a newer build
+	Tags    []string
+	Size    int64
+	Created time.Time
+	// Revision is the git commit the image was built from, empty outside of a git repository
+	Revision string
+	// Dirty is true when the working tree had uncommitted changes
+	Dirty   bool
+	Version string
+	// Platforms are the images of the other platforms of a multi-platform build
+	Platforms []Image
+
+	build  string
+	parent string
+}
+
+// imageTags returns the tags and labels of an image built from the given service. The image is tagged
+// with its usual name, which is the latest alias, the git commit, and the version when one is set.
+func (c *Client) imageTags(dockerService service.Service) ([]string, map[string]string) {
+	tags := []string{dockerService.Image}
+	labels := map[string]string{
+		LabelNamespace: c.cfg.DockerEnv["CARDINAL_NAMESPACE"],
+		LabelRootDir:   c.cfg.RootDir,
+		LabelService:   dockerService.Name,
+	}
+	repository := imageRepository(dockerService.Image)
+
+	sha, dirty, err := teacmd.GitRevision(c.cfg.RootDir)
+	if err != nil {
+		if logger.VerboseMode {
+			logger.Printf("Not tagging %s with a git commit: %v\r\n", dockerService.Image, err)
+		}
+	} else {
+		tag := sha
+		if dirty {
+			tag += "-dirty"
+		}
+		tags = append(tags, repository+":"+tag)
+		labels[LabelRevision] = sha
+		labels[LabelDirty] = strconv.FormatBool(dirty)
+	}
+
+	if version := c.cfg.CardinalBuild.Version; version != "" {
+		// Build metadata of semantic versions is not allowed in a tag
+		tags = append(tags, repository+":"+strings.ReplaceAll(version, "+", "_"))
+		labels[LabelVersion] = version
+	}
+
+	return tags, labels
+}
+
+// imageRepository returns the image name without its tag.
+func imageRepository(imageName string) string {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		// Keep the name as is when it isn't a valid reference, the build reports the error
+		return imageName
+	}
+	return reference.FamiliarName(named)
+}
+
+// ListImages returns the images built by the World CLI, sorted by namespace and service and from newest to oldest.
+func (c *Client) ListImages(ctx context.Context) ([]Image, error) {
+	summaries, err := c.client.ImageList(ctx, image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelNamespace)),
+	})
+	if err != nil {
+		return nil, eris.Wrap(err, "Failed to list images")
+	}
+
+	images := make([]Image, 0, len(summaries))
+	for _, summary := range summaries {
+		tags := slices.DeleteFunc(slices.Clone(summary.RepoTags), func(tag string) bool {
+			return tag == "<none>:<none>"
+		})
+		slices.Sort(tags)
+		images = append(images, Image{
+			ID:        summary.ID,
+			Namespace: summary.Labels[LabelNamespace],
+			Service:   summary.Labels[LabelService],
+			Tags:      tags,
+			Size:      summary.Size,
+			Created:   time.Unix(summary.Created, 0),
+			Revision:  summary.Labels[LabelRevision],
+			Dirty:     summary.Labels[LabelDirty] == "true",
+			Version:   summary.Labels[LabelVersion],
+			build:     summary.Labels[LabelBuild],
+			parent:    summary.Labels[LabelParent],
+		})
+	}
+	images = foldPlatformImages(images)
+
+	slices.SortStableFunc(images, func(a, b Image) int {
+		if a.Namespace != b.Namespace {
+			return strings.Compare(a.Namespace, b.Namespace)
+		}
+		if a.Service != b.Service {
+			return strings.Compare(a.Service, b.Service)
+		}
+		return b.Created.Compare(a.Created)
+	})
+	return images, nil
+}
+
+// foldPlatformImages moves the images of the other platforms of a multi-platform build into the image of the
+// daemon platform. Those whose parent is gone are kept on their own, so they can still be pruned.
+func foldPlatformImages(images []Image) []Image {
+	builds := make(map[string]bool)
+	for _, img := range images {
+		if img.build != "" {
+			builds[img.build] = true
+		}
+	}
+
+	platforms := make(map[string][]Image)
+	folded := make([]Image, 0, len(images))
+	for _, img := range images {
+		if img.parent != "" && builds[img.parent] {
+			platforms[img.parent] = append(platforms[img.parent], img)
+			continue
+		}
+		folded = append(folded, img)
+	}
+	for i, img := range folded {
+		if img.build != "" {
+			folded[i].Platforms = platforms[img.build]
+		}
+	}
+	return folded
+}
+
+// PruneImages removes the images built by the World CLI except the keep most recent ones of every service of
+// every namespace.
+// Images used by a container are kept. It returns the removed images.
+func (c *Client) PruneImages(ctx context.Context, keep int) ([]Image, error) {
+	images, err := c.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Images of stopped containers are kept too, removing them would break a restart
+	containers, err := c.client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, eris.Wrap(err, "Failed to list containers")
+	}
+	inUse := make(map[string]bool, len(containers))
+	for _, ctr := range containers {
+		inUse[ctr.ImageID] = true
+	}
+
+	// The images of a namespace are the builds of several services, each one keeps its own recent builds
+	type imageGroup struct{ namespace, service string }
+	groups := make(map[imageGroup][]Image)
+	for _, img := range images {
+		group := imageGroup{namespace: img.Namespace, service: img.Service}
+		groups[group] = append(groups[group], img)
+	}
+
+	removed := make([]Image, 0)
+	for _, group := range slices.SortedFunc(maps.Keys(groups), func(a, b imageGroup) int {
+		if a.namespace != b.namespace {
+			return strings.Compare(a.namespace, b.namespace)
+		}
+		return strings.Compare(a.service, b.service)
+	}) {
+		groupImages := groups[group]
+		if len(groupImages) <= keep {
+			continue
+		}
+		for _, img := range groupImages[keep:] {
+			if inUse[img.ID] {
+				printer.Notificationf("Keeping image %s, it is used by a container\n", ShortImageID(img.ID))
+				continue
+			}
+			if err := c.removeImage(ctx, img); err != nil {
+				printer.Notificationf("Keeping image %s: %v\n", ShortImageID(img.ID), err)
+				continue
+			}
+			for _, platformImg := range img.Platforms {
+				if err := c.removeImage(ctx, platformImg); err != nil {
+					printer.Notificationf("Keeping image %s: %v\n", ShortImageID(platformImg.ID), err)
+				}
+			}
+			removed = append(removed, img)
+		}
+	}
+	return removed, nil
+}
+
+// removeImage removes every tag of the image, which removes the image once the last tag is gone.
+func (c *Client) removeImage(ctx context.Context, img Image) error {
+	refs := img.Tags
+	if len(refs) == 0 {
+		refs = []string{img.ID}
+	}
+	for _, ref := range refs {
+		if _, err := c.client.ImageRemove(ctx, ref, image.RemoveOptions{PruneChildren: true}); err != nil {
+			return eris.Wrapf(err, "Failed to remove %s", ref)
+		}
+	}
+	return nil
+}
+
+// ShortImageID returns the first 12 characters of the hex digest of an image ID.
+func ShortImageID(id string) string {
+	const shortIDLength = 12
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > shortIDLength {
+		return id[:shortIDLength]
+	}
+	return id
+}
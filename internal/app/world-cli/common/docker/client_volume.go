@@ -3,14 +3,36 @@ package docker
 import (
 	"context"
 	"fmt"
+	"slices"
 
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/docker/service"
 	"pkg.world.dev/world-cli/internal/pkg/tea/component/multispinner"
 	"pkg.world.dev/world-cli/internal/pkg/tea/component/program"
 	"pkg.world.dev/world-cli/internal/pkg/tea/style"
 )
 
+// processVolumes processes the namespace volume and every named volume mounted by the given services.
+func (c *Client) processVolumes(ctx context.Context, processType processType, services ...service.Service) error {
+	volumeNames := []string{c.cfg.DockerEnv["CARDINAL_NAMESPACE"]}
+	for _, dockerService := range services {
+		for _, m := range dockerService.Mounts {
+			if m.Type == mount.TypeVolume && !slices.Contains(volumeNames, m.Source) {
+				volumeNames = append(volumeNames, m.Source)
+			}
+		}
+	}
+
+	for _, volumeName := range volumeNames {
+		if err := c.processVolume(ctx, processType, volumeName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Client) processVolume(ctx context.Context, processType processType, volumeName string) error {
 	// Create context with cancel
 	ctx, cancel := context.WithCancel(ctx)
@@ -17,11 +17,18 @@ func CelestiaDevNet(cfg *config.Config) Service {
 	// Check cardinal namespace
 	checkCardinalNamespace(cfg)
 
+	ports := publishAll(cfg, PortCelestiaRPC, PortCelestiaGateway)
+
+	// 26657 and 9090 are exposed to the network only, they are not published on the host
+	portBindings := newPortMap(ports)
+	portBindings["26657/tcp"] = []nat.PortBinding{}
+	portBindings["9090/tcp"] = []nat.PortBinding{}
+
 	return Service{
 		Name: getCelestiaDevNetContainerName(cfg),
 		Config: container.Config{
 			Image:        "ghcr.io/rollkit/local-celestia-devnet:latest",
-			ExposedPorts: getExposedPorts([]int{26658, 26659}),
+			ExposedPorts: getExposedPorts(ports),
 			Healthcheck: &container.HealthConfig{
 				Test:     []string{"CMD", "curl", "-f", "http://127.0.0.1:26659/head"},
 				Interval: 1 * time.Second,
@@ -30,14 +37,10 @@ func CelestiaDevNet(cfg *config.Config) Service {
 			},
 		},
 		HostConfig: container.HostConfig{
-			PortBindings: nat.PortMap{
-				"26657/tcp": []nat.PortBinding{},
-				"26658/tcp": []nat.PortBinding{{HostPort: "26658"}},
-				"26659/tcp": []nat.PortBinding{{HostPort: "26659"}},
-				"9090/tcp":  []nat.PortBinding{},
-			},
+			PortBindings:  portBindings,
 			RestartPolicy: container.RestartPolicy{Name: "on-failure"},
 			NetworkMode:   container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 		},
+		Ports: ports,
 	}
 }
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+// GetContainerName returns the container name of the service with the given name, which is either a
+// built-in service (see config.BuiltinServiceNames) or a user defined service.
+func GetContainerName(cfg *config.Config, name string) string {
+	return fmt.Sprintf("%s-%s", cfg.DockerEnv["CARDINAL_NAMESPACE"], name)
+}
+
+// CustomServices returns a builder for every user defined service of the [services] section of world.toml.
+func CustomServices(cfg *config.Config) []Builder {
+	builders := make([]Builder, 0, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		builders = append(builders, Custom(svc))
+	}
+	return builders
+}
+
+// Custom returns the builder of a user defined service. The config of the service is validated
+// when world.toml is loaded.
+func Custom(svc config.ServiceConfig) Builder {
+	return func(cfg *config.Config) Service {
+		// Check cardinal namespace
+		checkCardinalNamespace(cfg)
+
+		namespace := cfg.DockerEnv["CARDINAL_NAMESPACE"]
+		ports := customPorts(cfg, svc)
+
+		dependsOn := make([]string, 0, len(svc.DependsOn))
+		for _, dep := range svc.DependsOn {
+			dependsOn = append(dependsOn, GetContainerName(cfg, dep))
+		}
+
+		service := Service{
+			Name: GetContainerName(cfg, svc.Name),
+			Config: container.Config{
+				Image:        svc.Image,
+				Cmd:          svc.Command,
+				Env:          svc.EnvList(),
+				ExposedPorts: getExposedPorts(ports),
+				Healthcheck:  customHealthcheck(svc.Healthcheck),
+			},
+			HostConfig: container.HostConfig{
+				PortBindings:  newPortMap(ports),
+				RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+				Mounts:        customMounts(cfg, svc),
+				NetworkMode:   container.NetworkMode(namespace),
+			},
+			// Let the other containers reach the service by its name in world.toml
+			NetworkingConfig: network.NetworkingConfig{
+				EndpointsConfig: map[string]*network.EndpointSettings{
+					namespace: {Aliases: []string{svc.Name}},
+				},
+			},
+			Ports:     ports,
+			DependsOn: dependsOn,
+		}
+
+		if svc.Build != nil {
+			if service.Image == "" {
+				service.Image = strings.ToLower(GetContainerName(cfg, svc.Name))
+			}
+			service.BuildContext = resolveHostPath(cfg, svc.Build.Context)
+			service.DockerfilePath = svc.Build.Dockerfile
+			if service.DockerfilePath == "" {
+				service.DockerfilePath = "Dockerfile"
+			}
+			service.BuildTarget = svc.Build.Target
+			service.BuildArgs = make(map[string]string, len(svc.Build.Args))
+			for key, val := range svc.Build.Args {
+				service.BuildArgs[key] = fmt.Sprintf("%v", val)
+			}
+		}
+
+		return service
+	}
+}
+
+// CustomPortKey returns the key of the [ports] section overriding the host port of a user defined service.
+func CustomPortKey(name string, containerPort int) string {
+	return fmt.Sprintf("%s_%d", name, containerPort)
+}
+
+func customPorts(cfg *config.Config, svc config.ServiceConfig) []PublishedPort {
+	servicePorts, _ := svc.PublishedPorts()
+	ports := make([]PublishedPort, 0, len(servicePorts))
+	for _, port := range servicePorts {
+		key := CustomPortKey(svc.Name, port.Container)
+		host, ok := cfg.Ports[key]
+		if !ok {
+			host = port.Host + cfg.PortOffset
+		}
+		ports = append(ports, PublishedPort{
+			Key:       key,
+			Label:     fmt.Sprintf("%s (%d)", svc.Name, port.Container),
+			Container: port.Container,
+			Host:      host,
+		})
+	}
+	return ports
+}
+
+// customMounts returns the mounts of a user defined service. Named volumes are scoped to the namespace
+// so they are removed by purge and don't collide across shards.
+func customMounts(cfg *config.Config, svc config.ServiceConfig) []mount.Mount {
+	volumes, _ := svc.Mounts()
+	mounts := make([]mount.Mount, 0, len(volumes))
+	for _, volume := range volumes {
+		m := mount.Mount{
+			Type:     mount.TypeVolume,
+			Source:   GetContainerName(cfg, volume.Source),
+			Target:   volume.Target,
+			ReadOnly: volume.ReadOnly,
+		}
+		if volume.Bind {
+			m.Type = mount.TypeBind
+			m.Source = resolveHostPath(cfg, volume.Source)
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+func customHealthcheck(healthcheck *config.Healthcheck) *container.HealthConfig {
+	if healthcheck == nil {
+		return nil
+	}
+	test := healthcheck.Test
+	if test[0] != "CMD" && test[0] != "CMD-SHELL" && test[0] != "NONE" {
+		test = append([]string{"CMD"}, test...)
+	}
+	interval, timeout, startPeriod, _ := healthcheck.Durations()
+	return &container.HealthConfig{
+		Test:        test,
+		Interval:    interval,
+		Timeout:     timeout,
+		StartPeriod: startPeriod,
+		Retries:     healthcheck.Retries,
+	}
+}
+
+// resolveHostPath returns the absolute path of a path relative to the root directory.
+func resolveHostPath(cfg *config.Config, path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	root, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		root = cfg.RootDir
+	}
+	return filepath.Join(root, path)
+}
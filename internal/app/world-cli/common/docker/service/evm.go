@@ -79,6 +79,8 @@ func EVM(cfg *config.Config) Service {
 		}
 	}
 
+	ports := publishAll(cfg, PortEVMAPI, PortEVMRPC, PortEVMGRPC, PortEVMSequencer, PortEVMJSONRPC)
+
 	return Service{
 		Name: getEVMContainerName(cfg),
 		Config: container.Config{
@@ -95,13 +97,14 @@ func EVM(cfg *config.Config) Service {
 				fmt.Sprintf("CHAIN_ID=%s", chainID),
 				fmt.Sprintf("CHAIN_KEY_MNEMONIC=%s", chainKeyMnemonic),
 			},
-			ExposedPorts: getExposedPorts([]int{1317, 26657, 9090, 9601}),
+			ExposedPorts: getExposedPorts(ports),
 		},
 		HostConfig: container.HostConfig{
-			PortBindings:  newPortMap([]int{1317, 26657, 9090, 9601, 8545}),
+			PortBindings:  newPortMap(ports),
 			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
 			NetworkMode:   container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 		},
 		Platform: platform,
+		Ports:    ports,
 	}
 }
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"embed"
+	"fmt"
+	"path"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+const (
+	grafanaProvisioningDir = "/etc/grafana/provisioning"
+	// grafanaDashboardsDir is where the dashboards of the World CLI are written in the container
+	grafanaDashboardsDir = "/etc/grafana/dashboards"
+)
+
+//nolint:gochecknoglobals // the dashboards are embedded at compile time and are read-only
+//go:embed grafana/*.json
+var grafanaDashboards embed.FS
+
+// grafanaDatasources provisions Prometheus, with the uid the dashboards refer to.
+const grafanaDatasources = `apiVersion: 1
+datasources:
+  - name: Prometheus
+    uid: prometheus
+    type: prometheus
+    access: proxy
+    url: http://%s:9090
+    isDefault: true
+`
+
+// grafanaJaegerDatasource provisions Jaeger along with Prometheus when the stack traces.
+const grafanaJaegerDatasource = `  - name: Jaeger
+    uid: jaeger
+    type: jaeger
+    access: proxy
+    url: http://%s:16686
+`
+
+// grafanaDashboardProvider loads the dashboards of grafanaDashboardsDir into a World Engine folder.
+const grafanaDashboardProvider = `apiVersion: 1
+providers:
+  - name: world-cli
+    folder: World Engine
+    type: file
+    disableDeletion: true
+    options:
+      path: ` + grafanaDashboardsDir + `
+`
+
+func getGrafanaContainerName(cfg *config.Config) string {
+	return fmt.Sprintf("%s-grafana", cfg.DockerEnv["CARDINAL_NAMESPACE"])
+}
+
+// Grafana runs Grafana with Prometheus, and Jaeger when the stack traces, as datasources and the Nakama and
+// Cardinal dashboards.
+// Anonymous users are admins, it is only meant for local development.
+func Grafana(cfg *config.Config) Service {
+	// Check cardinal namespace
+	checkCardinalNamespace(cfg)
+
+	ports := publishAll(cfg, PortGrafana)
+
+	datasources := fmt.Sprintf(grafanaDatasources, getPrometheusContainerName(cfg))
+	if TracingEnabled(cfg) {
+		datasources += fmt.Sprintf(grafanaJaegerDatasource, getJaegerContainerName(cfg))
+	}
+	files := map[string]string{
+		path.Join(grafanaProvisioningDir, "datasources", "world.yaml"): datasources,
+		path.Join(grafanaProvisioningDir, "dashboards", "world.yaml"):  grafanaDashboardProvider,
+	}
+	entries, _ := grafanaDashboards.ReadDir("grafana")
+	for _, entry := range entries {
+		content, _ := grafanaDashboards.ReadFile(path.Join("grafana", entry.Name()))
+		files[path.Join(grafanaDashboardsDir, entry.Name())] = string(content)
+	}
+
+	return Service{
+		Name: getGrafanaContainerName(cfg),
+		Config: container.Config{
+			Image: "grafana/grafana:11.2.0",
+			Env: []string{
+				"GF_AUTH_ANONYMOUS_ENABLED=true",
+				"GF_AUTH_ANONYMOUS_ORG_ROLE=Admin",
+				"GF_AUTH_DISABLE_LOGIN_FORM=true",
+				"GF_DASHBOARDS_DEFAULT_HOME_DASHBOARD_PATH=" + path.Join(grafanaDashboardsDir, "cardinal.json"),
+			},
+			ExposedPorts: getExposedPorts(ports),
+		},
+		HostConfig: container.HostConfig{
+			PortBindings:  newPortMap(ports),
+			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+			// Keep the dashboards made in Grafana across restarts
+			Mounts: []mount.Mount{{Type: mount.TypeVolume,
+				Source: GetContainerName(cfg, "grafana-data"), Target: "/var/lib/grafana"}},
+			NetworkMode: container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
+		},
+		Files:     files,
+		Ports:     ports,
+		DependsOn: []string{getPrometheusContainerName(cfg)},
+	}
+}
@@ -13,7 +13,7 @@ func getJaegerContainerName(cfg *config.Config) string {
 }
 
 func Jaeger(cfg *config.Config) Service {
-	exposedPorts := []int{16686}
+	ports := publishAll(cfg, PortJaeger)
 
 	return Service{
 		Name: getJaegerContainerName(cfg),
@@ -39,10 +39,11 @@ func Jaeger(cfg *config.Config) Service {
 			User: "root",
 		},
 		HostConfig: container.HostConfig{
-			PortBindings: newPortMap(exposedPorts),
+			PortBindings: newPortMap(ports),
 			NetworkMode:  container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 			Mounts: []mount.Mount{{Type: mount.TypeVolume,
 				Source: cfg.DockerEnv["CARDINAL_NAMESPACE"], Target: "/badger"}},
 		},
+		Ports: ports,
 	}
 }
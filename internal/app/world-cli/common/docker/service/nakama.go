@@ -1,21 +1,56 @@
 package service
 
 import (
+	_ "embed"
 	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
 )
 
+const (
+	defaultNakamaImage       = "ghcr.io/argus-labs/world-engine-nakama:latest"
+	nakamaPluginBuilderImage = "heroiclabs/nakama-pluginbuilder"
+
+	// nakamaImageConfig is the config file of the Nakama image
+	nakamaImageConfig = "/nakama/data/local.yml"
+	// nakamaProjectConfig is where the config file of the project is mounted, it replaces nakamaImageConfig
+	nakamaProjectConfig = "/nakama/data/project.yml"
+	// nakamaProjectModules is where the runtime modules of the project are mounted. Nakama also loads the
+	// modules of the subdirectories of its modules directory, so the modules of the image are kept.
+	nakamaProjectModules = "/nakama/data/modules/project"
+
+	// NakamaVersionKey is the key of the [nakama] section with the Nakama version of the image, detected from
+	// the image when a Go plugin is built without it
+	NakamaVersionKey = "NAKAMA_VERSION"
+
+	// hostGatewayName resolves to the host in the containers started with a host-gateway mapping
+	hostGatewayName = "host.docker.internal"
+)
+
+// shellSafeRegexp matches the values that need no quotes in a shell command.
+//
+//nolint:gochecknoglobals // compiled once
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9._/:,=+-]+$`)
+
+//nolint:gochecknoglobals // nakamaDockerfileContent is embedded at compile time and is read-only
+//go:embed nakama.Dockerfile
+var nakamaDockerfileContent string
+
 func getNakamaContainerName(cfg *config.Config) string {
 	return fmt.Sprintf("%s-nakama", cfg.DockerEnv["CARDINAL_NAMESPACE"])
 }
 
-func Nakama(cfg *config.Config) Service {
+func Nakama(cfg *config.Config) Service { //nolint:funlen // it does what it needs to do
 	// Check cardinal namespace
 	checkCardinalNamespace(cfg)
 
@@ -32,7 +67,7 @@ func Nakama(cfg *config.Config) Service {
 	// Set default password if not provided
 	dbPassword := cfg.DockerEnv["DB_PASSWORD"]
 	if dbPassword == "" {
-		dbPassword = "very_unsecure_password_please_change" //nolint:gosec // This is a default password
+		dbPassword = defaultNakamaDBPassword
 	}
 
 	traceEnabled := cfg.DockerEnv["NAKAMA_TRACE_ENABLED"]
@@ -53,9 +88,11 @@ func Nakama(cfg *config.Config) Service {
 		}
 	}
 
-	nakamaImage := "ghcr.io/argus-labs/world-engine-nakama:latest"
-	if cfg.DockerEnv["NAKAMA_IMAGE"] != "" {
-		nakamaImage = cfg.DockerEnv["NAKAMA_IMAGE"]
+	nakamaImage := NakamaImage(cfg)
+
+	logLevel := cfg.DockerEnv["NAKAMA_LOG_LEVEL"]
+	if logLevel == "" {
+		logLevel = "INFO"
 	}
 
 	platform := ocispec.Platform{
@@ -78,35 +115,47 @@ func Nakama(cfg *config.Config) Service {
 	if metricsEnabled {
 		prometheusPort = 9100
 	}
-	exposedPorts := []int{7349, 7350, 7351}
-	databaseAddress := fmt.Sprintf("postgres:%s@%s:5432/nakama", dbPassword, getNakamaDBContainerName(cfg))
+	ports := publishAll(cfg, PortNakamaGRPC, PortNakamaHTTP, PortNakamaConsole)
+	databaseAddress := NakamaDBAddress(cfg)
+
+	// The flags of [nakama.flags] override the defaults
+	flags := map[string]string{
+		"config":                     nakamaImageConfig,
+		"socket.outgoing_queue_size": outgoingQueueSize,
+		"logger.level":               logLevel,
+		"metrics.prometheus_port":    strconv.Itoa(prometheusPort),
+	}
+	if cfg.DockerEnv[config.NakamaConfigFileKey] != "" {
+		flags["config"] = nakamaProjectConfig
+	}
+	maps.Copy(flags, cfg.NakamaFlags)
 
-	return Service{
+	service := Service{
 		Name: getNakamaContainerName(cfg),
 		Config: container.Config{
 			Image: nakamaImage,
 			Env: []string{
 				fmt.Sprintf("CARDINAL_CONTAINER=%s", getCardinalContainerName(cfg)),
-				fmt.Sprintf("CARDINAL_ADDR=%s:4040", getCardinalContainerName(cfg)),
+				fmt.Sprintf("CARDINAL_ADDR=%s", nakamaCardinalAddress(cfg)),
 				fmt.Sprintf("CARDINAL_NAMESPACE=%s", cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 				fmt.Sprintf("DB_PASSWORD=%s", dbPassword),
 				fmt.Sprintf("ENABLE_ALLOWLIST=%s", enableAllowList),
 				fmt.Sprintf("OUTGOING_QUEUE_SIZE=%s", outgoingQueueSize),
 				fmt.Sprintf("TRACE_ENABLED=%s", traceEnabled),
-				fmt.Sprintf("JAEGER_ADDR=%s:4317", getJaegerContainerName(cfg)),
+				fmt.Sprintf("JAEGER_ADDR=%s", nakamaTraceAddress(cfg)),
 				fmt.Sprintf("JAEGER_SAMPLE_RATE=%s", traceSampleRate),
 			},
 			Entrypoint: []string{
 				"/bin/sh",
 				"-ec",
 				fmt.Sprintf(
-					`/nakama/nakama migrate up --database.address %s && /nakama/nakama --database.address %s --config /nakama/data/local.yml --socket.outgoing_queue_size=64 --logger.level INFO --metrics.prometheus_port %d`, //nolint:lll
+					`/nakama/nakama migrate up --database.address %s && /nakama/nakama --database.address %s %s`,
 					databaseAddress,
 					databaseAddress,
-					prometheusPort,
+					nakamaFlags(flags),
 				),
 			},
-			ExposedPorts: getExposedPorts(exposedPorts),
+			ExposedPorts: getExposedPorts(ports),
 			Healthcheck: &container.HealthConfig{
 				Test:     []string{"CMD", "/nakama/nakama", "healthcheck"},
 				Interval: 1 * time.Second,
@@ -115,10 +164,101 @@ func Nakama(cfg *config.Config) Service {
 			},
 		},
 		HostConfig: container.HostConfig{
-			PortBindings:  newPortMap(exposedPorts),
+			PortBindings:  newPortMap(ports),
 			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+			Mounts:        nakamaMounts(cfg),
 			NetworkMode:   container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 		},
 		Platform: platform,
+		Ports:    ports,
+	}
+
+	// Cardinal runs natively in world cardinal dev, Nakama reaches it through the host
+	if cfg.CardinalOnHost {
+		service.ExtraHosts = []string{hostGatewayName + ":host-gateway"}
+	}
+
+	// Build an image adding the Go plugin of the project to the Nakama image
+	if pluginPath := cfg.DockerEnv[config.NakamaGoPluginKey]; pluginPath != "" {
+		builderImage := NakamaPluginBuilderImage(cfg)
+		service.Image = strings.ToLower(getNakamaContainerName(cfg))
+		service.Dockerfile = nakamaDockerfileContent
+		service.BuildTarget = "runtime"
+		service.BuildArgs = map[string]string{"PLUGIN_PATH": filepath.ToSlash(filepath.Clean(pluginPath))}
+		service.BaseImages = map[string]string{"NAKAMA_IMAGE": nakamaImage, "PLUGIN_BUILDER_IMAGE": builderImage}
+		service.Dependencies = []Service{
+			{Name: nakamaImage, Config: container.Config{Image: nakamaImage}, Platform: platform},
+			{Name: builderImage, Config: container.Config{Image: builderImage}, Platform: platform},
+		}
+	}
+
+	return service
+}
+
+// NakamaImage returns the Nakama image of the stack, before the Go plugin of the project is added.
+func NakamaImage(cfg *config.Config) string {
+	if image := cfg.DockerEnv["NAKAMA_IMAGE"]; image != "" {
+		return image
+	}
+	return defaultNakamaImage
+}
+
+// NakamaPluginBuilderImage returns the image building Go plugins for the Nakama version of NAKAMA_VERSION.
+func NakamaPluginBuilderImage(cfg *config.Config) string {
+	version := strings.TrimPrefix(cfg.DockerEnv[NakamaVersionKey], "v")
+	if version == "" {
+		version = "latest"
+	}
+	return nakamaPluginBuilderImage + ":" + version
+}
+
+// nakamaMounts mounts the Nakama config file and runtime modules of the project, read-only.
+func nakamaMounts(cfg *config.Config) []mount.Mount {
+	var mounts []mount.Mount
+	if path := cfg.DockerEnv[config.NakamaConfigFileKey]; path != "" {
+		mounts = append(mounts, mount.Mount{
+			Type: mount.TypeBind, Source: resolveHostPath(cfg, path), Target: nakamaProjectConfig, ReadOnly: true,
+		})
+	}
+	if path := cfg.DockerEnv[config.NakamaModulesDirKey]; path != "" {
+		mounts = append(mounts, mount.Mount{
+			Type: mount.TypeBind, Source: resolveHostPath(cfg, path), Target: nakamaProjectModules, ReadOnly: true,
+		})
+	}
+	return mounts
+}
+
+// nakamaFlags renders the flags of Nakama for its shell entrypoint, sorted by name.
+func nakamaFlags(flags map[string]string) string {
+	args := make([]string, 0, len(flags))
+	for _, name := range slices.Sorted(maps.Keys(flags)) {
+		args = append(args, fmt.Sprintf("--%s=%s", name, shellQuote(flags[name])))
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes a value for sh, unless it only has characters that are safe unquoted.
+func shellQuote(value string) string {
+	if shellSafeRegexp.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// nakamaCardinalAddress returns the address Nakama reaches Cardinal at: its container, or the host when
+// Cardinal runs natively.
+func nakamaCardinalAddress(cfg *config.Config) string {
+	if cfg.CardinalOnHost {
+		return hostGatewayName + ":" + strconv.Itoa(HostPort(cfg, PortCardinal))
+	}
+	return getCardinalContainerName(cfg) + ":4040"
+}
+
+// nakamaTraceAddress returns the OTLP endpoint Nakama exports its traces to: the OpenTelemetry collector when
+// the stack traces, which tags them with the namespace before forwarding them to Jaeger.
+func nakamaTraceAddress(cfg *config.Config) string {
+	if TracingEnabled(cfg) {
+		return getOTelCollectorContainerName(cfg) + ":" + strconv.Itoa(otelCollectorGRPCPort)
 	}
+	return getJaegerContainerName(cfg) + ":" + strconv.Itoa(otelCollectorGRPCPort)
 }
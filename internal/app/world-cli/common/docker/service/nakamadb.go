@@ -10,42 +10,73 @@ import (
 	"pkg.world.dev/world-cli/internal/pkg/logger"
 )
 
+const (
+	defaultNakamaDBImage = "postgres:12.2-alpine"
+	// defaultNakamaDBPassword is the password of the Nakama database when DB_PASSWORD is not set
+	defaultNakamaDBPassword = "very_unsecure_password_please_change" //nolint:gosec // This is a default password
+
+	// NakamaDBImageKey is the key of the [nakama] section with the Postgres image of the Nakama database
+	NakamaDBImageKey = "NAKAMA_DB_IMAGE"
+	// NakamaDBUser is the superuser of the Nakama database
+	NakamaDBUser = "postgres"
+	// NakamaDBName is the database of Nakama
+	NakamaDBName = "nakama"
+)
+
 func getNakamaDBContainerName(cfg *config.Config) string {
 	return fmt.Sprintf("%s-nakama-db", cfg.DockerEnv["CARDINAL_NAMESPACE"])
 }
 
 func NakamaDB(cfg *config.Config) Service {
-	exposedPorts := []int{5432, 8080}
+	ports := publishAll(cfg, PortNakamaDB, PortNakamaDBHTTP)
 
 	// Set default password if not provided
 	dbPassword := cfg.DockerEnv["DB_PASSWORD"]
 	if dbPassword == "" {
 		logger.Warn("Using default DB_PASSWORD, please change it.")
-		dbPassword = "very_unsecure_password_please_change" //nolint:gosec // This is a default password
+		dbPassword = defaultNakamaDBPassword
 	}
 
 	return Service{
 		Name: getNakamaDBContainerName(cfg),
 		Config: container.Config{
-			Image: "postgres:12.2-alpine",
+			Image: NakamaDBImage(cfg),
 			Env: []string{
-				"POSTGRES_DB=nakama",
+				"POSTGRES_DB=" + NakamaDBName,
 				fmt.Sprintf("POSTGRES_PASSWORD=%s", dbPassword),
 			},
-			ExposedPorts: getExposedPorts(exposedPorts),
+			ExposedPorts: getExposedPorts(ports),
 			Healthcheck: &container.HealthConfig{
-				Test:     []string{"CMD", "pg_isready", "-U", "postgres", "-d", "nakama"},
+				Test:     []string{"CMD", "pg_isready", "-U", NakamaDBUser, "-d", NakamaDBName},
 				Interval: 3 * time.Second,
 				Timeout:  3 * time.Second,
 				Retries:  5,
 			},
 		},
 		HostConfig: container.HostConfig{
-			PortBindings:  newPortMap(exposedPorts),
+			PortBindings:  newPortMap(ports),
 			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
 			Mounts: []mount.Mount{{Type: mount.TypeVolume, Source: cfg.DockerEnv["CARDINAL_NAMESPACE"],
 				Target: "/var/lib/postgresql/data"}},
 			NetworkMode: container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 		},
+		Ports: ports,
+	}
+}
+
+// NakamaDBImage returns the Postgres image of the Nakama database.
+func NakamaDBImage(cfg *config.Config) string {
+	if image := cfg.DockerEnv[NakamaDBImageKey]; image != "" {
+		return image
+	}
+	return defaultNakamaDBImage
+}
+
+// NakamaDBAddress returns the address Nakama connects to its database with, for its --database.address flag.
+func NakamaDBAddress(cfg *config.Config) string {
+	dbPassword := cfg.DockerEnv["DB_PASSWORD"]
+	if dbPassword == "" {
+		dbPassword = defaultNakamaDBPassword
 	}
+	return fmt.Sprintf("%s:%s@%s:5432/%s", NakamaDBUser, dbPassword, getNakamaDBContainerName(cfg), NakamaDBName)
 }
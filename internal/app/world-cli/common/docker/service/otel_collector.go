@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+const (
+	// otelCollectorConfigFile is the default config file of the contrib distribution of the collector,
+	// replaced by the config generated by the World CLI
+	otelCollectorConfigFile = "/etc/otelcol-contrib/config.yaml"
+	// otelCollectorGRPCPort receives OTLP over gRPC, Nakama and Cardinal export their traces to it
+	otelCollectorGRPCPort = 4317
+	// otelCollectorDatadogPort receives the traces of the Datadog tracer used by Cardinal
+	otelCollectorDatadogPort = 8126
+	// otelCollectorMetricsPort exposes the metrics of the collector to Prometheus
+	otelCollectorMetricsPort = 8888
+	// otelCollectorSpanMetricsPort exposes the metrics derived from the spans to Prometheus
+	otelCollectorSpanMetricsPort = 8889
+)
+
+// otelCollectorConfig receives OTLP and Datadog traces, tags them with the namespace of the shard so the
+// traces of several shards can be told apart in Jaeger, and exports them to Jaeger. The spanmetrics connector
+// also counts the spans and times them by service and span name, which gives Prometheus the tick duration and
+// the message rate of Cardinal, since Cardinal serves no metrics itself.
+const otelCollectorConfig = `receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:%[1]d
+      http:
+        endpoint: 0.0.0.0:4318
+  datadog:
+    endpoint: 0.0.0.0:%[2]d
+
+processors:
+  batch: {}
+  resource:
+    attributes:
+      - key: service.namespace
+        value: %[3]s
+        action: upsert
+
+connectors:
+  spanmetrics:
+    histogram:
+      explicit:
+        buckets: [1ms, 2ms, 5ms, 10ms, 25ms, 50ms, 100ms, 250ms, 500ms, 1s, 2500ms, 5s]
+    metrics_flush_interval: 15s
+
+exporters:
+  otlp/jaeger:
+    endpoint: %[4]s
+    tls:
+      insecure: true
+  prometheus:
+    endpoint: 0.0.0.0:%[6]d
+
+service:
+  telemetry:
+    metrics:
+      address: 0.0.0.0:%[5]d
+  pipelines:
+    traces:
+      receivers: [otlp, datadog]
+      processors: [resource, batch]
+      exporters: [otlp/jaeger, spanmetrics]
+    metrics:
+      receivers: [spanmetrics]
+      exporters: [prometheus]
+`
+
+func getOTelCollectorContainerName(cfg *config.Config) string {
+	return fmt.Sprintf("%s-otel-collector", cfg.DockerEnv["CARDINAL_NAMESPACE"])
+}
+
+// TracingEnabled returns true when the stack exports traces to Jaeger through the OpenTelemetry collector.
+// Tracing is on with --telemetry, unless both Nakama and Cardinal turn it off in world.toml.
+func TracingEnabled(cfg *config.Config) bool {
+	if !cfg.Telemetry {
+		return false
+	}
+	return cfg.DockerEnv["NAKAMA_TRACE_ENABLED"] != "false" || cfg.DockerEnv["TELEMETRY_TRACE_ENABLED"] != "false"
+}
+
+// OTelCollector runs the OpenTelemetry collector receiving the traces of Nakama and Cardinal, forwarding them
+// to Jaeger and serving the metrics of their spans to Prometheus.
+func OTelCollector(cfg *config.Config) Service {
+	// Check cardinal namespace
+	checkCardinalNamespace(cfg)
+
+	namespace := cfg.DockerEnv["CARDINAL_NAMESPACE"]
+	jaeger := getJaegerContainerName(cfg) + ":" + strconv.Itoa(otelCollectorGRPCPort)
+
+	return Service{
+		Name: getOTelCollectorContainerName(cfg),
+		Config: container.Config{
+			Image: "otel/opentelemetry-collector-contrib:0.111.0",
+		},
+		HostConfig: container.HostConfig{
+			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+			NetworkMode:   container.NetworkMode(namespace),
+		},
+		Files: map[string]string{
+			otelCollectorConfigFile: fmt.Sprintf(otelCollectorConfig, otelCollectorGRPCPort,
+				otelCollectorDatadogPort, strconv.Quote(namespace), strconv.Quote(jaeger), otelCollectorMetricsPort,
+				otelCollectorSpanMetricsPort),
+		},
+		DependsOn: []string{getJaegerContainerName(cfg)},
+	}
+}
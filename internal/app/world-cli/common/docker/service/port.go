@@ -0,0 +1,153 @@
+package service
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+)
+
+// Keys of the [ports] section of world.toml. Each key overrides the host port of one published port.
+const (
+	PortCardinal        = "cardinal"
+	PortCardinalDebug   = "cardinal_debug"
+	PortRedis           = "redis"
+	PortNakamaGRPC      = "nakama_grpc"
+	PortNakamaHTTP      = "nakama_http"
+	PortNakamaConsole   = "nakama_console"
+	PortNakamaDB        = "nakama_db"
+	PortNakamaDBHTTP    = "nakama_db_http"
+	PortJaeger          = "jaeger"
+	PortPrometheus      = "prometheus"
+	PortGrafana         = "grafana"
+	PortEVMAPI          = "evm_api"
+	PortEVMRPC          = "evm_rpc"
+	PortEVMGRPC         = "evm_grpc"
+	PortEVMSequencer    = "evm_sequencer"
+	PortEVMJSONRPC      = "evm_jsonrpc"
+	PortCelestiaRPC     = "celestia_rpc"
+	PortCelestiaGateway = "celestia_gateway"
+)
+
+// PublishedPort is a container port that is published on the host.
+type PublishedPort struct {
+	// Key is the name of the port in the [ports] section of world.toml
+	Key string
+	// Label is a human readable description of the port
+	Label     string
+	Container int
+	Host      int
+}
+
+const maxPort = 65535
+
+type portSpec struct {
+	container int
+	label     string
+}
+
+// knownPorts lists every port the local stack publishes. The container port is also the default host port.
+//
+//nolint:gochecknoglobals // read-only lookup table
+var knownPorts = map[string]portSpec{
+	PortCardinal:        {4040, "Cardinal"},
+	PortCardinalDebug:   {40000, "Cardinal Debugger"},
+	PortRedis:           {6379, "Redis"},
+	PortNakamaGRPC:      {7349, "Nakama gRPC"},
+	PortNakamaHTTP:      {7350, "Nakama API"},
+	PortNakamaConsole:   {7351, "Nakama Console"},
+	PortNakamaDB:        {5432, "Nakama DB"},
+	PortNakamaDBHTTP:    {8080, "Nakama DB HTTP"},
+	PortJaeger:          {16686, "Jaeger UI"},
+	PortPrometheus:      {9090, "Prometheus"},
+	PortGrafana:         {3000, "Grafana"},
+	PortEVMAPI:          {1317, "EVM API"},
+	PortEVMRPC:          {26657, "EVM RPC"},
+	PortEVMGRPC:         {9090, "EVM gRPC"},
+	PortEVMSequencer:    {9601, "EVM Sequencer"},
+	PortEVMJSONRPC:      {8545, "EVM JSON-RPC"},
+	PortCelestiaRPC:     {26658, "Celestia RPC"},
+	PortCelestiaGateway: {26659, "Celestia Gateway"},
+}
+
+// HostPort returns the host port for the given port key, taking overrides from world.toml into account.
+func HostPort(cfg *config.Config, key string) int {
+	if port, ok := cfg.Ports[key]; ok {
+		return port
+	}
+
+	// REDIS_PORT predates the [ports] section and is still honored
+	if key == PortRedis && cfg.DockerEnv["REDIS_PORT"] != "" {
+		port, err := strconv.Atoi(cfg.DockerEnv["REDIS_PORT"])
+		if err == nil {
+			return port
+		}
+		logger.Error("Failed to convert redis port to int, defaulting to 6379", err)
+	}
+
+	return knownPorts[key].container + cfg.PortOffset
+}
+
+// ContainerPort returns the port the service listens on in its container for the given port key.
+func ContainerPort(key string) int {
+	return knownPorts[key].container
+}
+
+// ValidatePorts returns an error if the [ports] section of world.toml contains an unknown key,
+// i.e. neither a built-in port nor a port of a user defined service (see CustomPortKey),
+// or if the port offset moves a default port out of range.
+func ValidatePorts(cfg *config.Config) error {
+	customKeys := make(map[string]bool)
+	for _, svc := range cfg.Services {
+		for _, port := range customPorts(cfg, svc) {
+			customKeys[port.Key] = true
+		}
+	}
+	for key := range cfg.Ports {
+		if _, ok := knownPorts[key]; !ok && !customKeys[key] {
+			return eris.Errorf("unknown port %q in [ports], must be one of (%s)", key, portKeys())
+		}
+	}
+	for key, spec := range knownPorts {
+		if spec.container+cfg.PortOffset > maxPort {
+			return eris.Errorf("port offset %d moves %s (%d) out of range", cfg.PortOffset, key, spec.container)
+		}
+	}
+	return nil
+}
+
+func portKeys() string {
+	keys := make([]string, 0, len(knownPorts))
+	for key := range knownPorts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, ", ")
+}
+
+// publish returns the published port for the given key.
+func publish(cfg *config.Config, key string) PublishedPort {
+	spec := knownPorts[key]
+	return PublishedPort{
+		Key:       key,
+		Label:     spec.label,
+		Container: spec.container,
+		Host:      HostPort(cfg, key),
+	}
+}
+
+func publishAll(cfg *config.Config, keys ...string) []PublishedPort {
+	ports := make([]PublishedPort, 0, len(keys))
+	for _, key := range keys {
+		ports = append(ports, publish(cfg, key))
+	}
+	return ports
+}
+
+func containerPort(port int) nat.Port {
+	return nat.Port(strconv.Itoa(port) + "/tcp")
+}
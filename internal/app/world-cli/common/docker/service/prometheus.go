@@ -2,56 +2,103 @@ package service
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
 )
 
-// containerCmd is a static template used immutably within Prometheus().
-const containerCmd = `sh -s <<EOF
-cat > ./prometheus.yaml <<EON
-global:
-  scrape_interval:     __NAKAMA_METRICS_INTERVAL__s
-  evaluation_interval: __NAKAMA_METRICS_INTERVAL__s
-
-scrape_configs:
-  - job_name: nakama
-    metrics_path: /
-    static_configs:
-      - targets: ['__NAKAMA_CONTAINER__:9100']
-EON
-prometheus --config.file=./prometheus.yaml
-EOF
-`
+const (
+	// prometheusConfigFile is where the scrape config generated by the World CLI is written in the container
+	prometheusConfigFile = "/etc/prometheus/world.yml"
+	defaultMetricsPath   = "/metrics"
+)
+
+// scrapeJob is a metrics endpoint scraped by Prometheus.
+type scrapeJob struct {
+	name   string
+	target string
+	path   string
+}
 
 func getPrometheusContainerName(cfg *config.Config) string {
 	return fmt.Sprintf("%s-prometheus", cfg.DockerEnv["CARDINAL_NAMESPACE"])
 }
 
 func Prometheus(cfg *config.Config) Service {
-	nakamaMetricsInterval := cfg.DockerEnv["NAKAMA_METRICS_INTERVAL"]
-	if nakamaMetricsInterval == "" {
-		nakamaMetricsInterval = "30"
-	}
+	// Check cardinal namespace
+	checkCardinalNamespace(cfg)
 
-	exposedPorts := []int{9090}
-
-	cmd := containerCmd
-
-	cmd = strings.ReplaceAll(cmd, "__NAKAMA_CONTAINER__", getNakamaContainerName(cfg))
-	cmd = strings.ReplaceAll(cmd, "__NAKAMA_METRICS_INTERVAL__", nakamaMetricsInterval)
+	ports := publishAll(cfg, PortPrometheus)
 
 	return Service{
 		Name: getPrometheusContainerName(cfg),
 		Config: container.Config{
-			Image:      "prom/prometheus:v2.54.1",
-			Entrypoint: []string{"/bin/sh", "-c"},
-			Cmd:        []string{cmd},
+			Image: "prom/prometheus:v2.54.1",
+			Cmd:   []string{"--config.file=" + prometheusConfigFile, "--storage.tsdb.path=/prometheus"},
 		},
 		HostConfig: container.HostConfig{
-			PortBindings: newPortMap(exposedPorts),
+			PortBindings: newPortMap(ports),
 			NetworkMode:  container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 		},
+		Files: map[string]string{prometheusConfigFile: prometheusConfig(cfg)},
+		Ports: ports,
+	}
+}
+
+// scrapeJobs returns the metrics endpoints of the stack: Nakama, Redis through its exporter, the OpenTelemetry
+// collector and the metrics it derives from the spans when the stack traces, and the user defined services
+// with a metrics endpoint. Cardinal serves no metrics, its ticks and messages are measured from its spans.
+func scrapeJobs(cfg *config.Config) []scrapeJob {
+	jobs := []scrapeJob{
+		{name: "nakama", target: getNakamaContainerName(cfg) + ":9100", path: "/"},
+		{name: "redis", target: getRedisExporterContainerName(cfg) + ":9121", path: defaultMetricsPath},
+	}
+	if TracingEnabled(cfg) {
+		jobs = append(jobs, scrapeJob{
+			name:   "otel-collector",
+			target: getOTelCollectorContainerName(cfg) + ":" + strconv.Itoa(otelCollectorMetricsPort),
+			path:   defaultMetricsPath,
+		}, scrapeJob{
+			name:   "spanmetrics",
+			target: getOTelCollectorContainerName(cfg) + ":" + strconv.Itoa(otelCollectorSpanMetricsPort),
+			path:   defaultMetricsPath,
+		})
+	}
+	for _, svc := range cfg.Services {
+		if svc.Metrics == nil {
+			continue
+		}
+		path := svc.Metrics.Path
+		if path == "" {
+			path = defaultMetricsPath
+		}
+		jobs = append(jobs, scrapeJob{
+			name:   svc.Name,
+			target: GetContainerName(cfg, svc.Name) + ":" + strconv.Itoa(svc.Metrics.Port),
+			path:   path,
+		})
+	}
+	return jobs
+}
+
+// prometheusConfig returns the Prometheus config scraping every metrics endpoint of the stack.
+// Endpoints that are not running are reported as down by Prometheus and don't prevent the others
+// from being scraped.
+func prometheusConfig(cfg *config.Config) string {
+	interval := cfg.DockerEnv["NAKAMA_METRICS_INTERVAL"]
+	if interval == "" {
+		interval = "30"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "global:\n  scrape_interval: %ss\n  evaluation_interval: %ss\n\n", interval, interval)
+	b.WriteString("scrape_configs:\n")
+	for _, job := range scrapeJobs(cfg) {
+		fmt.Fprintf(&b, "  - job_name: %s\n", strconv.Quote(job.name))
+		fmt.Fprintf(&b, "    metrics_path: %s\n", strconv.Quote(job.path))
+		fmt.Fprintf(&b, "    static_configs:\n      - targets: [%s]\n", strconv.Quote(job.target))
 	}
+	return b.String()
 }
@@ -2,45 +2,55 @@ package service
 
 import (
 	"fmt"
-	"strconv"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
-	"pkg.world.dev/world-cli/internal/pkg/logger"
 )
 
 func getRedisContainerName(cfg *config.Config) string {
 	return fmt.Sprintf("%s-redis", cfg.DockerEnv["CARDINAL_NAMESPACE"])
 }
 
+func getRedisVolumeName(cfg *config.Config) string {
+	return fmt.Sprintf("%s-redis-data", cfg.DockerEnv["CARDINAL_NAMESPACE"])
+}
+
 func Redis(cfg *config.Config) Service {
 	// Check cardinal namespace
 	checkCardinalNamespace(cfg)
 
-	redisPort := cfg.DockerEnv["REDIS_PORT"]
-	if redisPort == "" {
-		redisPort = "6379"
-	}
+	ports := publishAll(cfg, PortRedis)
 
-	intPort, err := strconv.Atoi(redisPort)
-	if err != nil {
-		logger.Error("Failed to convert redis port to int, defaulting to 6379", err)
-		intPort = 6379
+	// Require the password Cardinal connects with, redis-cli reads it from REDISCLI_AUTH
+	var cmd, env []string
+	if password := cfg.DockerEnv["REDIS_PASSWORD"]; password != "" {
+		cmd = []string{"redis-server", "--requirepass", password}
+		env = []string{"REDISCLI_AUTH=" + password}
 	}
-	exposedPorts := []int{intPort}
 
 	return Service{
 		Name: getRedisContainerName(cfg),
 		Config: container.Config{
 			Image:        "redis:latest",
-			ExposedPorts: getExposedPorts(exposedPorts),
+			Cmd:          cmd,
+			Env:          env,
+			ExposedPorts: getExposedPorts(ports),
+			Healthcheck: &container.HealthConfig{
+				// redis-cli exits with 0 on error replies such as NOAUTH, only PONG means ready
+				Test:     []string{"CMD-SHELL", "redis-cli ping | grep -q PONG"},
+				Interval: 1 * time.Second,
+				Timeout:  1 * time.Second,
+				Retries:  20,
+			},
 		},
 		HostConfig: container.HostConfig{
-			PortBindings:  newPortMap(exposedPorts),
+			PortBindings:  newPortMap(ports),
 			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
-			Mounts:        []mount.Mount{{Type: mount.TypeVolume, Source: "data", Target: "/redis"}},
+			Mounts:        []mount.Mount{{Type: mount.TypeVolume, Source: getRedisVolumeName(cfg), Target: "/data"}},
 			NetworkMode:   container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
 		},
+		Ports: ports,
 	}
 }
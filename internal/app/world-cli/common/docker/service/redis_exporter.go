@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+func getRedisExporterContainerName(cfg *config.Config) string {
+	return fmt.Sprintf("%s-redis-exporter", cfg.DockerEnv["CARDINAL_NAMESPACE"])
+}
+
+// RedisExporter exports the metrics of Redis to Prometheus, including the latency of its commands.
+func RedisExporter(cfg *config.Config) Service {
+	// Check cardinal namespace
+	checkCardinalNamespace(cfg)
+
+	env := []string{fmt.Sprintf("REDIS_ADDR=redis://%s:6379", getRedisContainerName(cfg))}
+	if password := cfg.DockerEnv["REDIS_PASSWORD"]; password != "" {
+		env = append(env, "REDIS_PASSWORD="+password)
+	}
+
+	return Service{
+		Name: getRedisExporterContainerName(cfg),
+		Config: container.Config{
+			Image: "oliver006/redis_exporter:v1.62.0",
+			Env:   env,
+		},
+		HostConfig: container.HostConfig{
+			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+			NetworkMode:   container.NetworkMode(cfg.DockerEnv["CARDINAL_NAMESPACE"]),
+		},
+		DependsOn: []string{getRedisContainerName(cfg)},
+	}
+}
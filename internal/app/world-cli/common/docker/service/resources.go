@@ -0,0 +1,36 @@
+package service
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+// ApplyResources sets the resource limits and restart policy of the [resources] section of world.toml on
+// the host config of the service. Services without a section keep their defaults.
+func ApplyResources(cfg *config.Config, s *Service) {
+	for name, resources := range cfg.Resources {
+		if GetContainerName(cfg, name) != s.Name {
+			continue
+		}
+		if resources.CPUs > 0 {
+			s.NanoCPUs = int64(resources.CPUs * 1e9) //nolint:mnd // CPUs to nano CPUs
+		}
+		if resources.Memory > 0 {
+			s.Memory = resources.Memory
+		}
+		if resources.Restart != "" {
+			s.RestartPolicy = container.RestartPolicy{
+				Name:              container.RestartPolicyMode(resources.Restart),
+				MaximumRetryCount: resources.MaxRetries,
+			}
+		}
+		for _, ulimitName := range slices.Sorted(maps.Keys(resources.Ulimits)) {
+			ulimit := resources.Ulimits[ulimitName]
+			s.Ulimits = append(s.Ulimits, &units.Ulimit{Name: ulimitName, Soft: ulimit.Soft, Hard: ulimit.Hard})
+		}
+	}
+}
@@ -2,12 +2,15 @@ package service
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/go-connections/nat"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/rotisserie/eris"
 	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
 	"pkg.world.dev/world-cli/internal/pkg/logger"
 )
@@ -33,33 +36,81 @@ type Service struct {
 	Dockerfile string
 	// BuildTarget is the target build of the Dockerfile e.g. builder or runtime
 	BuildTarget string
+	// BuildArgs are extra build args passed to the Dockerfile
+	BuildArgs map[string]string
+	// BaseImages are the base images of the Dockerfile, keyed by the build arg selecting them.
+	// They are pinned by digest before the build.
+	BaseImages map[string]string
+	// Ports are the container ports published on the host
+	Ports []PublishedPort
+	// BuildContext is the directory the image is built from when the Dockerfile is not embedded
+	BuildContext string
+	// DockerfilePath is the path of the Dockerfile inside BuildContext
+	DockerfilePath string
+	// DependsOn are the names of the containers that must be running before this service starts
+	DependsOn []string
+	// Secrets tells whether the build fetches private modules with the credentials of the user
+	Secrets bool
+	// Files are written into the container every time it starts, keyed by their absolute path. Unlike bind
+	// mounts, they also work with a remote Docker host.
+	Files map[string]string
+}
+
+// NeedsBuild returns true if the image of the service is built instead of pulled.
+func (s Service) NeedsBuild() bool {
+	return s.Dockerfile != "" || s.BuildContext != ""
+}
+
+// ValidateBuildTarget returns an error if the build target of the service is not a stage of its Dockerfile.
+// Services built from their own context are not checked because their Dockerfile is read by Docker.
+func (s Service) ValidateBuildTarget() error {
+	if s.Dockerfile == "" || s.BuildTarget == "" {
+		return nil
+	}
+	stages := DockerfileStages(s.Dockerfile)
+	for _, stage := range stages {
+		if strings.EqualFold(stage, s.BuildTarget) {
+			return nil
+		}
+	}
+	return eris.Errorf("build target %q of %s is not a stage of its Dockerfile, available stages: (%s)",
+		s.BuildTarget, s.Name, strings.Join(stages, ", "))
+}
+
+var dockerfileStageRegexp = regexp.MustCompile(`(?im)^\s*FROM\s+(?:--\S+\s+)*\S+\s+AS\s+([^\s#]+)`)
+
+// DockerfileStages returns the names of the build stages of the Dockerfile.
+func DockerfileStages(dockerfile string) []string {
+	matches := dockerfileStageRegexp.FindAllStringSubmatch(dockerfile, -1)
+	stages := make([]string, 0, len(matches))
+	for _, match := range matches {
+		stages = append(stages, match[1])
+	}
+	return stages
 }
 
 func SetBuildkitSupport(buildkitSupport bool) {
 	BuildkitSupport = buildkitSupport
 }
 
-func getExposedPorts(ports []int) nat.PortSet {
+func getExposedPorts(ports []PublishedPort) nat.PortSet {
 	exposedPorts := make(nat.PortSet)
 	for _, port := range ports {
-		if port < 1 || port > 65535 {
-			panic(fmt.Sprintf("invalid port %d, must be between 1 and 65535", port))
+		if port.Container < 1 || port.Container > 65535 {
+			panic(fmt.Sprintf("invalid port %d, must be between 1 and 65535", port.Container))
 		}
-		tcpPort := nat.Port(strconv.Itoa(port) + "/tcp")
-		exposedPorts[tcpPort] = struct{}{}
+		exposedPorts[containerPort(port.Container)] = struct{}{}
 	}
 	return exposedPorts
 }
 
-func newPortMap(ports []int) nat.PortMap {
+func newPortMap(ports []PublishedPort) nat.PortMap {
 	portMap := make(nat.PortMap)
 	for _, port := range ports {
-		if port < 1 || port > 65535 {
-			panic(fmt.Sprintf("invalid port %d, must be between 1 and 65535", port))
+		if port.Host < 1 || port.Host > 65535 {
+			panic(fmt.Sprintf("invalid port %d, must be between 1 and 65535", port.Host))
 		}
-		portStr := strconv.Itoa(port)
-		tcpPort := nat.Port(portStr + "/tcp")
-		portMap[tcpPort] = []nat.PortBinding{{HostPort: portStr}}
+		portMap[containerPort(port.Container)] = []nat.PortBinding{{HostPort: strconv.Itoa(port.Host)}}
 	}
 	return portMap
 }
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"gotest.tools/v3/assert"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+func TestHostPortDefaultsToContainerPort(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{}}
+	assert.Equal(t, 4040, HostPort(cfg, PortCardinal))
+	assert.Equal(t, 6379, HostPort(cfg, PortRedis))
+	assert.Equal(t, 7351, HostPort(cfg, PortNakamaConsole))
+}
+
+func TestHostPortOverrides(t *testing.T) {
+	cfg := &config.Config{
+		DockerEnv: map[string]string{"REDIS_PORT": "6390"},
+		Ports:     map[string]int{PortCardinal: 5050},
+	}
+	assert.Equal(t, 5050, HostPort(cfg, PortCardinal))
+	// REDIS_PORT is still honored when [ports] doesn't set redis
+	assert.Equal(t, 6390, HostPort(cfg, PortRedis))
+
+	cfg.Ports[PortRedis] = 6400
+	assert.Equal(t, 6400, HostPort(cfg, PortRedis))
+}
+
+func TestPublishedPortsAreBoundToHostPorts(t *testing.T) {
+	cfg := &config.Config{
+		DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"},
+		Ports:     map[string]int{PortRedis: 6400},
+	}
+	redis := Redis(cfg)
+	assert.Equal(t, 1, len(redis.Ports))
+	assert.Equal(t, 6379, redis.Ports[0].Container)
+	assert.Equal(t, 6400, redis.Ports[0].Host)
+	assert.Equal(t, "6400", redis.PortBindings["6379/tcp"][0].HostPort)
+}
+
+func TestValidatePorts(t *testing.T) {
+	cfg := &config.Config{Ports: map[string]int{PortCardinal: 5050}}
+	assert.NilError(t, ValidatePorts(cfg))
+
+	cfg.Ports["not_a_service"] = 1234
+	assert.ErrorContains(t, ValidatePorts(cfg), "not_a_service")
+}
+
+func TestPortOffsetMovesDefaultPorts(t *testing.T) {
+	cfg := &config.Config{
+		DockerEnv:  map[string]string{},
+		Ports:      map[string]int{PortRedis: 6400},
+		PortOffset: 100,
+	}
+	assert.Equal(t, 4140, HostPort(cfg, PortCardinal))
+	// explicit ports are not offset
+	assert.Equal(t, 6400, HostPort(cfg, PortRedis))
+	assert.NilError(t, ValidatePorts(cfg))
+
+	cfg.PortOffset = 60000
+	assert.ErrorContains(t, ValidatePorts(cfg), "out of range")
+}
+
+func TestRedisVolumeIsScopedToNamespace(t *testing.T) {
+	alpha := Redis(&config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"}})
+	beta := Redis(&config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "beta"}})
+	assert.Assert(t, alpha.Mounts[0].Source != beta.Mounts[0].Source)
+}
+
+func TestCustomServiceBuilder(t *testing.T) {
+	cfg := &config.Config{
+		RootDir:    "/game",
+		DockerEnv:  map[string]string{"CARDINAL_NAMESPACE": "alpha"},
+		Ports:      map[string]int{"minio_9001": 9201},
+		PortOffset: 10,
+		Services: []config.ServiceConfig{
+			{
+				Name:        "minio",
+				Image:       "minio/minio",
+				Env:         map[string]any{"MINIO_ROOT_USER": "admin"},
+				Ports:       []string{"9000", "9001"},
+				Volumes:     []string{"data:/data", "./config:/etc/minio:ro"},
+				Healthcheck: &config.Healthcheck{Test: []string{"curl", "-f", "http://localhost:9000"}},
+				DependsOn:   []string{"redis"},
+			},
+			{
+				Name:  "payments",
+				Build: &config.Build{Context: "payments"},
+			},
+		},
+	}
+	builders := CustomServices(cfg)
+	assert.Equal(t, 2, len(builders))
+
+	minio := builders[0](cfg)
+	assert.Equal(t, "alpha-minio", minio.Name)
+	assert.Equal(t, "minio/minio", minio.Image)
+	assert.DeepEqual(t, []string{"MINIO_ROOT_USER=admin"}, minio.Env)
+	assert.DeepEqual(t, []string{"alpha-redis"}, minio.DependsOn)
+	assert.DeepEqual(t, []string{"CMD", "curl", "-f", "http://localhost:9000"}, minio.Healthcheck.Test)
+	assert.DeepEqual(t, []string{"minio"}, minio.NetworkingConfig.EndpointsConfig["alpha"].Aliases)
+	assert.Check(t, !minio.NeedsBuild())
+
+	// the offset applies to declared ports, [ports] overrides win
+	assert.Equal(t, 9010, minio.Ports[0].Host)
+	assert.Equal(t, 9201, minio.Ports[1].Host)
+	assert.NilError(t, ValidatePorts(cfg))
+
+	assert.Equal(t, 2, len(minio.Mounts))
+	assert.Equal(t, "alpha-data", minio.Mounts[0].Source)
+	assert.Equal(t, "/game/config", minio.Mounts[1].Source)
+	assert.Check(t, minio.Mounts[1].ReadOnly)
+
+	payments := builders[1](cfg)
+	assert.Check(t, payments.NeedsBuild())
+	assert.Equal(t, "alpha-payments", payments.Image)
+	assert.Equal(t, "/game/payments", payments.BuildContext)
+	assert.Equal(t, "Dockerfile", payments.DockerfilePath)
+	// Only the Cardinal build gets the credentials for private modules
+	assert.Check(t, !payments.Secrets)
+	assert.Check(t, Cardinal(cfg).Secrets)
+}
+
+func TestEmbeddedDockerfileHasDefaultTargets(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{}}
+	assert.NilError(t, Cardinal(cfg).ValidateBuildTarget())
+
+	cfg.Debug = true
+	assert.Equal(t, defaultCardinalDebugTarget, Cardinal(cfg).BuildTarget)
+	assert.NilError(t, Cardinal(cfg).ValidateBuildTarget())
+}
+
+func TestCardinalBuildOverrides(t *testing.T) {
+	cfg := &config.Config{
+		DockerEnv: map[string]string{},
+		CardinalBuild: config.CardinalBuild{
+			DockerfileContent:  "FROM golang:1.24 AS build\nFROM --platform=linux/amd64 scratch as runtime\n",
+			ExtraStagesContent: "FROM runtime AS custom\n",
+			Target:             "custom",
+			Args:               map[string]any{"GOFLAGS": "-mod=vendor", "LEVEL": 2},
+		},
+	}
+	cardinal := Cardinal(cfg)
+	assert.DeepEqual(t, []string{"build", "runtime", "custom"}, DockerfileStages(cardinal.Dockerfile))
+	assert.Equal(t, "custom", cardinal.BuildTarget)
+	assert.DeepEqual(t, map[string]string{"GOFLAGS": "-mod=vendor", "LEVEL": "2"}, cardinal.BuildArgs)
+	assert.NilError(t, cardinal.ValidateBuildTarget())
+
+	cfg.CardinalBuild.Target = "missing"
+	assert.ErrorContains(t, Cardinal(cfg).ValidateBuildTarget(), `build target "missing"`)
+}
+
+func TestEmbeddedDockerfileUsesBuildKitSecrets(t *testing.T) {
+	t.Cleanup(func() { SetBuildkitSupport(false) })
+	cfg := &config.Config{DockerEnv: map[string]string{}}
+
+	SetBuildkitSupport(true)
+	dockerfile := embeddedDockerfile(cfg)
+	assert.Check(t, strings.Contains(dockerfile, "--mount=type=secret,id="+SecretGitHubToken))
+	assert.Check(t, strings.Contains(dockerfile, "--mount=type=ssh"))
+	assert.Check(t, !strings.Contains(dockerfile, "ARG GITHUB_TOKEN"))
+
+	SetBuildkitSupport(false)
+	dockerfile = embeddedDockerfile(cfg)
+	assert.Check(t, !strings.Contains(dockerfile, "--mount="))
+	assert.Check(t, strings.Contains(dockerfile, "ARG GITHUB_TOKEN"))
+
+	// the token must never be printed or written to the git config of a layer
+	for _, buildkit := range []bool{true, false} {
+		SetBuildkitSupport(buildkit)
+		dockerfile = embeddedDockerfile(cfg)
+		assert.Check(t, !strings.Contains(dockerfile, "echo"))
+		assert.Check(t, !strings.Contains(dockerfile, "git config --global"))
+	}
+}
+
+func TestEmbeddedDockerfileHonorsModuleFiles(t *testing.T) {
+	t.Cleanup(func() { SetBuildkitSupport(false) })
+	cfg := &config.Config{RootDir: t.TempDir(), DockerEnv: map[string]string{}}
+
+	// without go.sum the module versions are resolved during the build
+	dockerfile := embeddedDockerfile(cfg)
+	assert.Check(t, strings.Contains(dockerfile, "ENV GOFLAGS=-mod=mod"))
+	assert.Check(t, strings.Contains(dockerfile, "go mod download"))
+	assert.Check(t, !strings.Contains(dockerfile, "go mod tidy"))
+	assert.Check(t, !strings.Contains(dockerfile, "rm go.sum"))
+
+	assert.NilError(t, os.WriteFile(filepath.Join(cfg.RootDir, "go.sum"), nil, 0o600))
+	dockerfile = embeddedDockerfile(cfg)
+	assert.Check(t, !strings.Contains(dockerfile, "GOFLAGS"))
+	assert.Check(t, strings.Contains(dockerfile, "go.sum"))
+
+	// vendored modules are built as is, without downloading anything
+	assert.NilError(t, os.MkdirAll(filepath.Join(cfg.RootDir, "vendor"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(cfg.RootDir, "vendor", "modules.txt"), nil, 0o600))
+	dockerfile = embeddedDockerfile(cfg)
+	assert.Check(t, !strings.Contains(dockerfile, "go mod download"))
+
+	// the module and build caches are only mounted with BuildKit
+	assert.Check(t, !strings.Contains(dockerfile, "type=cache"))
+	SetBuildkitSupport(true)
+	dockerfile = embeddedDockerfile(cfg)
+	assert.Check(t, strings.Contains(dockerfile, "--mount=type=cache,target=/go/pkg/mod"))
+	assert.Check(t, strings.Contains(dockerfile, "--mount=type=cache,target=/root/.cache/go-build"))
+
+	// the base images are build args so they can be pinned by digest
+	cardinal := Cardinal(cfg)
+	assert.DeepEqual(t, []string{"GO_IMAGE", "RUNTIME_IMAGE"}, slices.Sorted(maps.Keys(cardinal.BaseImages)))
+	assert.Check(t, strings.Contains(cardinal.Dockerfile, "FROM ${GO_IMAGE} AS build"))
+	assert.Check(t, strings.Contains(cardinal.Dockerfile, "FROM ${RUNTIME_IMAGE} AS runtime"))
+}
+
+func TestResourcesAreAppliedToTheService(t *testing.T) {
+	cfg := &config.Config{
+		DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"},
+		Resources: map[string]config.ServiceResources{
+			"redis": {
+				CPUs:       1.5,
+				Memory:     512 << 20,
+				Restart:    config.RestartOnFailure,
+				MaxRetries: 3,
+				Ulimits:    map[string]config.Ulimit{"nofile": {Soft: 1024, Hard: 4096}},
+			},
+		},
+	}
+
+	redis := Redis(cfg)
+	ApplyResources(cfg, &redis)
+	assert.Equal(t, int64(1_500_000_000), redis.NanoCPUs)
+	assert.Equal(t, int64(512<<20), redis.Memory)
+	assert.Equal(t, container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 3},
+		redis.RestartPolicy)
+	assert.Equal(t, 1, len(redis.Ulimits))
+	assert.Equal(t, units.Ulimit{Name: "nofile", Soft: 1024, Hard: 4096}, *redis.Ulimits[0])
+
+	// Services without a section keep their defaults
+	nakama := Nakama(cfg)
+	ApplyResources(cfg, &nakama)
+	assert.Equal(t, int64(0), nakama.Memory)
+	assert.Equal(t, container.RestartPolicyUnlessStopped, nakama.RestartPolicy.Name)
+}
+
+func TestCardinalAndRedisHaveHealthchecks(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"}}
+	cardinal := Cardinal(cfg)
+	assert.DeepEqual(t, []string{"CMD", "healthcheck"}, cardinal.Healthcheck.Test)
+	assert.Assert(t, strings.Contains(cardinal.Dockerfile, "COPY --from=healthcheck /go/bin/healthcheck /usr/bin"))
+
+	redis := Redis(cfg)
+	assert.Assert(t, redis.Healthcheck != nil)
+	assert.Equal(t, 0, len(redis.Cmd))
+
+	// Redis requires the password Cardinal connects with
+	cfg.DockerEnv["REDIS_PASSWORD"] = "secret"
+	redis = Redis(cfg)
+	assert.DeepEqual(t, []string{"redis-server", "--requirepass", "secret"}, []string(redis.Cmd))
+	assert.Assert(t, slices.Contains(redis.Env, "REDISCLI_AUTH=secret"))
+	assert.Assert(t, slices.Contains(Cardinal(cfg).Env, "REDIS_PASSWORD=secret"))
+
+	// A project Dockerfile may not have the healthcheck binary
+	cfg.CardinalBuild.DockerfileContent = "FROM golang:1.24 AS runtime\n"
+	assert.Assert(t, Cardinal(cfg).Healthcheck == nil)
+}
+
+func TestNakamaFlagsAndProjectFiles(t *testing.T) {
+	cfg := &config.Config{
+		RootDir:   "/game",
+		DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha", "OUTGOING_QUEUE_SIZE": "256"},
+	}
+	nakama := Nakama(cfg)
+	entrypoint := nakama.Entrypoint[2]
+	assert.Assert(t, strings.Contains(entrypoint, "--socket.outgoing_queue_size=256"), entrypoint)
+	assert.Assert(t, strings.Contains(entrypoint, "--config=/nakama/data/local.yml"), entrypoint)
+	assert.Assert(t, strings.Contains(entrypoint, "--logger.level=INFO"), entrypoint)
+	assert.Equal(t, 0, len(nakama.Mounts))
+	assert.Assert(t, !nakama.NeedsBuild())
+
+	cfg.DockerEnv[config.NakamaConfigFileKey] = "nakama/config.yml"
+	cfg.DockerEnv[config.NakamaModulesDirKey] = "nakama/modules"
+	cfg.DockerEnv["NAKAMA_LOG_LEVEL"] = "DEBUG"
+	cfg.NakamaFlags = map[string]string{"session.token_expiry_sec": "7200", "runtime.env": "MODE=dev mode"}
+	nakama = Nakama(cfg)
+	entrypoint = nakama.Entrypoint[2]
+	assert.Assert(t, strings.Contains(entrypoint, "--config=/nakama/data/project.yml"), entrypoint)
+	assert.Assert(t, strings.Contains(entrypoint, "--logger.level=DEBUG"), entrypoint)
+	assert.Assert(t, strings.Contains(entrypoint, "--session.token_expiry_sec=7200"), entrypoint)
+	assert.Assert(t, strings.Contains(entrypoint, "--runtime.env='MODE=dev mode'"), entrypoint)
+	assert.Equal(t, 2, len(nakama.Mounts))
+	assert.Equal(t, "/game/nakama/config.yml", nakama.Mounts[0].Source)
+	assert.Equal(t, "/nakama/data/modules/project", nakama.Mounts[1].Target)
+	assert.Assert(t, nakama.Mounts[1].ReadOnly)
+
+	// A Go plugin is built into an image of the Nakama version
+	cfg.DockerEnv[config.NakamaGoPluginKey] = "nakama/plugin"
+	cfg.DockerEnv[NakamaVersionKey] = "3.22.0"
+	nakama = Nakama(cfg)
+	assert.Assert(t, nakama.NeedsBuild())
+	assert.Equal(t, "alpha-nakama", nakama.Image)
+	assert.Equal(t, "heroiclabs/nakama-pluginbuilder:3.22.0", nakama.BaseImages["PLUGIN_BUILDER_IMAGE"])
+	assert.Equal(t, "nakama/plugin", nakama.BuildArgs["PLUGIN_PATH"])
+	assert.NilError(t, nakama.ValidateBuildTarget())
+}
+
+func TestNakamaDBImageCanBeUpgraded(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha", "DB_PASSWORD": "secret"}}
+	assert.Equal(t, "postgres:12.2-alpine", NakamaDB(cfg).Image)
+	assert.Equal(t, "postgres:secret@alpha-nakama-db:5432/nakama", NakamaDBAddress(cfg))
+	assert.Assert(t, strings.Contains(Nakama(cfg).Entrypoint[2], "--database.address "+NakamaDBAddress(cfg)))
+
+	cfg.DockerEnv[NakamaDBImageKey] = "postgres:16-alpine"
+	assert.Equal(t, "postgres:16-alpine", NakamaDB(cfg).Image)
+}
+
+func TestPrometheusScrapesTheStackAndUserServices(t *testing.T) {
+	cfg := &config.Config{
+		DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"},
+		Services: []config.ServiceConfig{
+			{Name: "payments", Image: "payments", Metrics: &config.Metrics{Port: 9102}},
+			{Name: "minio", Image: "minio/minio"},
+		},
+	}
+	prometheus := Prometheus(cfg)
+	assert.DeepEqual(t, []string{"--config.file=/etc/prometheus/world.yml", "--storage.tsdb.path=/prometheus"},
+		[]string(prometheus.Cmd))
+
+	scrapeConfig := prometheus.Files["/etc/prometheus/world.yml"]
+	for _, want := range []string{
+		`scrape_interval: 30s`,
+		`targets: ["alpha-nakama:9100"]`,
+		`targets: ["alpha-redis-exporter:9121"]`,
+		`job_name: "payments"`,
+		`targets: ["alpha-payments:9102"]`,
+	} {
+		assert.Assert(t, strings.Contains(scrapeConfig, want), "%s not in\n%s", want, scrapeConfig)
+	}
+	assert.Assert(t, !strings.Contains(scrapeConfig, "minio"))
+	// Cardinal serves no metrics
+	assert.Assert(t, !strings.Contains(scrapeConfig, "alpha-cardinal"))
+}
+
+func TestGrafanaIsProvisioned(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"}}
+	grafana := Grafana(cfg)
+	assert.Equal(t, 3000, grafana.Ports[0].Host)
+
+	datasources := grafana.Files["/etc/grafana/provisioning/datasources/world.yaml"]
+	assert.Assert(t, strings.Contains(datasources, "url: http://alpha-prometheus:9090"), datasources)
+	assert.Assert(t, !strings.Contains(datasources, "jaeger"), datasources)
+	assert.Assert(t, strings.Contains(grafana.Files["/etc/grafana/provisioning/dashboards/world.yaml"],
+		"path: /etc/grafana/dashboards"))
+
+	for _, name := range []string{"cardinal.json", "nakama.json"} {
+		content, ok := grafana.Files["/etc/grafana/dashboards/"+name]
+		assert.Assert(t, ok, name)
+		var dashboard struct {
+			UID    string `json:"uid"`
+			Panels []struct {
+				Datasource struct {
+					UID string `json:"uid"`
+				} `json:"datasource"`
+			} `json:"panels"`
+		}
+		assert.NilError(t, json.Unmarshal([]byte(content), &dashboard), name)
+		assert.Assert(t, dashboard.UID != "", name)
+		for _, panel := range dashboard.Panels {
+			assert.Equal(t, "prometheus", panel.Datasource.UID, name)
+		}
+	}
+
+	// Jaeger is only a datasource when the stack traces
+	cfg.Telemetry = true
+	datasources = Grafana(cfg).Files["/etc/grafana/provisioning/datasources/world.yaml"]
+	assert.Assert(t, strings.Contains(datasources, "url: http://alpha-prometheus:9090"), datasources)
+	assert.Assert(t, strings.Contains(datasources, "url: http://alpha-jaeger:16686"), datasources)
+}
+
+func TestTracesFlowThroughTheCollector(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"}}
+	assert.Assert(t, !TracingEnabled(cfg))
+	assert.Assert(t, slices.Contains(Cardinal(cfg).Env, "TELEMETRY_TRACE_ENABLED=false"))
+	assert.Assert(t, slices.Contains(Nakama(cfg).Env, "JAEGER_ADDR=alpha-jaeger:4317"))
+
+	cfg.Telemetry = true
+	assert.Assert(t, TracingEnabled(cfg))
+	cardinalEnv := Cardinal(cfg).Env
+	for _, want := range []string{
+		"TELEMETRY_TRACE_ENABLED=true",
+		"OTEL_EXPORTER_OTLP_ENDPOINT=http://alpha-otel-collector:4317",
+		"OTEL_RESOURCE_ATTRIBUTES=service.namespace=alpha",
+		"DD_AGENT_HOST=alpha-otel-collector",
+		"DD_TRACE_AGENT_PORT=8126",
+	} {
+		assert.Assert(t, slices.Contains(cardinalEnv, want), "%s not in %v", want, cardinalEnv)
+	}
+	assert.Assert(t, slices.Contains(Nakama(cfg).Env, "JAEGER_ADDR=alpha-otel-collector:4317"))
+
+	collector := OTelCollector(cfg)
+	assert.DeepEqual(t, []string{"alpha-jaeger"}, collector.DependsOn)
+	collectorConfig := collector.Files["/etc/otelcol-contrib/config.yaml"]
+	for _, want := range []string{
+		`value: "alpha"`,
+		`endpoint: "alpha-jaeger:4317"`,
+		`receivers: [otlp, datadog]`,
+		`exporters: [otlp/jaeger, spanmetrics]`,
+		`receivers: [spanmetrics]`,
+		`endpoint: 0.0.0.0:8889`,
+	} {
+		assert.Assert(t, strings.Contains(collectorConfig, want), "%s not in\n%s", want, collectorConfig)
+	}
+	scrapeConfig := Prometheus(cfg).Files["/etc/prometheus/world.yml"]
+	for _, want := range []string{`targets: ["alpha-otel-collector:8888"]`, `targets: ["alpha-otel-collector:8889"]`} {
+		assert.Assert(t, strings.Contains(scrapeConfig, want), "%s not in\n%s", want, scrapeConfig)
+	}
+
+	// The collector takes no profiles, the profiler and the Datadog traces go to the agent of world.toml
+	cfg.DockerEnv["TELEMETRY_PROFILER_ENABLED"] = "true"
+	cardinalEnv = Cardinal(cfg).Env
+	assert.Assert(t, !slices.Contains(cardinalEnv, "DD_AGENT_HOST=alpha-otel-collector"), cardinalEnv)
+	assert.Assert(t, slices.Contains(cardinalEnv, "OTEL_EXPORTER_OTLP_ENDPOINT=http://alpha-otel-collector:4317"))
+	cfg.DockerEnv["DD_AGENT_HOST"] = "datadog-agent"
+	assert.Assert(t, slices.Contains(Cardinal(cfg).Env, "DD_AGENT_HOST=datadog-agent"))
+	delete(cfg.DockerEnv, "TELEMETRY_PROFILER_ENABLED")
+
+	// Tracing stays off when both Nakama and Cardinal turn it off
+	cfg.DockerEnv["NAKAMA_TRACE_ENABLED"] = "false"
+	cfg.DockerEnv["TELEMETRY_TRACE_ENABLED"] = "false"
+	assert.Assert(t, !TracingEnabled(cfg))
+}
+
+func TestNakamaReachesTheNativeCardinal(t *testing.T) {
+	cfg := &config.Config{DockerEnv: map[string]string{"CARDINAL_NAMESPACE": "alpha"}, PortOffset: 10}
+	nakama := Nakama(cfg)
+	assert.Assert(t, slices.Contains(nakama.Env, "CARDINAL_ADDR=alpha-cardinal:4040"))
+	assert.Assert(t, len(nakama.ExtraHosts) == 0)
+
+	cfg.CardinalOnHost = true
+	nakama = Nakama(cfg)
+	assert.Assert(t, slices.Contains(nakama.Env, "CARDINAL_ADDR=host.docker.internal:4050"))
+	assert.DeepEqual(t, []string{"host.docker.internal:host-gateway"}, nakama.ExtraHosts)
+}
@@ -0,0 +1,287 @@
+// Package endpoint resolves the container runtime the World CLI talks to: the local Docker daemon, a Docker
+// context such as a remote host over SSH, or the Docker-compatible API of Podman.
+package endpoint
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	dockerconfig "github.com/docker/cli/cli/config"
+	clidocker "github.com/docker/cli/cli/context/docker"
+	"github.com/docker/cli/cli/context/store"
+	"github.com/docker/docker/client"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+)
+
+const (
+	// defaultContext is the name of the Docker context using DOCKER_HOST or the default socket.
+	defaultContext = "default"
+	// pingTimeout bounds the time spent checking that the runtime is running.
+	pingTimeout = 10 * time.Second
+)
+
+// overrides is the runtime selected with the --runtime and --context flags.
+//
+//nolint:gochecknoglobals // set once from the command line flags
+var overrides config.Runtime
+
+// SetOverrides selects the runtime from the command line flags, which replace the [runtime] section of
+// world.toml when set.
+func SetOverrides(r config.Runtime) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	overrides = r
+	return nil
+}
+
+// Endpoint is the API endpoint of a container runtime.
+type Endpoint struct {
+	// Runtime is docker or podman
+	Runtime string
+	// Context is the name of the Docker context of the endpoint, empty when it doesn't come from a context
+	Context string
+	// Host is the address of the API, e.g. unix:///var/run/docker.sock or ssh://user@host
+	Host string
+	// Source is how the endpoint was selected, e.g. --context or DOCKER_HOST
+	Source string
+
+	endpoint clidocker.Endpoint
+}
+
+// Resolve returns the endpoint selected by the command line flags, the given [runtime] section of
+// world.toml, or the environment, in that order. Without any selection, the Docker daemon is used unless
+// it isn't installed and a Podman socket is found.
+func Resolve(selected config.Runtime) (Endpoint, error) {
+	source := "world.toml"
+	if overrides != (config.Runtime{}) {
+		selected, source = overrides, "command line flags"
+	}
+	if err := selected.Validate(); err != nil {
+		return Endpoint{}, err
+	}
+
+	var (
+		ep  Endpoint
+		err error
+	)
+	switch {
+	case selected.Context != "":
+		ep, err = contextEndpoint(selected.Context, source)
+	case selected.Name == config.RuntimePodman:
+		ep, err = podmanEndpoint()
+	case selected.Name == config.RuntimeDocker:
+		ep, err = dockerEndpoint()
+	default:
+		ep, err = detectEndpoint()
+	}
+	if err != nil {
+		return Endpoint{}, err
+	}
+
+	if logger.VerboseMode {
+		logger.Printf("Using %s at %s (%s)\r\n", ep.Runtime, ep.Host, ep.Source)
+	}
+	return ep, nil
+}
+
+// Current returns the endpoint selected by the command line flags, the world.toml of the current
+// directory, or the environment.
+func Current() (Endpoint, error) {
+	selected := config.Runtime{}
+	if cfg, err := config.GetConfig(nil); err == nil {
+		selected = cfg.Runtime
+	}
+	return Resolve(selected)
+}
+
+// NewAPIClient returns a client of the API of the endpoint.
+func NewAPIClient(ep Endpoint) (*client.Client, error) {
+	opts, err := ep.endpoint.ClientOpts()
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to connect to %s", ep.Host)
+	}
+	// The environment provides the TLS settings of DOCKER_HOST
+	cli, err := client.NewClientWithOpts(append([]client.Opt{client.FromEnv}, opts...)...)
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to create a client of %s", ep.Host)
+	}
+	return cli, nil
+}
+
+// Ping returns an error when the runtime of the endpoint is not running or not reachable.
+func Ping(ctx context.Context, ep Endpoint) error {
+	cli, err := NewAPIClient(ep)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if _, err := cli.Ping(ctx); err != nil {
+		return eris.Wrapf(err, "%s is not reachable at %s", ep.Runtime, ep.Host)
+	}
+	return nil
+}
+
+// ServerRuntime returns the runtime serving the API and its version. A Docker endpoint can be served by
+// Podman, e.g. when the Docker socket is provided by podman-docker.
+func ServerRuntime(ctx context.Context, cli client.APIClient) (string, string, error) {
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return "", "", eris.Wrap(err, "Failed to get the server version")
+	}
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), config.RuntimePodman) {
+			return config.RuntimePodman, component.Version, nil
+		}
+	}
+	return config.RuntimeDocker, version.Version, nil
+}
+
+// detectEndpoint returns the Docker endpoint, or the Podman socket when Docker is not installed.
+func detectEndpoint() (Endpoint, error) {
+	ep, err := dockerEndpoint()
+	if err != nil || ep.Source != "default socket" || socketExists(ep.Host) {
+		return ep, err
+	}
+	if podman, err := podmanEndpoint(); err == nil {
+		podman.Source = "detected " + podman.Source
+		return podman, nil
+	}
+	return ep, nil
+}
+
+// dockerEndpoint returns the endpoint the docker CLI would use: DOCKER_HOST, DOCKER_CONTEXT, the current
+// context of the Docker config file, or the default socket.
+func dockerEndpoint() (Endpoint, error) {
+	if host := os.Getenv(client.EnvOverrideHost); host != "" {
+		return hostEndpoint(config.RuntimeDocker, host, client.EnvOverrideHost), nil
+	}
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" && name != defaultContext {
+		return contextEndpoint(name, "DOCKER_CONTEXT")
+	}
+	if cfg, err := dockerconfig.Load(dockerconfig.Dir()); err == nil &&
+		cfg.CurrentContext != "" && cfg.CurrentContext != defaultContext {
+		return contextEndpoint(cfg.CurrentContext, "current docker context")
+	}
+	return hostEndpoint(config.RuntimeDocker, client.DefaultDockerHost, "default socket"), nil
+}
+
+// contextEndpoint returns the endpoint of a Docker context, with its TLS materials.
+func contextEndpoint(name, source string) (Endpoint, error) {
+	if name == defaultContext {
+		ep, err := dockerEndpoint()
+		ep.Context = defaultContext
+		return ep, err
+	}
+
+	contextStore := store.New(dockerconfig.ContextStoreDir(), store.NewConfig(
+		func() any { return &contextMetadata{} },
+		store.EndpointTypeGetter(clidocker.DockerEndpoint, func() any { return &clidocker.EndpointMeta{} }),
+	))
+	metadata, err := contextStore.GetMetadata(name)
+	if err != nil {
+		return Endpoint{}, eris.Wrapf(err, "Docker context %q not found, list the contexts with: docker context ls",
+			name)
+	}
+	meta, err := clidocker.EndpointFromContext(metadata)
+	if err != nil {
+		return Endpoint{}, eris.Wrapf(err, "invalid Docker context %q", name)
+	}
+	endpoint, err := clidocker.WithTLSData(contextStore, name, meta)
+	if err != nil {
+		return Endpoint{}, eris.Wrapf(err, "Failed to load the TLS materials of the Docker context %q", name)
+	}
+	return Endpoint{
+		Runtime:  config.RuntimeDocker,
+		Context:  name,
+		Host:     meta.Host,
+		Source:   source,
+		endpoint: endpoint,
+	}, nil
+}
+
+// contextMetadata is the metadata of a Docker context, only the endpoint is used.
+type contextMetadata struct {
+	Description string `json:",omitempty"`
+}
+
+// podmanEndpoint returns the Docker-compatible API socket of Podman.
+func podmanEndpoint() (Endpoint, error) {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		if strings.HasPrefix(host, "ssh://") {
+			return Endpoint{}, eris.Errorf("CONTAINER_HOST=%s: remote Podman over SSH is not supported, forward "+
+				"its socket with ssh -L or use a Docker context", host)
+		}
+		return hostEndpoint(config.RuntimePodman, host, "CONTAINER_HOST"), nil
+	}
+	for _, socket := range podmanSockets() {
+		if socketExists(socket) {
+			return hostEndpoint(config.RuntimePodman, socket, "Podman socket"), nil
+		}
+	}
+	return Endpoint{}, eris.New("the Podman socket was not found, start it with: " + podmanStartHint())
+}
+
+// podmanSockets returns the addresses of the Podman API socket, from the most to the least likely.
+func podmanSockets() []string {
+	switch runtime.GOOS {
+	case "linux":
+		// The rootless socket of the user, then the rootful socket
+		var sockets []string
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			sockets = append(sockets, "unix://"+filepath.Join(dir, "podman", "podman.sock"))
+		}
+		return append(sockets, "unix:///run/podman/podman.sock")
+	case "windows":
+		return []string{"npipe:////./pipe/podman-machine-default"}
+	default:
+		// The socket of the Podman machine is only known to podman
+		out, err := exec.Command("podman", "machine", "inspect", "--format",
+			"{{.ConnectionInfo.PodmanSocket.Path}}").Output()
+		if err != nil {
+			return nil
+		}
+		sockets := make([]string, 0)
+		for _, path := range strings.Fields(string(out)) {
+			sockets = append(sockets, "unix://"+path)
+		}
+		return sockets
+	}
+}
+
+func podmanStartHint() string {
+	if runtime.GOOS == "linux" {
+		return "systemctl --user enable --now podman.socket"
+	}
+	return "podman machine start"
+}
+
+func hostEndpoint(runtimeName, host, source string) Endpoint {
+	return Endpoint{
+		Runtime:  runtimeName,
+		Host:     host,
+		Source:   source,
+		endpoint: clidocker.Endpoint{EndpointMeta: clidocker.EndpointMeta{Host: host}},
+	}
+}
+
+// socketExists returns true unless the host is a unix socket that doesn't exist. Other hosts are assumed
+// to exist, they are checked when connecting.
+func socketExists(host string) bool {
+	path, ok := strings.CutPrefix(host, "unix://")
+	if !ok {
+		return true
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
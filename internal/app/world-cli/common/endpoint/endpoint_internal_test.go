@@ -0,0 +1,72 @@
+package endpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dockerconfig "github.com/docker/cli/cli/config"
+	"gotest.tools/v3/assert"
+	"pkg.world.dev/world-cli/internal/app/world-cli/common/config"
+)
+
+// writeDockerContext stores a Docker context the way docker context create does.
+func writeDockerContext(t *testing.T, dir, name, host string) {
+	t.Helper()
+	sum := sha256.Sum256([]byte(name))
+	metaDir := filepath.Join(dir, "contexts", "meta", hex.EncodeToString(sum[:]))
+	assert.NilError(t, os.MkdirAll(metaDir, 0o755))
+	meta := `{"Name":"` + name + `","Metadata":{},"Endpoints":{"docker":{"Host":"` + host + `","SkipTLSVerify":false}}}`
+	assert.NilError(t, os.WriteFile(filepath.Join(metaDir, "meta.json"), []byte(meta), 0o600))
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	dockerconfig.SetDir(dir)
+	writeDockerContext(t, dir, "build-box", "ssh://me@build-box")
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+	t.Setenv("CONTAINER_HOST", "")
+
+	// A context of world.toml
+	ep, err := Resolve(config.Runtime{Context: "build-box"})
+	assert.NilError(t, err)
+	assert.Equal(t, config.RuntimeDocker, ep.Runtime)
+	assert.Equal(t, "build-box", ep.Context)
+	assert.Equal(t, "ssh://me@build-box", ep.Host)
+	assert.Equal(t, "world.toml", ep.Source)
+
+	_, err = Resolve(config.Runtime{Context: "missing"})
+	assert.ErrorContains(t, err, "docker context ls")
+
+	// The current context of the Docker config file
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"currentContext":"build-box"}`), 0o600))
+	ep, err = Resolve(config.Runtime{Name: config.RuntimeDocker})
+	assert.NilError(t, err)
+	assert.Equal(t, "current docker context", ep.Source)
+	assert.Equal(t, "ssh://me@build-box", ep.Host)
+
+	// DOCKER_HOST wins over the current context, like with the docker CLI
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.2:2376")
+	ep, err = Resolve(config.Runtime{})
+	assert.NilError(t, err)
+	assert.Equal(t, "DOCKER_HOST", ep.Source)
+	assert.Equal(t, "tcp://10.0.0.2:2376", ep.Host)
+
+	t.Setenv("CONTAINER_HOST", "unix:///tmp/podman.sock")
+	ep, err = Resolve(config.Runtime{Name: config.RuntimePodman})
+	assert.NilError(t, err)
+	assert.Equal(t, config.RuntimePodman, ep.Runtime)
+	assert.Equal(t, "unix:///tmp/podman.sock", ep.Host)
+
+	// The command line flags replace world.toml
+	assert.NilError(t, SetOverrides(config.Runtime{Context: "build-box"}))
+	t.Cleanup(func() { overrides = config.Runtime{} })
+	ep, err = Resolve(config.Runtime{Name: config.RuntimePodman})
+	assert.NilError(t, err)
+	assert.Equal(t, "build-box", ep.Context)
+	assert.Equal(t, "command line flags", ep.Source)
+	assert.ErrorContains(t, SetOverrides(config.Runtime{Name: "containerd"}), "invalid runtime")
+}
@@ -0,0 +1,237 @@
+package gotest
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// Actions of the events of go test -json.
+const (
+	ActionStart       = "start"
+	ActionRun         = "run"
+	ActionOutput      = "output"
+	ActionPass        = "pass"
+	ActionFail        = "fail"
+	ActionSkip        = "skip"
+	ActionBuildOutput = "build-output"
+	ActionBuildFail   = "build-fail"
+)
+
+// Event is a line of go test -json, see go doc test2json.
+type Event struct {
+	Time        time.Time `json:"Time"`
+	Action      string    `json:"Action"`
+	Package     string    `json:"Package"`
+	Test        string    `json:"Test"`
+	Elapsed     float64   `json:"Elapsed"`
+	Output      string    `json:"Output"`
+	ImportPath  string    `json:"ImportPath"`
+	FailedBuild string    `json:"FailedBuild"`
+}
+
+// Report collects the results of a go test -json run.
+type Report struct {
+	packages []*packageResult
+	byName   map[string]*packageResult
+	// builds is the output of the builds, by import path
+	builds map[string]*strings.Builder
+}
+
+type packageResult struct {
+	name    string
+	start   time.Time
+	elapsed float64
+	action  string
+	output  strings.Builder
+	failed  string
+	tests   []*testResult
+	byName  map[string]*testResult
+}
+
+type testResult struct {
+	name    string
+	elapsed float64
+	action  string
+	output  strings.Builder
+}
+
+// NewReport returns an empty report.
+func NewReport() *Report {
+	return &Report{byName: make(map[string]*packageResult), builds: make(map[string]*strings.Builder)}
+}
+
+// Add records an event.
+func (r *Report) Add(e Event) {
+	if e.Action == ActionBuildOutput || e.Action == ActionBuildFail {
+		if r.builds[e.ImportPath] == nil {
+			r.builds[e.ImportPath] = &strings.Builder{}
+		}
+		r.builds[e.ImportPath].WriteString(e.Output)
+		return
+	}
+	if e.Package == "" {
+		return
+	}
+
+	pkg := r.byName[e.Package]
+	if pkg == nil {
+		pkg = &packageResult{name: e.Package, start: e.Time, byName: make(map[string]*testResult)}
+		r.packages = append(r.packages, pkg)
+		r.byName[e.Package] = pkg
+	}
+	if e.Test == "" {
+		switch e.Action {
+		case ActionOutput:
+			pkg.output.WriteString(e.Output)
+		case ActionPass, ActionFail, ActionSkip:
+			pkg.action, pkg.elapsed, pkg.failed = e.Action, e.Elapsed, e.FailedBuild
+		}
+		return
+	}
+
+	test := pkg.byName[e.Test]
+	if test == nil {
+		test = &testResult{name: e.Test}
+		pkg.tests = append(pkg.tests, test)
+		pkg.byName[e.Test] = test
+	}
+	switch e.Action {
+	case ActionOutput:
+		test.output.WriteString(e.Output)
+	case ActionPass, ActionFail, ActionSkip:
+		test.action, test.elapsed = e.Action, e.Elapsed
+	}
+}
+
+// Output returns the output of a test, or of a package when test is empty. The output of a package that
+// failed to build is its build output.
+func (r *Report) Output(pkgName string, test string) string {
+	pkg := r.byName[pkgName]
+	if pkg == nil {
+		return ""
+	}
+	if test != "" {
+		if t := pkg.byName[test]; t != nil {
+			return t.output.String()
+		}
+		return ""
+	}
+	if build := r.builds[pkg.failed]; build != nil {
+		return build.String() + pkg.output.String()
+	}
+	return pkg.output.String()
+}
+
+// Failed returns true when a package or a test failed.
+func (r *Report) Failed() bool {
+	for _, pkg := range r.packages {
+		if pkg.action == ActionFail {
+			return true
+		}
+		for _, test := range pkg.tests {
+			if test.action == ActionFail {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report in the JUnit XML format understood by CI services, a test suite per package.
+// A package that fails without a failed test, e.g. one that doesn't build, is reported as a failed test case
+// named after the package.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suites := junitTestSuites{}
+	for _, pkg := range r.packages {
+		suite := junitTestSuite{Name: pkg.name, Time: pkg.elapsed}
+		if !pkg.start.IsZero() {
+			suite.Timestamp = pkg.start.UTC().Format(time.RFC3339)
+		}
+		testFailed := false
+		for _, test := range pkg.tests {
+			testCase := junitTestCase{Name: test.name, ClassName: pkg.name, Time: test.elapsed}
+			switch test.action {
+			case ActionFail:
+				testCase.Failure = &junitMessage{Message: "Failed", Contents: test.output.String()}
+				suite.Failures++
+				testFailed = true
+			case ActionSkip:
+				testCase.Skipped = &junitMessage{Message: "Skipped", Contents: test.output.String()}
+				suite.Skipped++
+			default:
+				testCase.SystemOut = test.output.String()
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		if pkg.action == ActionFail && !testFailed {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      pkg.name,
+				ClassName: pkg.name,
+				Time:      pkg.elapsed,
+				Failure:   &junitMessage{Message: "Failed", Contents: r.Output(pkg.name, "")},
+			})
+			suite.Failures++
+		}
+		suite.Tests = len(suite.Cases)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Time += suite.Time
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	// Summing the durations adds floating point noise
+	suites.Time = math.Round(suites.Time*1000) / 1000 //nolint:mnd // milliseconds
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return eris.Wrap(err, "Failed to write the JUnit report")
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return eris.Wrap(err, "Failed to write the JUnit report")
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return eris.Wrap(err, "Failed to write the JUnit report")
+	}
+	return nil
+}
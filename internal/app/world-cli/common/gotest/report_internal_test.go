@@ -0,0 +1,51 @@
+package gotest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWriteJUnitReportsEveryPackage(t *testing.T) {
+	report := NewReport()
+	for _, e := range []Event{
+		{Action: ActionStart, Package: "game/system"},
+		{Action: ActionRun, Package: "game/system", Test: "TestMove"},
+		{Action: ActionOutput, Package: "game/system", Test: "TestMove", Output: "    move_test.go:9: boom\n"},
+		{Action: ActionFail, Package: "game/system", Test: "TestMove", Elapsed: 0.5},
+		{Action: ActionRun, Package: "game/system", Test: "TestSpawn"},
+		{Action: ActionSkip, Package: "game/system", Test: "TestSpawn"},
+		{Action: ActionFail, Package: "game/system", Elapsed: 0.6},
+		{Action: ActionBuildOutput, ImportPath: "game/query [game/query.test]", Output: "query.go:3: undefined: x\n"},
+		{Action: ActionBuildFail, ImportPath: "game/query [game/query.test]"},
+		{Action: ActionStart, Package: "game/query"},
+		{Action: ActionFail, Package: "game/query", FailedBuild: "game/query [game/query.test]"},
+	} {
+		report.Add(e)
+	}
+	assert.Assert(t, report.Failed())
+	assert.Equal(t, "    move_test.go:9: boom\n", report.Output("game/system", "TestMove"))
+
+	var out bytes.Buffer
+	assert.NilError(t, report.WriteJUnit(&out))
+	var suites junitTestSuites
+	assert.NilError(t, xml.Unmarshal(out.Bytes(), &suites))
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+
+	// The package that doesn't build is a failed test case with the build errors
+	query := suites.Suites[1]
+	assert.Equal(t, "game/query", query.Name)
+	assert.Equal(t, 1, len(query.Cases))
+	assert.Equal(t, "query.go:3: undefined: x\n", query.Cases[0].Failure.Contents)
+}
+
+func TestFailedIsFalseWhenEverythingPasses(t *testing.T) {
+	report := NewReport()
+	report.Add(Event{Action: ActionPass, Package: "game", Test: "TestMove"})
+	report.Add(Event{Action: ActionPass, Package: "game"})
+	assert.Assert(t, !report.Failed())
+}
@@ -21,3 +21,13 @@ func FindUnusedPort(start int, end int) (int, error) {
 	}
 	return 0, eris.Errorf("no available port in the range %d-%d", start, end)
 }
+
+// IsPortAvailable reports whether the given port can be bound on all interfaces.
+func IsPortAvailable(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	_ = listener.Close()
+	return true
+}
@@ -0,0 +1,296 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+const (
+	registerComponent = "RegisterComponent"
+	registerMessage   = "RegisterMessage"
+	registerQuery     = "RegisterQuery"
+	registerSystems   = "RegisterSystems"
+
+	// initWorld is the function of the starter template registering the elements, its parameter is the world
+	initWorld = "MustInitWorld"
+	// defaultWorld is the world in the statements to add by hand when main.go has no initWorld, as in the
+	// starter template
+	defaultWorld = "w"
+)
+
+// registration describes how main.go registers an element.
+type registration struct {
+	// function is the function of Cardinal registering the element
+	function string
+	// pkgPath is the import path of the package of the element
+	pkgPath string
+	// ident is the identifier of the element in its package
+	ident string
+	// arg returns the argument registering the element, from the names main.go imports Cardinal and the
+	// package of the element with, and the expression of the world
+	arg func(cardinal string, pkg string, world string) string
+}
+
+func newRegistration(kind Kind, n names, pkgPath string) registration {
+	r := registration{pkgPath: pkgPath}
+	switch kind {
+	case KindComponent:
+		r.function, r.ident = registerComponent, n.Type
+		r.arg = func(cardinal string, pkg string, world string) string {
+			return fmt.Sprintf("%s.%s[%s.%s](%s)", cardinal, registerComponent, pkg, n.Type, world)
+		}
+	case KindMessage:
+		r.function, r.ident = registerMessage, n.Type+"Msg"
+		r.arg = func(cardinal string, pkg string, world string) string {
+			return fmt.Sprintf("%s.%s[%s.%sMsg, %s.%sResult](%s, %q)", cardinal, registerMessage, pkg, n.Type, pkg,
+				n.Type, world, n.Route)
+		}
+	case KindQuery:
+		r.function, r.ident = registerQuery, n.Type
+		r.arg = func(cardinal string, pkg string, world string) string {
+			return fmt.Sprintf("%s.%s[%s.%sRequest, %s.%sResponse](%s, %q, %s.%s)", cardinal, registerQuery, pkg,
+				n.Type, pkg, n.Type, world, n.Route, pkg, n.Type)
+		}
+	case KindSystem:
+		// Systems are arguments of RegisterSystems, not registered one by one
+		r.function, r.ident = registerSystems, n.Type+"System"
+		r.arg = func(_ string, pkg string, _ string) string {
+			return pkg + "." + n.Type + "System"
+		}
+	}
+	return r
+}
+
+// edit inserts text at an offset of the source.
+type edit struct {
+	offset int
+	text   string
+}
+
+// register adds the element to the registrations of main.go, next to the last one of its kind, and imports its
+// package if needed. The source is edited as text at the positions of its syntax tree, so that the formatting
+// of the user is kept. Without a registration of the kind to add to, it returns a nil source and the statement to
+// add by hand.
+func register(src []byte, r registration) ([]byte, string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, mainFile, src, parser.ParseComments)
+	if err != nil {
+		return nil, "", eris.Wrap(err, "Failed to parse")
+	}
+	cardinal := importName(file, cardinalPackage)
+	if cardinal == "" {
+		return nil, "", eris.Errorf("%s doesn't import %s", mainFile, cardinalPackage)
+	}
+	pkg := importName(file, r.pkgPath)
+	imported := pkg != ""
+	if !imported {
+		pkg = path.Base(r.pkgPath)
+	}
+	if imported && refers(file, pkg, r.ident) {
+		// Already registered, e.g. by hand before generating the file
+		return src, "", nil
+	}
+
+	parent, anchor, world := findAnchor(file, cardinal, r.function)
+	if parent == nil {
+		worldName := worldParam(file, cardinal)
+		var manual string
+		if r.function == registerSystems {
+			manual = fmt.Sprintf("Must(%s.%s(%s, %s))", cardinal, registerSystems, worldName,
+				r.arg(cardinal, pkg, worldName))
+		} else {
+			manual = fmt.Sprintf("Must(%s)", r.arg(cardinal, pkg, worldName))
+		}
+		return nil, manual, nil
+	}
+
+	worldExpr := string(src[offset(fset, world.Pos()):offset(fset, world.End())])
+	edits := []edit{insertArg(fset, src, parent, anchor, r.arg(cardinal, pkg, worldExpr))}
+	if !imported {
+		edits = append(edits, insertImport(fset, src, file, r.pkgPath))
+	}
+	slices.SortFunc(edits, func(a, b edit) int { return b.offset - a.offset })
+	updated := slices.Clone(src)
+	for _, e := range edits {
+		updated = slices.Insert(updated, e.offset, []byte(e.text)...)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), mainFile, updated, parser.SkipObjectResolution); err != nil {
+		return nil, "", eris.Wrap(err, "the updated source doesn't parse")
+	}
+	return updated, "", nil
+}
+
+// worldParam returns the name of the world parameter of initWorld, or defaultWorld when the file has no such
+// function.
+func worldParam(file *ast.File, cardinal string) string {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Name.Name != initWorld {
+			continue
+		}
+		for _, field := range fn.Type.Params.List {
+			star, ok := field.Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			sel, ok := star.X.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "World" {
+				continue
+			}
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == cardinal && len(field.Names) > 0 {
+				return field.Names[0].Name
+			}
+		}
+	}
+	return defaultWorld
+}
+
+// importName returns the name a file imports a package with, or an empty string if it doesn't import it.
+func importName(file *ast.File, pkgPath string) string {
+	for _, spec := range file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err != nil || p != pkgPath {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return path.Base(pkgPath)
+	}
+	return ""
+}
+
+// refers tells whether the file refers to pkg.ident.
+func refers(file *ast.File, pkg string, ident string) bool {
+	found := false
+	ast.Inspect(file, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok && sel.Sel.Name == ident {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == pkg {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// findAnchor finds the last registration of the kind of function: the call whose arguments the new one goes
+// to, the argument it goes after, and the world the registration is given. Systems go after the last argument
+// of the last call of RegisterSystems, other elements after the last call of their function passed to another
+// call, usually Must.
+func findAnchor(file *ast.File, cardinal string, function string) (*ast.CallExpr, ast.Expr, ast.Expr) {
+	var parent *ast.CallExpr
+	var anchor, world ast.Expr
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if function == registerSystems {
+			if calls(call, cardinal, function) && len(call.Args) > 0 {
+				parent, anchor, world = call, call.Args[len(call.Args)-1], call.Args[0]
+			}
+			return true
+		}
+		for _, arg := range call.Args {
+			if registration, ok := arg.(*ast.CallExpr); ok && calls(registration, cardinal, function) &&
+				len(registration.Args) > 0 {
+				parent, anchor, world = call, arg, registration.Args[0]
+			}
+		}
+		return true
+	})
+	return parent, anchor, world
+}
+
+// calls tells whether call calls cardinal.function, instantiated or not.
+func calls(call *ast.CallExpr, cardinal string, function string) bool {
+	fun := call.Fun
+	switch index := fun.(type) {
+	case *ast.IndexExpr:
+		fun = index.X
+	case *ast.IndexListExpr:
+		fun = index.X
+	}
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != function {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && x.Name == cardinal
+}
+
+// insertArg inserts arg after the anchor argument of the call. Arguments on their own lines get a new line with
+// the same indentation, after a trailing comma and comment, others are appended to the line.
+func insertArg(fset *token.FileSet, src []byte, call *ast.CallExpr, anchor ast.Expr, arg string) edit {
+	i := slices.Index(call.Args, anchor)
+	previous := call.Lparen
+	if i > 0 {
+		previous = call.Args[i-1].End()
+	}
+	end := offset(fset, anchor.End())
+	if fset.Position(previous).Line == fset.Position(anchor.Pos()).Line {
+		return edit{offset: end, text: ", " + arg}
+	}
+	if rest := bytes.TrimLeft(src[end:], " \t"); len(rest) > 0 && rest[0] == ',' {
+		return edit{offset: lineEnd(src, end), text: "\n" + indentation(src, offset(fset, anchor.Pos())) + arg + ","}
+	}
+	return edit{offset: end, text: ",\n" + indentation(src, offset(fset, anchor.Pos())) + arg}
+}
+
+// insertImport imports pkgPath after the last import of the same module, or the last import.
+func insertImport(fset *token.FileSet, src []byte, file *ast.File, pkgPath string) edit {
+	module := path.Dir(pkgPath) + "/"
+	var decl, moduleDecl *ast.GenDecl
+	var last, moduleLast *ast.ImportSpec
+	for _, d := range file.Decls {
+		gen, ok := d.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ImportSpec) //nolint:errcheck // import declarations only have import specs
+			decl, last = gen, spec
+			if p, _ := strconv.Unquote(spec.Path.Value); strings.HasPrefix(p, module) {
+				moduleDecl, moduleLast = gen, spec
+			}
+		}
+	}
+	if moduleLast != nil {
+		decl, last = moduleDecl, moduleLast
+	}
+	if !decl.Lparen.IsValid() {
+		return edit{offset: offset(fset, decl.End()), text: "\nimport " + strconv.Quote(pkgPath)}
+	}
+	return edit{
+		offset: lineEnd(src, offset(fset, last.End())),
+		text:   "\n" + indentation(src, offset(fset, last.Pos())) + strconv.Quote(pkgPath),
+	}
+}
+
+func offset(fset *token.FileSet, pos token.Pos) int {
+	return fset.Position(pos).Offset
+}
+
+// lineEnd returns the offset of the end of the line of the offset.
+func lineEnd(src []byte, offset int) int {
+	if i := bytes.IndexByte(src[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(src)
+}
+
+// indentation returns the leading whitespace of the line of the offset.
+func indentation(src []byte, offset int) string {
+	start := bytes.LastIndexByte(src[:offset], '\n') + 1
+	line := src[start:offset]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
@@ -0,0 +1,213 @@
+// Package scaffold generates the components, messages, queries and systems of a game shard, and registers them
+// in the world of its main.go.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/rotisserie/eris"
+)
+
+// Kind is the kind of element of the ECS a file is generated for.
+type Kind string
+
+const (
+	KindComponent Kind = "component"
+	KindMessage   Kind = "message"
+	KindQuery     Kind = "query"
+	KindSystem    Kind = "system"
+
+	// cardinalPackage is the import path of Cardinal
+	cardinalPackage = "pkg.world.dev/world-engine/cardinal"
+	// mainFile is the file of the game shard registering everything in the world
+	mainFile = "main.go"
+)
+
+//nolint:gochecknoglobals // the templates are embedded at compile time and are read-only
+//go:embed templates/*.go.tmpl
+var templates embed.FS
+
+// kindDirs are the packages the files of each kind go to, as in the starter template.
+//
+//nolint:gochecknoglobals // read-only
+var kindDirs = map[Kind]string{
+	KindComponent: "component",
+	KindMessage:   "msg",
+	KindQuery:     "query",
+	KindSystem:    "system",
+}
+
+// kindSuffixes are the words dropped from the end of a name, the generated identifiers adding their own.
+//
+//nolint:gochecknoglobals // read-only
+var kindSuffixes = map[Kind][]string{
+	KindMessage: {"msg", "message"},
+	KindQuery:   {"query", "request"},
+	KindSystem:  {"system"},
+}
+
+// Result tells what Generate did.
+type Result struct {
+	// File is the generated file
+	File string
+	// Registered tells whether main.go registers the new element, Manual is the statement to add otherwise
+	Registered bool
+	Manual     string
+}
+
+// names are the names of the element, from the one given on the command line.
+type names struct {
+	// Package is the package of the file
+	Package string
+	// Type is the identifier the generated ones start with, e.g. PlayerHealth
+	Type string
+	// File is the name of the file, e.g. player_health.go
+	File string
+	// Route is the name the message or query is registered with, e.g. player-health
+	Route string
+	// Cardinal is the import path of Cardinal
+	Cardinal string
+}
+
+// Generate writes the file of a new element of the kind to its package in gameDir, whose module is modulePath,
+// then registers it in MustInitWorld of main.go.
+func Generate(gameDir string, modulePath string, kind Kind, name string) (*Result, error) {
+	dir, ok := kindDirs[kind]
+	if !ok {
+		return nil, eris.Errorf("unknown kind %q", kind)
+	}
+	n, err := newNames(kind, dir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	mainPath := filepath.Join(gameDir, mainFile)
+	src, err := os.ReadFile(mainPath)
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to read %s", mainPath)
+	}
+	file := filepath.Join(gameDir, dir, n.File)
+	if _, err := os.Stat(file); err == nil {
+		return nil, eris.Errorf("%s already exists", file)
+	}
+
+	content, err := render(kind, n)
+	if err != nil {
+		return nil, err
+	}
+	updated, manual, err := register(src, newRegistration(kind, n, modulePath+"/"+dir))
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to register %s in %s", n.Type, mainPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, eris.Wrapf(err, "Failed to create %s", filepath.Dir(file))
+	}
+	if err := os.WriteFile(file, content, 0644); err != nil { //nolint:gosec // source code
+		return nil, eris.Wrapf(err, "Failed to write %s", file)
+	}
+	result := &Result{File: file, Manual: manual}
+	if updated != nil {
+		if err := os.WriteFile(mainPath, updated, 0644); err != nil { //nolint:gosec // source code
+			return nil, eris.Wrapf(err, "Failed to write %s", mainPath)
+		}
+		result.Registered = true
+	}
+	return result, nil
+}
+
+// newNames derives the names of the element from name, in any case, e.g. player-health, player_health or
+// PlayerHealth.
+func newNames(kind Kind, dir string, name string) (names, error) {
+	words, err := splitWords(name)
+	if err != nil {
+		return names{}, err
+	}
+	for _, suffix := range kindSuffixes[kind] {
+		if len(words) > 1 && strings.EqualFold(words[len(words)-1], suffix) {
+			words = words[:len(words)-1]
+			break
+		}
+	}
+
+	var typeName strings.Builder
+	lower := make([]string, 0, len(words))
+	for _, word := range words {
+		lower = append(lower, strings.ToLower(word))
+		// Acronyms keep their case
+		if len(word) > 1 && strings.ToUpper(word) == word {
+			typeName.WriteString(word)
+			continue
+		}
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		typeName.WriteString(string(runes))
+	}
+	return names{
+		Package:  dir,
+		Type:     typeName.String(),
+		File:     strings.Join(lower, "_") + ".go",
+		Route:    strings.Join(lower, "-"),
+		Cardinal: cardinalPackage,
+	}, nil
+}
+
+// splitWords splits a name on dashes, underscores, spaces and case changes.
+func splitWords(name string) ([]string, error) {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			flush()
+		case unicode.IsUpper(r):
+			// A word starts at an upper case letter after a lower case one or a digit, or at the last letter of
+			// an acronym followed by a lower case one, as in HTTPServer
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				flush()
+			}
+			word = append(word, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			return nil, eris.Errorf("invalid name %q, only letters, digits, dashes and underscores are allowed", name)
+		}
+	}
+	flush()
+	if len(words) == 0 || !unicode.IsLetter([]rune(words[0])[0]) {
+		return nil, eris.Errorf("invalid name %q, it must start with a letter", name)
+	}
+	return words, nil
+}
+
+// render renders the template of the kind, formatted.
+func render(kind Kind, n names) ([]byte, error) {
+	tmpl, err := template.ParseFS(templates, "templates/"+string(kind)+".go.tmpl")
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to parse the %s template", kind)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return nil, eris.Wrapf(err, "Failed to render the %s template", kind)
+	}
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, eris.Wrapf(err, "Failed to format the %s template", kind)
+	}
+	return content, nil
+}
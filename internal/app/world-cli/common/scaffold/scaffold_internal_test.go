@@ -0,0 +1,154 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+const templateModule = "github.com/argus-labs/starter-game-template/cardinal"
+
+// newGameDir returns a game directory with the main.go of the starter template.
+func newGameDir(t *testing.T) string {
+	src, err := os.ReadFile(filepath.Join("..", "testdata", "starter-game-template", "cardinal", mainFile))
+	assert.NilError(t, err)
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, mainFile), src, 0600))
+	return dir
+}
+
+func TestNewNames(t *testing.T) {
+	for _, test := range []struct {
+		kind  Kind
+		name  string
+		typ   string
+		file  string
+		route string
+	}{
+		{KindComponent, "Position", "Position", "position.go", "position"},
+		{KindMessage, "move-player", "MovePlayer", "move_player.go", "move-player"},
+		{KindMessage, "MovePlayerMsg", "MovePlayer", "move_player.go", "move-player"},
+		{KindQuery, "player_position", "PlayerPosition", "player_position.go", "player-position"},
+		{KindQuery, "HTTPStatusQuery", "HTTPStatus", "http_status.go", "http-status"},
+		{KindSystem, "move system", "Move", "move.go", "move"},
+		{KindSystem, "System", "System", "system.go", "system"},
+	} {
+		n, err := newNames(test.kind, kindDirs[test.kind], test.name)
+		assert.NilError(t, err)
+		assert.Equal(t, test.typ, n.Type, test.name)
+		assert.Equal(t, test.file, n.File, test.name)
+		assert.Equal(t, test.route, n.Route, test.name)
+	}
+
+	_, err := newNames(KindComponent, "component", "2D")
+	assert.ErrorContains(t, err, "must start with a letter")
+	_, err = newNames(KindComponent, "component", "player.health")
+	assert.ErrorContains(t, err, "invalid name")
+}
+
+func TestGenerateRegistersInMainGo(t *testing.T) {
+	dir := newGameDir(t)
+	for _, kind := range []Kind{KindComponent, KindMessage, KindQuery, KindSystem} {
+		result, err := Generate(dir, templateModule, kind, "player-position")
+		assert.NilError(t, err)
+		assert.Assert(t, result.Registered, kind)
+		assert.Equal(t, filepath.Join(dir, kindDirs[kind], "player_position.go"), result.File)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "msg", "player_position.go"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(content), "package msg\n"))
+	assert.Assert(t, strings.Contains(string(content), "type PlayerPositionResult struct"))
+	content, err = os.ReadFile(filepath.Join(dir, "query", "player_position.go"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(content),
+		"func PlayerPosition(world cardinal.WorldContext, req *PlayerPositionRequest) (*PlayerPositionResponse, error)"))
+
+	src, err := os.ReadFile(filepath.Join(dir, mainFile))
+	assert.NilError(t, err)
+	main := string(src)
+	for _, registration := range []string{
+		"\t\tcardinal.RegisterComponent[component.Health](w),\n" +
+			"\t\tcardinal.RegisterComponent[component.PlayerPosition](w),\n\t)",
+		"\t\tcardinal.RegisterMessage[msg.AttackPlayerMsg, msg.AttackPlayerMsgReply](w, \"attack-player\"),\n" +
+			"\t\tcardinal.RegisterMessage[msg.PlayerPositionMsg, msg.PlayerPositionResult](w, \"player-position\"),\n\t)",
+		"\t\tcardinal.RegisterQuery[query.PlayerPositionRequest, query.PlayerPositionResponse](w, " +
+			"\"player-position\", query.PlayerPosition),\n\t)",
+		"\t\tsystem.PlayerSpawnerSystem,\n\t\tsystem.PlayerPositionSystem,\n\t))",
+	} {
+		assert.Assert(t, strings.Contains(main, registration), registration)
+	}
+	// The init systems are left alone
+	assert.Assert(t, strings.Contains(main, "RegisterInitSystems(w,\n\t\tsystem.SpawnDefaultPlayersSystem,\n\t))"))
+
+	_, err = Generate(dir, templateModule, KindComponent, "PlayerPosition")
+	assert.ErrorContains(t, err, "already exists")
+}
+
+const compactMain = `package main
+
+import (
+	"pkg.world.dev/world-engine/cardinal"
+
+	comp "example.com/game/component"
+)
+
+func MustInitWorld(world *cardinal.World) {
+	Must(cardinal.RegisterComponent[comp.Player](world)) // players
+	Must(cardinal.RegisterSystems(world, comp.Noop))
+}
+`
+
+func TestRegisterKeepsFormatting(t *testing.T) {
+	n, err := newNames(KindComponent, "component", "Health")
+	assert.NilError(t, err)
+	updated, manual, err := register([]byte(compactMain), newRegistration(KindComponent, n,
+		"example.com/game/component"))
+	assert.NilError(t, err)
+	assert.Equal(t, "", manual)
+	assert.Equal(t, strings.Replace(compactMain, "[comp.Player](world))",
+		"[comp.Player](world), cardinal.RegisterComponent[comp.Health](world))", 1), string(updated))
+
+	// The package of the systems is imported after the other ones of the game
+	n, err = newNames(KindSystem, "system", "Regen")
+	assert.NilError(t, err)
+	updated, _, err = register([]byte(compactMain), newRegistration(KindSystem, n, "example.com/game/system"))
+	assert.NilError(t, err)
+	expected := strings.Replace(compactMain, "comp.Noop)", "comp.Noop, system.RegenSystem)", 1)
+	expected = strings.Replace(expected, "\"example.com/game/component\"\n",
+		"\"example.com/game/component\"\n\t\"example.com/game/system\"\n", 1)
+	assert.Equal(t, expected, string(updated))
+
+	// Without a query registered yet, the registration is left to the user
+	n, err = newNames(KindQuery, "query", "PlayerHealth")
+	assert.NilError(t, err)
+	updated, manual, err = register([]byte(compactMain), newRegistration(KindQuery, n, "example.com/game/query"))
+	assert.NilError(t, err)
+	assert.Assert(t, updated == nil)
+	// The world is the parameter of MustInitWorld
+	assert.Equal(t, "Must(cardinal.RegisterQuery[query.PlayerHealthRequest, query.PlayerHealthResponse](world, "+
+		"\"player-health\", query.PlayerHealth))", manual)
+}
+
+func TestManualRegistrationUsesTheWorldOfMain(t *testing.T) {
+	n, err := newNames(KindQuery, "query", "PlayerHealth")
+	assert.NilError(t, err)
+	r := newRegistration(KindQuery, n, "example.com/game/query")
+
+	// Without MustInitWorld, the statement uses the world of the starter template
+	src := "package main\n\nimport \"pkg.world.dev/world-engine/cardinal\"\n\nfunc main() {\n\t_ = cardinal.World{}\n}\n"
+	_, manual, err := register([]byte(src), r)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(manual, "](w, "), manual)
+
+	// The name of the world follows the import name of Cardinal
+	src = "package main\n\nimport ce \"pkg.world.dev/world-engine/cardinal\"\n\n" +
+		"func MustInitWorld(game *ce.World) {}\n"
+	_, manual, err = register([]byte(src), r)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(manual, "Must(ce.RegisterQuery["), manual)
+	assert.Assert(t, strings.Contains(manual, "](game, "), manual)
+}
@@ -25,7 +25,7 @@ func CheckDependenciesCmd(deps []dependency.Dependency) tea.Cmd {
 		var res []DependencyStatus
 		var resErr error
 		for _, dep := range deps {
-			err := dep.Cmd.Run()
+			err := dep.Check()
 			res = append(res, DependencyStatus{
 				Dependency:  dep,
 				IsInstalled: err == nil,
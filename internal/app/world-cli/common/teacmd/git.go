@@ -238,3 +238,17 @@ func generateRandomHexString(length int) (string, error) {
 	}
 	return hex.EncodeToString(randomBytes), nil
 }
+
+// GitRevision returns the short SHA of the commit checked out in dir, and whether the working tree has
+// uncommitted changes.
+func GitRevision(dir string) (string, bool, error) {
+	sha, err := git("-C", dir, "rev-parse", "--short=12", "HEAD")
+	if err != nil {
+		return "", false, err
+	}
+	status, err := git("-C", dir, "status", "--porcelain")
+	if err != nil {
+		return "", false, err
+	}
+	return sha, status != "", nil
+}
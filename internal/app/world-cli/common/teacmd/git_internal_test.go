@@ -178,3 +178,28 @@ func TestGenerateRandomHexString(t *testing.T) {
 		})
 	}
 }
+
+func TestGitRevision(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := GitRevision(dir)
+	assert.Check(t, err != nil, "expected an error outside of a git repository")
+
+	_, err = git("-C", dir, "init")
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o600))
+	_, err = git("-C", dir, "add", "-A")
+	assert.NilError(t, err)
+	_, err = git("-C", dir, "-c", "user.name=World CLI", "-c", "user.email=no-reply@world.dev",
+		"commit", "-m", "Initial commit")
+	assert.NilError(t, err)
+
+	sha, dirty, err := GitRevision(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, 12, len(sha))
+	assert.Check(t, !dirty)
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o600))
+	_, dirty, err = GitRevision(dir)
+	assert.NilError(t, err)
+	assert.Check(t, dirty)
+}
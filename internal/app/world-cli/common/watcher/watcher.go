@@ -0,0 +1,147 @@
+package watcher
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rotisserie/eris"
+	"pkg.world.dev/world-cli/internal/pkg/logger"
+)
+
+// Watcher reports the changes of the files of a directory tree, except the ignored ones.
+type Watcher struct {
+	root     string
+	ignore   []string
+	debounce time.Duration
+	fs       *fsnotify.Watcher
+}
+
+// New watches every directory under root that is not ignored. Ignore globs with a slash match the path
+// relative to root, globs without a slash match the name of the file or of any of its parent directories.
+// Changes are reported once no other change happened for the debounce duration.
+func New(root string, ignore []string, debounce time.Duration) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, eris.Wrap(err, "Failed to create the file watcher")
+	}
+	w := &Watcher{root: root, ignore: ignore, debounce: debounce, fs: fsWatcher}
+	if err := w.addTree(root); err != nil {
+		_ = fsWatcher.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// Run calls onChange with the paths, relative to root, of the files changed since the last call, until
+// the context is done.
+func (w *Watcher) Run(ctx context.Context, onChange func(paths []string)) error {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	var changed []string
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error("File watcher error", err)
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			rel, ignored := w.relIgnored(event.Name)
+			if ignored || event.Op == fsnotify.Chmod {
+				continue
+			}
+			// New directories are watched too
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(event.Name); err != nil {
+						logger.Error("Failed to watch a new directory", err)
+					}
+				}
+			}
+			if !slices.Contains(changed, rel) {
+				changed = append(changed, rel)
+			}
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			onChange(changed)
+			changed = nil
+		}
+	}
+}
+
+// addTree watches the directory and its subdirectories that are not ignored.
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may be removed while it is walked
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return eris.Wrapf(err, "Failed to read %s", name)
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if _, ignored := w.relIgnored(name); ignored {
+			return filepath.SkipDir
+		}
+		if err := w.fs.Add(name); err != nil {
+			return eris.Wrapf(err, "Failed to watch %s", name)
+		}
+		return nil
+	})
+}
+
+// relIgnored returns the path relative to root and whether it is ignored.
+func (w *Watcher) relIgnored(name string) (string, bool) {
+	rel, err := filepath.Rel(w.root, name)
+	if err != nil {
+		return name, true
+	}
+	rel = filepath.ToSlash(rel)
+	return rel, Ignored(rel, w.ignore)
+}
+
+// Ignored returns true when the path, relative to the watched directory with forward slashes, matches one of
+// the ignore globs. The root itself is never ignored.
+func Ignored(rel string, ignore []string) bool {
+	if rel == "." {
+		return false
+	}
+	for _, glob := range ignore {
+		if strings.Contains(glob, "/") {
+			// Match the path and the directories it is in
+			for prefix := rel; prefix != "."; prefix = path.Dir(prefix) {
+				if ok, _ := path.Match(glob, prefix); ok {
+					return true
+				}
+			}
+			continue
+		}
+		for _, name := range strings.Split(rel, "/") {
+			if ok, _ := path.Match(glob, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestIgnored(t *testing.T) {
+	ignore := []string{".*", "*_test.go", "vendor", "web/*.js"}
+	testCases := []struct {
+		rel     string
+		ignored bool
+	}{
+		{".", false},
+		{"main.go", false},
+		{"system/move.go", false},
+		{"system/move_test.go", true},
+		{".git/HEAD", true},
+		{"system/.move.go.swp", true},
+		{"vendor/github.com/x/y.go", true},
+		{"web/app.js", true},
+		{"web/app/index.js", false},
+		{"web/app.js/x.go", true},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.ignored, Ignored(tc.rel, ignore), tc.rel)
+	}
+}
+
+func TestRunReportsDebouncedChanges(t *testing.T) {
+	root := t.TempDir()
+	assert.NilError(t, os.Mkdir(filepath.Join(root, "vendor"), 0755))
+	w, err := New(root, []string{"vendor", "*_test.go"}, 50*time.Millisecond)
+	assert.NilError(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	changes := make(chan []string, 1)
+	go func() {
+		_ = w.Run(ctx, func(paths []string) { changes <- paths })
+	}()
+
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "vendor", "dep.go"), []byte("package dep"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "main_test.go"), []byte("package main"), 0600))
+	assert.NilError(t, os.Mkdir(filepath.Join(root, "system"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "system", "move.go"), []byte("package system"), 0600))
+	assert.NilError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main"), 0600))
+
+	// Changes may be reported in several batches, none of them with an ignored file
+	for seen := false; !seen; {
+		select {
+		case paths := <-changes:
+			assert.Assert(t, len(paths) > 0)
+			for _, path := range paths {
+				assert.Assert(t, path != "vendor/dep.go" && path != "main_test.go", path)
+				seen = seen || path == "main.go"
+			}
+		case <-ctx.Done():
+			t.Fatal("main.go change not reported")
+		}
+	}
+}
@@ -26,6 +26,8 @@ type Dependencies struct {
 	CloudHandler        interfaces.CloudHandler
 	EVMHandler          interfaces.EVMHandler
 	CardinalHandler     interfaces.CardinalHandler
+	RegistryHandler     interfaces.RegistryHandler
+	NakamaHandler       interfaces.NakamaHandler
 	SetupController     interfaces.CommandSetupController
 }
 
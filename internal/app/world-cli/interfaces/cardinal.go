@@ -13,4 +13,14 @@ type CardinalHandler interface {
 	Dev(ctx context.Context, f models.DevCardinalFlags) error
 	Purge(ctx context.Context, f models.PurgeCardinalFlags) error
 	Build(ctx context.Context, f models.BuildCardinalFlags) error
+	List(ctx context.Context, f models.ListCardinalFlags) error
+	Images(ctx context.Context, f models.ImagesCardinalFlags) error
+	PruneImages(ctx context.Context, f models.PruneImagesCardinalFlags) error
+	Load(ctx context.Context, f models.LoadCardinalFlags) error
+	TraceOpen(ctx context.Context, f models.TraceOpenCardinalFlags) error
+	DebugSetup(ctx context.Context, f models.DebugSetupCardinalFlags) error
+	DebugAttach(ctx context.Context, f models.DebugAttachCardinalFlags) error
+	Test(ctx context.Context, f models.TestCardinalFlags) error
+	Bench(ctx context.Context, f models.BenchCardinalFlags) error
+	New(ctx context.Context, f models.NewCardinalFlags) error
 }
@@ -0,0 +1,25 @@
+package interfaces
+
+import (
+	"context"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+// NakamaHandler manages the database of the local Nakama.
+type NakamaHandler interface {
+	// DumpDB writes a dump of the Nakama database to a file.
+	DumpDB(ctx context.Context, flags models.DumpNakamaDBFlags) error
+
+	// RestoreDB replaces the Nakama database with a dump.
+	RestoreDB(ctx context.Context, flags models.RestoreNakamaDBFlags) error
+
+	// PsqlDB opens psql on the Nakama database, or runs a single command.
+	PsqlDB(ctx context.Context, flags models.PsqlNakamaDBFlags) error
+
+	// MigrateDB runs the schema migrations of Nakama.
+	MigrateDB(ctx context.Context, flags models.MigrateNakamaDBFlags) error
+
+	// UpgradeDB moves the Nakama database to the Postgres image of world.toml, keeping its data.
+	UpgradeDB(ctx context.Context, flags models.UpgradeNakamaDBFlags) error
+}
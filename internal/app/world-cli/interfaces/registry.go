@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+
+	"pkg.world.dev/world-cli/internal/app/world-cli/models"
+)
+
+// RegistryHandler manages the credentials of the container registries images are pushed to.
+type RegistryHandler interface {
+	// Login stores the credentials of a registry in the Docker config file.
+	Login(ctx context.Context, flags models.LoginRegistryFlags) error
+
+	// Logout removes the credentials of a registry from the Docker config file.
+	Logout(ctx context.Context, flags models.LogoutRegistryFlags) error
+}
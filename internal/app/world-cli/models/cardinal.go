@@ -1,43 +1,135 @@
 package models
 
+import "time"
+
 type StartCardinalFlags struct {
 	Config     string
+	Shard      string
 	Detach     bool
 	LogLevel   string
 	Debug      bool
 	Telemetry  bool
+	Grafana    bool
 	Editor     bool
 	EditorPort string
+	AutoPorts  bool
+	BuildArgs  []string
+	SSH        bool
+	Insecure   bool
+	NoCache    bool
+	NoBuild    bool
+	// Wait blocks a detached start until the stack is ready, for at most WaitTimeout seconds
+	Wait        bool
+	WaitTimeout int
 }
 
 type StopCardinalFlags struct {
 	Config string
+	Shard  string
 }
 
 type RestartCardinalFlags struct {
 	Config string
+	Shard  string
 	Detach bool
 	Debug  bool
 }
 
 type DevCardinalFlags struct {
 	Config    string
+	Shard     string
 	Editor    bool
 	PrettyLog bool
+	AutoPorts bool
+	Debug     bool
+	Nakama    bool
+	NoDocker  bool
+	Persist   bool
 }
 
 type PurgeCardinalFlags struct {
 	Config string
+	Shard  string
 }
 
 type BuildCardinalFlags struct {
+	Config     string
+	Shard      string
+	LogLevel   string
+	Debug      bool
+	Telemetry  bool
+	Push       []string
+	PushResult string
+	Auth       string
+	User       string
+	Pass       string
+	RegToken   string
+	Output     string
+	WithStack  bool
+	ShowLog    bool
+	BuildArgs  []string
+	Target     string
+	Platforms  []string
+	Version    string
+	SSH        bool
+	Insecure   bool
+	NoCache    bool
+}
+
+type ListCardinalFlags struct{}
+
+type ImagesCardinalFlags struct{}
+
+type PruneImagesCardinalFlags struct {
+	Keep int
+}
+
+type LoadCardinalFlags struct {
+	File string
+}
+
+type TraceOpenCardinalFlags struct {
+	Config  string
+	Shard   string
+	Service string
+}
+
+type DebugSetupCardinalFlags struct {
+	Config string
+	Shard  string
+	// IDE is vscode, goland or all
+	IDE string
+}
+
+type DebugAttachCardinalFlags struct {
+	Config string
+	Shard  string
+}
+
+type TestCardinalFlags struct {
+	Config string
+	Shard  string
+	// Args are the packages to test followed by the flags of go test
+	Args         []string
+	CoverProfile string
+	JUnit        string
+}
+
+type BenchCardinalFlags struct {
 	Config    string
-	LogLevel  string
-	Debug     bool
-	Telemetry bool
-	Push      string
-	Auth      string
-	User      string
-	Pass      string
-	RegToken  string
+	Shard     string
+	Scenario  string
+	URL       string
+	Namespace string
+	Personas  int
+	Rate      float64
+	Duration  time.Duration
+	Output    string
+}
+
+type NewCardinalFlags struct {
+	Config string
+	Shard  string
+	Kind   string
+	Name   string
 }
@@ -0,0 +1,34 @@
+package models
+
+type DumpNakamaDBFlags struct {
+	Config string
+	Shard  string
+	Output string
+}
+
+type RestoreNakamaDBFlags struct {
+	Config string
+	Shard  string
+	File   string
+	Yes    bool
+}
+
+type PsqlNakamaDBFlags struct {
+	Config  string
+	Shard   string
+	Command string
+}
+
+type MigrateNakamaDBFlags struct {
+	Config  string
+	Shard   string
+	Command string
+	Limit   int
+	Yes     bool
+}
+
+type UpgradeNakamaDBFlags struct {
+	Config string
+	Shard  string
+	Yes    bool
+}
@@ -0,0 +1,11 @@
+package models
+
+type LoginRegistryFlags struct {
+	Server        string
+	Username      string
+	PasswordStdin bool
+}
+
+type LogoutRegistryFlags struct {
+	Server string
+}